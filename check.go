@@ -3,6 +3,8 @@ package main
 import (
 	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 )
 
 var (
@@ -11,11 +13,318 @@ var (
 	ErrAmbiguousField        = errors.New("ambiguous field")
 	ErrUnknownParam          = errors.New("unknown param")
 	ErrUnknownFragment       = errors.New("unknown fragment")
+	ErrUnknownWindow         = errors.New("unknown window")
 	ErrFragmentParamMismatch = errors.New("mismatched fragment params")
+	ErrUnknownType           = errors.New("unknown type")
+	ErrAmbiguousType         = errors.New("ambiguous type")
+	ErrInvalidListParam      = errors.New("invalid use of list param")
+	ErrInvalidOperand        = errors.New("invalid operand")
+	ErrInvalidFunctionArgs   = errors.New("invalid function args")
+	ErrMissingAlias          = errors.New("missing alias")
+	ErrUngroupedField        = errors.New("ungrouped field")
+	ErrInvalidAggregate      = errors.New("invalid aggregate")
+	ErrDuplicateParam        = errors.New("duplicate param")
+	ErrInvalidPaginate       = errors.New("invalid paginate")
+	ErrInvalidDistinct       = errors.New("invalid distinct")
+	ErrColumnCountMismatch   = errors.New("column count mismatch")
+	ErrInvalidSubquery       = errors.New("invalid subquery")
+	ErrInvalidSetOp          = errors.New("invalid set operation")
+	ErrInvalidWindow         = errors.New("invalid window")
+	ErrConditionalJoin       = errors.New("conditional join referenced outside its condition")
+	ErrInvalidLock           = errors.New("invalid locking clause")
+	ErrInvalidConflictTarget = errors.New("invalid conflict target")
+	ErrInvalidTypeDef        = errors.New("invalid type declaration")
 )
 
+// postgres functions with known return types. functions not listed here
+// can still be called, but their results are untyped.
+type BuiltinFunction struct {
+	Name    string
+	MinArgs int
+	MaxArgs int // -1 for variadic
+
+	// postgres type name of the result, unless ReturnsArgType is set
+	ReturnTypeName string
+	// eg coalesce, which returns the type of its first arg
+	ReturnsArgType bool
+	// aggregates are typed by aggregateValueType
+	IsAggregate bool
+	// window functions can only be called with OVER. aggregates can be
+	// called with OVER too, but aren't window functions otherwise
+	IsWindow bool
+}
+
+var BuiltinFunctions = []BuiltinFunction{
+	{Name: "coalesce", MinArgs: 1, MaxArgs: -1, ReturnsArgType: true},
+	{Name: "nullif", MinArgs: 2, MaxArgs: 2, ReturnsArgType: true},
+	{Name: "greatest", MinArgs: 1, MaxArgs: -1, ReturnsArgType: true},
+	{Name: "least", MinArgs: 1, MaxArgs: -1, ReturnsArgType: true},
+	{Name: "abs", MinArgs: 1, MaxArgs: 1, ReturnsArgType: true},
+	{Name: "round", MinArgs: 1, MaxArgs: 2, ReturnTypeName: "numeric"},
+	{Name: "lower", MinArgs: 1, MaxArgs: 1, ReturnTypeName: "text"},
+	{Name: "upper", MinArgs: 1, MaxArgs: 1, ReturnTypeName: "text"},
+	{Name: "trim", MinArgs: 1, MaxArgs: 1, ReturnTypeName: "text"},
+	{Name: "concat", MinArgs: 1, MaxArgs: -1, ReturnTypeName: "text"},
+	{Name: "substr", MinArgs: 2, MaxArgs: 3, ReturnTypeName: "text"},
+	{Name: "replace", MinArgs: 3, MaxArgs: 3, ReturnTypeName: "text"},
+	{Name: "length", MinArgs: 1, MaxArgs: 1, ReturnTypeName: "integer"},
+	{Name: "now", MinArgs: 0, MaxArgs: 0, ReturnTypeName: "timestamptz"},
+	{Name: "date_trunc", MinArgs: 2, MaxArgs: 3, ReturnTypeName: "timestamptz"},
+	{Name: "random", MinArgs: 0, MaxArgs: 0, ReturnTypeName: "double"},
+	{Name: "gen_random_uuid", MinArgs: 0, MaxArgs: 0, ReturnTypeName: "uuid"},
+
+	{Name: "count", MinArgs: 1, MaxArgs: 1, IsAggregate: true},
+	{Name: "sum", MinArgs: 1, MaxArgs: 1, IsAggregate: true},
+	{Name: "avg", MinArgs: 1, MaxArgs: 1, IsAggregate: true},
+	{Name: "min", MinArgs: 1, MaxArgs: 1, IsAggregate: true},
+	{Name: "max", MinArgs: 1, MaxArgs: 1, IsAggregate: true},
+	{Name: "array_agg", MinArgs: 1, MaxArgs: 1, IsAggregate: true},
+	{Name: "string_agg", MinArgs: 2, MaxArgs: 2, ReturnTypeName: "text", IsAggregate: true},
+	{Name: "bool_and", MinArgs: 1, MaxArgs: 1, ReturnTypeName: "boolean", IsAggregate: true},
+
+	{Name: "row_number", MinArgs: 0, MaxArgs: 0, ReturnTypeName: "bigint", IsWindow: true},
+	{Name: "rank", MinArgs: 0, MaxArgs: 0, ReturnTypeName: "bigint", IsWindow: true},
+	{Name: "dense_rank", MinArgs: 0, MaxArgs: 0, ReturnTypeName: "bigint", IsWindow: true},
+	{Name: "percent_rank", MinArgs: 0, MaxArgs: 0, ReturnTypeName: "double", IsWindow: true},
+	{Name: "cume_dist", MinArgs: 0, MaxArgs: 0, ReturnTypeName: "double", IsWindow: true},
+	{Name: "ntile", MinArgs: 1, MaxArgs: 1, ReturnTypeName: "integer", IsWindow: true},
+	{Name: "lag", MinArgs: 1, MaxArgs: 3, ReturnsArgType: true, IsWindow: true},
+	{Name: "lead", MinArgs: 1, MaxArgs: 3, ReturnsArgType: true, IsWindow: true},
+	{Name: "first_value", MinArgs: 1, MaxArgs: 1, ReturnsArgType: true, IsWindow: true},
+	{Name: "last_value", MinArgs: 1, MaxArgs: 1, ReturnsArgType: true, IsWindow: true},
+	{Name: "nth_value", MinArgs: 2, MaxArgs: 2, ReturnsArgType: true, IsWindow: true},
+}
+
+func findBuiltinFunction(name string) (BuiltinFunction, bool) {
+	for _, f := range BuiltinFunctions {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return BuiltinFunction{}, false
+}
+
+// describes a value of the named postgres type
+func valueType(typeName string, notNull bool) TableField {
+	return TableField{
+		Type:     TableFieldTypeFromName(typeName),
+		TypeName: typeName,
+		NotNull:  notNull,
+	}
+}
+
+// describes the result of a cast. modifiers such as varchar(255) don't change
+// the type, and arrays are left untyped.
+func castValueType(typeName string, notNull bool) TableField {
+	if strings.HasSuffix(typeName, "[]") {
+		return TableField{TypeName: typeName, NotNull: notNull}
+	}
+	if i := strings.Index(typeName, "("); i >= 0 {
+		typeName = typeName[:i]
+	}
+	return valueType(typeName, notNull)
+}
+
+// arithmetic isn't defined for these, so it's likely a missing cast
+func isArithmeticOperand(field TableField) bool {
+	switch field.Type {
+	case TableFieldTypeText, TableFieldTypeBoolean, TableFieldTypeUUID, TableFieldTypeJSON, TableFieldTypeBytea:
+		return false
+	default:
+		return true
+	}
+}
+
+// orders numeric types by how postgres widens them to a common type
+func numericRank(t TableFieldType) int {
+	switch t {
+	case TableFieldTypeSmallInt:
+		return 1
+	case TableFieldTypeSerial, TableFieldTypeInteger:
+		return 2
+	case TableFieldTypeBigSerial, TableFieldTypeBigInt:
+		return 3
+	case TableFieldTypeNumeric:
+		return 4
+	case TableFieldTypeReal:
+		return 5
+	case TableFieldTypeDouble:
+		return 6
+	default:
+		return 0
+	}
+}
+
+// resolves a column of a UNION, INTERSECT or EXCEPT to a type that both
+// branches can be converted to. numbers are widened, untyped values such as
+// NULL take the other type, and other types must match. ok is false if
+// there's no common type.
+func commonValueType(a TableField, b TableField) (TableField, bool) {
+	result := a
+	ok := true
+
+	switch {
+	case a.Type == TableFieldTypeNone:
+		result.Type, result.TypeName = b.Type, b.TypeName
+	case b.Type == TableFieldTypeNone, a.Type == b.Type:
+	case numericRank(a.Type) > 0 && numericRank(b.Type) > 0:
+		if numericRank(b.Type) > numericRank(a.Type) {
+			result.Type, result.TypeName = b.Type, b.TypeName
+		}
+	default:
+		ok = false
+	}
+
+	// serial columns are only unique within their own table
+	result.PrimaryKey = false
+	result.NotNull = (a.NotNull || a.PrimaryKey) && (b.NotNull || b.PrimaryKey)
+	return result, ok
+}
+
+func paramValueType(param Param) TableField {
+	switch param.Type {
+	case ParamTypeString:
+		return valueType("text", param.Required)
+	case ParamTypeNumber:
+		return valueType("integer", param.Required)
+	case ParamTypeBool:
+		return valueType("boolean", param.Required)
+	default:
+		return TableField{NotNull: param.Required}
+	}
+}
+
+// an aggregate called with OVER is a window call instead
+func isAggregateCall(expr *Expression) bool {
+	if expr.Type != ExpressionTypeFunction || expr.Over != nil {
+		return false
+	}
+	f, ok := findBuiltinFunction(expr.FunctionName)
+	return ok && f.IsAggregate
+}
+
+func containsAggregate(expr *Expression) bool {
+	if isAggregateCall(expr) {
+		return true
+	}
+	for _, child := range expr.Children() {
+		if containsAggregate(child) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWindowCall(expr *Expression) bool {
+	return expr.Type == ExpressionTypeFunction && expr.Over != nil
+}
+
+func containsWindowCall(expr *Expression) bool {
+	if isWindowCall(expr) {
+		return true
+	}
+	for _, child := range expr.Children() {
+		if containsWindowCall(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// the type of an aggregate call. aggregates skip nulls, so they're only null
+// if every input is null, or if there are no rows. that can only happen
+// without a GROUP BY, or over a window frame that can be empty. count is never null.
+func aggregateValueType(f BuiltinFunction, expr *Expression, hasRows bool) TableField {
+	var arg TableField
+	if len(expr.FunctionArgs) > 0 {
+		arg = expr.FunctionArgs[0].ValueType
+	}
+	notNull := hasRows && arg.NotNull
+
+	switch f.Name {
+	case "count":
+		return valueType("bigint", true)
+	case "sum":
+		switch arg.Type {
+		case TableFieldTypeSmallInt, TableFieldTypeInteger, TableFieldTypeSerial:
+			return valueType("bigint", notNull)
+		case TableFieldTypeBigInt, TableFieldTypeBigSerial:
+			return valueType("numeric", notNull)
+		}
+	case "avg":
+		switch arg.Type {
+		case TableFieldTypeReal, TableFieldTypeDouble:
+			return valueType("double", notNull)
+		default:
+			return valueType("numeric", notNull)
+		}
+	case "array_agg":
+		// elements may be null, but the array is only null when there are no rows
+		result := TableField{NotNull: hasRows}
+		if arg.TypeName != "" {
+			result.TypeName = arg.TypeName + "[]"
+		}
+		return result
+	}
+
+	if f.ReturnTypeName != "" {
+		return valueType(f.ReturnTypeName, notNull)
+	}
+
+	// sum over other types, min and max
+	result := arg
+	result.NotNull = notNull
+	return result
+}
+
+// the type of a call to a builtin function. strict functions return null
+// for any null arg, so the result is only not-null if every arg is.
+func functionValueType(f BuiltinFunction, expr *Expression, hasRows bool) TableField {
+	if f.IsAggregate {
+		return aggregateValueType(f, expr, hasRows)
+	}
+
+	allNotNull := true
+	anyNotNull := false
+	for _, arg := range expr.FunctionArgs {
+		allNotNull = allNotNull && arg.ValueType.NotNull
+		anyNotNull = anyNotNull || arg.ValueType.NotNull
+	}
+
+	var result TableField
+	if f.ReturnsArgType && len(expr.FunctionArgs) > 0 {
+		result = expr.FunctionArgs[0].ValueType
+	} else {
+		result = valueType(f.ReturnTypeName, true)
+	}
+
+	switch f.Name {
+	case "coalesce":
+		result.NotNull = anyNotNull
+	case "nullif":
+		result.NotNull = false
+	case "lag", "lead", "nth_value":
+		// null when the row is outside the partition or frame
+		result.NotNull = false
+	case "first_value", "last_value":
+		result.NotNull = hasRows && allNotNull
+	default:
+		result.NotNull = allNotNull
+	}
+
+	return result
+}
+
 type CheckError struct {
-	Err error
+	Err  error
+	Line int // 0 if unknown
+}
+
+func (e CheckError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+	}
+	return e.Err.Error()
 }
 
 // todo: consider moving this into struct
@@ -32,11 +341,357 @@ type Scope struct {
 	Locals []Param
 
 	QueryParamToGlobalName map[string]string
+
+	// checking an {if} condition, which is written as go rather than sql
+	IsTemplateCondition bool
 }
 
 type TableContext struct {
 	Tables  []Table
 	Aliases []string // one to one with Tables
+	// one to one with Tables, set for tables joined in {if ...}{end}
+	JoinConditions []*Expression
+	// one to one with Tables, set for the nullable side of an outer join,
+	// whose columns are NULL when there's no match
+	Nullable []bool
+
+	// relations that subqueries can select from, including CTEs
+	Schema Schema
+	// the enclosing select's tables, which a correlated subquery can reference
+	Outer *TableContext
+	// where fields that resolve to Outer are recorded
+	OuterFields *[]Field
+
+	// the query has a GROUP BY, so every aggregate has at least one row
+	IsGrouped bool
+
+	// the select's WINDOW definitions, which OVER clauses can name
+	Windows []NamedWindow
+
+	// the columns * expands to, in order. columns merged by USING or
+	// NATURAL joins are listed once, first, like postgres
+	StarColumns []columnRef
+	// the right side of columns merged by USING or NATURAL joins. unqualified
+	// names resolve to the left side instead
+	MergedColumns []columnRef
+}
+
+// identifies a column by the index of its table in the context, so
+// self joins under different aliases are distinct
+type columnRef struct {
+	TableIndex int
+	Name       string
+}
+
+// whether two checked expressions are the same, including fields written with
+// and without their table
+func isSameExpr(tableCtx TableContext, a Expression, b Expression) bool {
+	isField := func(e Expression) bool {
+		return e.Type == ExpressionTypeLiteral && e.LiteralType == LiteralTypeFieldName
+	}
+	if isField(a) && isField(b) {
+		refA, okA := resolveColumnRef(tableCtx, a.LiteralField)
+		refB, okB := resolveColumnRef(tableCtx, b.LiteralField)
+		return okA && okB && refA == refB
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// whether an ORDER BY expression is in the select list, by alias or by value
+func isSelected(tableCtx TableContext, stmt *SelectStmt, columns []ResultColumn, expr Expression) bool {
+	if expr.Type == ExpressionTypeLiteral && expr.LiteralType == LiteralTypeFieldName && isOutputColumn(columns, expr.LiteralField) {
+		return true
+	}
+	for _, f := range stmt.Fields {
+		fieldExpr := Expression{Type: ExpressionTypeLiteral, LiteralType: LiteralTypeFieldName, LiteralField: f}
+		if f.Expr != nil {
+			fieldExpr = *f.Expr
+		}
+		if isSameExpr(tableCtx, fieldExpr, expr) {
+			return true
+		}
+	}
+	return false
+}
+
+// postgres requires DISTINCT ON expressions to match the leftmost ORDER BY
+// items, in any order, and SELECT DISTINCT to only order by selected fields.
+// checking here reports the problem before the query is run.
+func checkDistinct(tableCtx TableContext, stmt *SelectStmt, columns []ResultColumn) []CheckError {
+	var errors []CheckError
+
+	if len(stmt.DistinctOn) > 0 && len(stmt.OrderBy) > 0 {
+		leading := stmt.OrderBy
+		if len(leading) > len(stmt.DistinctOn) {
+			leading = leading[:len(stmt.DistinctOn)]
+		}
+
+		for _, item := range leading {
+			matched := false
+			for _, distinctExpr := range stmt.DistinctOn {
+				if item.SortParamName == "" && isSameExpr(tableCtx, distinctExpr, item.Expr) {
+					matched = true
+				}
+			}
+			if !matched {
+				errors = append(errors, CheckError{
+					Err:  fmt.Errorf("%w: DISTINCT ON expressions must match the leftmost ORDER BY expressions", ErrInvalidDistinct),
+					Line: stmt.DistinctOnLine,
+				})
+				break
+			}
+		}
+
+		if len(errors) == 0 && len(stmt.OrderBy) < len(stmt.DistinctOn) {
+			errors = append(errors, CheckError{
+				Err:  fmt.Errorf("%w: every DISTINCT ON expression must be in ORDER BY", ErrInvalidDistinct),
+				Line: stmt.DistinctOnLine,
+			})
+		}
+	}
+
+	if stmt.Distinct && len(stmt.DistinctOn) == 0 {
+		for _, item := range stmt.OrderBy {
+			if item.SortParamName != "" {
+				for _, option := range item.SortOptions {
+					optionExpr := Expression{Type: ExpressionTypeLiteral, LiteralType: LiteralTypeFieldName, LiteralField: option}
+					if !isSelected(tableCtx, stmt, columns, optionExpr) {
+						errors = append(errors, CheckError{Err: fmt.Errorf("%w: for SELECT DISTINCT, sort column %s must be selected", ErrInvalidDistinct, option.Name)})
+					}
+				}
+				continue
+			}
+			if !isSelected(tableCtx, stmt, columns, item.Expr) {
+				errors = append(errors, CheckError{Err: fmt.Errorf("%w: for SELECT DISTINCT, ORDER BY expressions must be selected", ErrInvalidDistinct)})
+			}
+		}
+	}
+
+	return errors
+}
+
+func findResultColumn(columns []ResultColumn, name string) (ResultColumn, bool) {
+	for _, col := range columns {
+		if col.Name == name {
+			return col, true
+		}
+	}
+	return ResultColumn{}, false
+}
+
+// like postgres, an unqualified name in ORDER BY may refer to a select field
+// by its alias or name, eg ORDER BY count
+func isOutputColumn(columns []ResultColumn, field Field) bool {
+	if field.TableName != "" {
+		return false
+	}
+	_, ok := findResultColumn(columns, field.Name)
+	return ok
+}
+
+// like checkFieldWithTable, but only reports whether the field resolves
+// to exactly one column
+func resolveColumnRef(tableCtx TableContext, field Field) (columnRef, bool) {
+	refs := findColumnRefs(tableCtx, field)
+	if len(refs) != 1 {
+		return columnRef{}, false
+	}
+	return refs[0], true
+}
+
+// every column the field could refer to, which is more than one if it's ambiguous
+func findColumnRefs(tableCtx TableContext, field Field) []columnRef {
+	var refs []columnRef
+	for i, tableDef := range tableCtx.Tables {
+		if field.TableName != "" && field.TableName != tableCtx.Aliases[i] {
+			continue
+		}
+		for _, fieldDef := range tableDef.Fields {
+			ref := columnRef{TableIndex: i, Name: fieldDef.Name}
+			if fieldDef.Name == field.Name && !(field.TableName == "" && isMergedColumn(tableCtx, ref)) {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+func isMergedColumn(tableCtx TableContext, ref columnRef) bool {
+	for _, merged := range tableCtx.MergedColumns {
+		if merged == ref {
+			return true
+		}
+	}
+	return false
+}
+
+func tableColumnRefs(tableIndex int, tableDef Table) []columnRef {
+	refs := make([]columnRef, 0, len(tableDef.Fields))
+	for _, fieldDef := range tableDef.Fields {
+		refs = append(refs, columnRef{TableIndex: tableIndex, Name: fieldDef.Name})
+	}
+	return refs
+}
+
+// the columns a NATURAL join matches on, which are the ones with the same
+// name on both sides. the joined table is the last one in the context.
+func naturalJoinColumns(tableCtx TableContext) []string {
+	right := len(tableCtx.Tables) - 1
+
+	seen := map[string]bool{}
+	var names []string
+	for _, ref := range tableCtx.StarColumns {
+		if ref.TableIndex == right || seen[ref.Name] {
+			continue
+		}
+		for _, fieldDef := range tableCtx.Tables[right].Fields {
+			if fieldDef.Name == ref.Name {
+				seen[ref.Name] = true
+				names = append(names, ref.Name)
+			}
+		}
+	}
+	return names
+}
+
+// merges the columns a USING or NATURAL join matches on, which must be on
+// both sides. the joined table is the last one in the context.
+func mergeJoinColumns(tableCtx *TableContext, names []string) []CheckError {
+	var errors []CheckError
+
+	right := len(tableCtx.Tables) - 1
+	left := *tableCtx
+	left.Tables = left.Tables[:right]
+	left.Aliases = left.Aliases[:right]
+
+	var merged []columnRef
+	isMerged := map[columnRef]bool{}
+	for _, name := range names {
+		leftRefs := findColumnRefs(left, Field{Name: name})
+		rightRef := columnRef{TableIndex: right, Name: name}
+
+		hasRight := false
+		for _, fieldDef := range tableCtx.Tables[right].Fields {
+			hasRight = hasRight || fieldDef.Name == name
+		}
+
+		switch {
+		case len(leftRefs) > 1:
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: join column %s found in multiple tables", ErrAmbiguousField, name)})
+		case len(leftRefs) == 0:
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: join column %s not found on the left side", ErrUnknownField, name)})
+		case !hasRight:
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: join column %s not found in %s", ErrUnknownField, name, tableCtx.Tables[right].Name)})
+		default:
+			merged = append(merged, leftRefs[0])
+			isMerged[leftRefs[0]] = true
+			isMerged[rightRef] = true
+			tableCtx.MergedColumns = append(tableCtx.MergedColumns, rightRef)
+		}
+	}
+
+	star := merged
+	for _, ref := range tableCtx.StarColumns {
+		if !isMerged[ref] {
+			star = append(star, ref)
+		}
+	}
+	tableCtx.StarColumns = star
+
+	return errors
+}
+
+// what select fields and HAVING may reference outside of an aggregate
+type groupingContext struct {
+	Exprs   []Expression // checked GROUP BY expressions
+	Columns map[columnRef]bool
+	// tables whose primary key is grouped, so each of their columns is too
+	Tables map[int]bool
+}
+
+func newGroupingContext(tableCtx TableContext, groupBy []Expression) groupingContext {
+	grouping := groupingContext{
+		Exprs:   groupBy,
+		Columns: map[columnRef]bool{},
+		Tables:  map[int]bool{},
+	}
+
+	for _, expr := range groupBy {
+		if expr.Type != ExpressionTypeLiteral || expr.LiteralType != LiteralTypeFieldName {
+			continue
+		}
+		ref, ok := resolveColumnRef(tableCtx, expr.LiteralField)
+		if !ok {
+			continue
+		}
+		grouping.Columns[ref] = true
+		tableDef := tableCtx.Tables[ref.TableIndex]
+		for _, fieldDef := range tableDef.Fields {
+			if fieldDef.Name == ref.Name && fieldDef.PrimaryKey {
+				grouping.Tables[ref.TableIndex] = true
+			}
+		}
+	}
+
+	return grouping
+}
+
+func (grouping groupingContext) hasColumn(ref columnRef) bool {
+	return grouping.Columns[ref] || grouping.Tables[ref.TableIndex]
+}
+
+func ungroupedFieldError(field Field) CheckError {
+	return CheckError{
+		Err: fmt.Errorf("%w: %s must appear in GROUP BY or be used in an aggregate", ErrUngroupedField, field.Name),
+	}
+}
+
+// checks a select field that isn't an expression, including `*`
+func checkGroupedField(tableCtx TableContext, grouping groupingContext, field Field) []CheckError {
+	if !field.All {
+		ref, ok := resolveColumnRef(tableCtx, field)
+		if ok && !grouping.hasColumn(ref) {
+			return []CheckError{ungroupedFieldError(field)}
+		}
+		return nil
+	}
+
+	var errors []CheckError
+	for i, tableDef := range tableCtx.Tables {
+		if field.TableName != "" && field.TableName != tableCtx.Aliases[i] {
+			continue
+		}
+		for _, fieldDef := range tableDef.Fields {
+			if !grouping.hasColumn(columnRef{TableIndex: i, Name: fieldDef.Name}) {
+				errors = append(errors, ungroupedFieldError(Field{Name: fieldDef.Name}))
+			}
+		}
+	}
+	return errors
+}
+
+// checks that columns are only referenced outside of aggregates if they're grouped.
+// a whole expression is also allowed if it matches one in the GROUP BY.
+func checkGroupedExpr(tableCtx TableContext, grouping groupingContext, expr *Expression) []CheckError {
+	for _, groupExpr := range grouping.Exprs {
+		if reflect.DeepEqual(groupExpr, *expr) {
+			return nil
+		}
+	}
+
+	if isAggregateCall(expr) {
+		return nil
+	}
+
+	if expr.Type == ExpressionTypeLiteral && expr.LiteralType == LiteralTypeFieldName {
+		return checkGroupedField(tableCtx, grouping, expr.LiteralField)
+	}
+
+	var errors []CheckError
+	for _, child := range expr.Children() {
+		errors = append(errors, checkGroupedExpr(tableCtx, grouping, child)...)
+	}
+	return errors
 }
 
 func checkTable(schema Schema, table string) (Table, CheckError) {
@@ -51,7 +706,21 @@ func checkTable(schema Schema, table string) (Table, CheckError) {
 	}
 }
 
+// a column of the nullable side of an outer join, which is NULL when there's
+// no matching row
+func nullableField(fieldDef TableField) TableField {
+	fieldDef.NotNull = false
+	fieldDef.PrimaryKey = false
+	return fieldDef
+}
+
 func checkField(tableCtx TableContext, field Field) (TableField, CheckError) {
+	_, fieldResult, checkErr := checkFieldWithTable(tableCtx, field)
+	return fieldResult, checkErr
+}
+
+// same as checkField, but also returns the table the field belongs to
+func checkFieldWithTable(tableCtx TableContext, field Field) (Table, TableField, CheckError) {
 	// todo: consider not looping
 
 	// if field is qualified, check for that table (or table with that alias)
@@ -60,7 +729,9 @@ func checkField(tableCtx TableContext, field Field) (TableField, CheckError) {
 
 	tableMatchCount := 0
 	fieldMatchCount := 0
+	var tableResult Table
 	var fieldResult TableField
+	isNullable := false
 	for i, tableDef := range tableCtx.Tables {
 		shouldCheckThisTable := false
 		if field.TableName == "" || field.TableName == tableCtx.Aliases[i] {
@@ -71,215 +742,2019 @@ func checkField(tableCtx TableContext, field Field) (TableField, CheckError) {
 			if field.All {
 				// found either a table that matches, or no table qualifier.
 				// nothing else to check
-				return fieldResult, CheckError{}
+				return tableDef, fieldResult, CheckError{}
 			}
 			for _, fieldDef := range tableDef.Fields {
+				if field.TableName == "" && isMergedColumn(tableCtx, columnRef{TableIndex: i, Name: fieldDef.Name}) {
+					continue
+				}
 				if fieldDef.Name == field.Name {
 					fieldMatchCount++
+					tableResult = tableDef
 					fieldResult = fieldDef
+					isNullable = tableCtx.Nullable[i]
+				}
+			}
+		}
+	}
+
+	if fieldMatchCount == 1 {
+		if isNullable {
+			fieldResult = nullableField(fieldResult)
+		}
+		return tableResult, fieldResult, CheckError{}
+	}
+
+	// a subquery's own tables take priority over the enclosing select's
+	if fieldMatchCount == 0 && tableCtx.Outer != nil {
+		outerTable, outerField, checkErr := checkFieldWithTable(*tableCtx.Outer, field)
+		if checkErr.Err == nil {
+			*tableCtx.OuterFields = append(*tableCtx.OuterFields, field)
+			return outerTable, outerField, checkErr
+		}
+	}
+
+	if tableMatchCount == 0 {
+		return Table{}, TableField{}, CheckError{
+			Err: fmt.Errorf("%w: table %s not found", ErrUnknownTable, field.TableName),
+		}
+	}
+
+	if fieldMatchCount > 1 {
+		return Table{}, TableField{}, CheckError{
+			Err: fmt.Errorf("%w: field %s found in multiple tables", ErrAmbiguousField, field.Name),
+		}
+	}
+
+	return Table{}, TableField{}, CheckError{
+		Err: fmt.Errorf("%w: field %s not found", ErrUnknownField, field.Name),
+	}
+}
+
+// expands a select field into the columns it produces.
+// assumes the field has already been checked.
+func checkResultColumns(tableCtx TableContext, field Field) []ResultColumn {
+	var columns []ResultColumn
+
+	if field.All {
+		refs := tableCtx.StarColumns
+		if field.TableName != "" {
+			refs = nil
+			for i, tableDef := range tableCtx.Tables {
+				if field.TableName == tableCtx.Aliases[i] {
+					refs = append(refs, tableColumnRefs(i, tableDef)...)
+				}
+			}
+		}
+		for _, ref := range refs {
+			tableDef := tableCtx.Tables[ref.TableIndex]
+			for _, fieldDef := range tableDef.Fields {
+				if tableCtx.Nullable[ref.TableIndex] {
+					fieldDef = nullableField(fieldDef)
+				}
+				if fieldDef.Name == ref.Name {
+					columns = append(columns, ResultColumn{
+						Name:      fieldDef.Name,
+						TableName: tableDef.Name,
+						Field:     fieldDef,
+					})
+				}
+			}
+		}
+		return columns
+	}
+
+	tableDef, fieldDef, checkErr := checkFieldWithTable(tableCtx, field)
+	if checkErr.Err != nil {
+		return nil
+	}
+
+	name := field.Alias
+	if name == "" {
+		name = field.Name
+	}
+
+	return append(columns, ResultColumn{
+		Name:      name,
+		TableName: tableDef.Name,
+		Field:     fieldDef,
+	})
+}
+
+// the result column for a select field that's an expression. like postgres,
+// a function call is named after the function if there's no alias, and a cast
+// is named after what it casts. other expressions need an alias so the column
+// can be named in the result struct.
+func checkExprResultColumn(field Field) (ResultColumn, CheckError) {
+	named := field.Expr
+	for named.Type == ExpressionTypeCast {
+		named = named.Left
+	}
+
+	name := field.Alias
+	if name == "" && named.Type == ExpressionTypeFunction {
+		name = named.FunctionName
+	}
+	if name == "" && named.Type == ExpressionTypeLiteral && named.LiteralType == LiteralTypeFieldName {
+		name = named.LiteralField.Name
+	}
+	if name == "" {
+		return ResultColumn{}, CheckError{
+			Err: fmt.Errorf("%w: select expressions other than function calls need an alias", ErrMissingAlias),
+		}
+	}
+
+	column := ResultColumn{
+		Name:  name,
+		Field: field.Expr.ValueType,
+	}
+	column.Field.Name = name
+
+	return column, CheckError{}
+}
+
+// table.column type mappings have to name a column in the schema, otherwise
+// a typo would silently leave the column unmapped
+func checkTypeMappings(schema Schema, types TypeMap) []CheckError {
+	var errors []CheckError
+	for _, mapping := range types.Mappings {
+		if mapping.Table == "" {
+			continue
+		}
+		tableDef, checkErr := checkTable(schema, mapping.Table)
+		if checkErr.Err != nil {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: table %s of type mapping %s.%s not found in schema", ErrUnknownTable, mapping.Table, mapping.Table, mapping.Column)})
+			continue
+		}
+		found := false
+		for _, fieldDef := range tableDef.Fields {
+			found = found || fieldDef.Name == mapping.Column
+		}
+		if !found {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: column %s of type mapping %s.%s not found in schema", ErrUnknownField, mapping.Column, mapping.Table, mapping.Column)})
+		}
+	}
+	return errors
+}
+
+// resolves the types of declared struct fields, which can be builtin or
+// mapped types
+func checkTypeDefs(types TypeMap, typeDefs []TypeDef) []CheckError {
+	var errors []CheckError
+
+	seen := map[string]bool{}
+	for _, typeDef := range typeDefs {
+		if seen[typeDef.Name] {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: type %s is declared more than once", ErrInvalidTypeDef, typeDef.Name)})
+		}
+		seen[typeDef.Name] = true
+
+		if len(typeDef.Fields) == 0 {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: type %s has no fields", ErrInvalidTypeDef, typeDef.Name)})
+		}
+		for _, field := range typeDef.Fields {
+			if field.IsList {
+				errors = append(errors, CheckError{Err: fmt.Errorf("%w: field %s of %s can't be a list", ErrInvalidTypeDef, field.Name, typeDef.Name)})
+			}
+		}
+
+		errors = append(errors, checkParamTypes(types, nil, typeDef.Fields)...)
+	}
+
+	return errors
+}
+
+func findTypeDef(typeDefs []TypeDef, name string) (TypeDef, bool) {
+	for _, typeDef := range typeDefs {
+		if typeDef.Name == name {
+			return typeDef, true
+		}
+	}
+	return TypeDef{}, false
+}
+
+func findParam(params []Param, name string) (Param, bool) {
+	for _, param := range params {
+		if param.Name == name {
+			return param, true
+		}
+	}
+	return Param{}, false
+}
+
+// resolves param types that reference a declared type or the type mapping config
+func checkParamTypes(types TypeMap, typeDefs []TypeDef, params []Param) []CheckError {
+	var errors []CheckError
+
+	// ORDER BY declares its own params, which may clash with the query's
+	seen := map[string]bool{}
+	for _, param := range params {
+		if seen[param.Name] {
+			errors = append(errors, CheckError{
+				Err: fmt.Errorf("%w: %s", ErrDuplicateParam, param.Name),
+			})
+		}
+		seen[param.Name] = true
+	}
+
+	for i, param := range params {
+		if param.Type != ParamTypeCustom && param.Type != ParamTypeStruct {
+			continue
+		}
+
+		if typeDef, ok := findTypeDef(typeDefs, param.TypeName); ok {
+			params[i].Type = ParamTypeStruct
+			params[i].Fields = typeDef.Fields
+			continue
+		}
+		// the rows of a bulk insert, whose fields are inferred
+		if param.Type == ParamTypeStruct {
+			continue
+		}
+
+		goTypes := types.LookupName(param.TypeName)
+		if len(goTypes) == 0 {
+			errors = append(errors, CheckError{
+				Err: fmt.Errorf("%w: type %s for param %s is not a builtin or mapped type", ErrUnknownType, param.TypeName, param.Name),
+			})
+			continue
+		}
+		if len(goTypes) > 1 {
+			importPaths := make([]string, 0, len(goTypes))
+			for _, goType := range goTypes {
+				importPaths = append(importPaths, goType.ImportPath)
+			}
+			errors = append(errors, CheckError{
+				Err: fmt.Errorf("%w: type %s for param %s is mapped from more than one package: %s", ErrAmbiguousType, param.TypeName, param.Name, strings.Join(importPaths, ", ")),
+			})
+			continue
+		}
+
+		params[i].GoType = goTypes[0]
+	}
+
+	return errors
+}
+
+func checkFragment(scope Scope, fragmentName string) (Query, CheckError) {
+	// todo: consider not looping
+	for _, fragment := range scope.Fragments {
+		if fragment.Name == fragmentName {
+			return fragment, CheckError{}
+		}
+	}
+	return Query{}, CheckError{
+		Err: fmt.Errorf("%w: fragment %s not found", ErrUnknownFragment, fragmentName),
+	}
+}
+
+func checkParam(scope Scope, field string) (Param, CheckError) {
+	// todo: consider not looping
+	for _, param := range scope.Locals {
+		if param.Name == field {
+			return param, CheckError{}
+		}
+	}
+	for _, param := range scope.QueryParams {
+		if param.Name == field {
+			return param, CheckError{}
+		}
+	}
+	return Param{}, CheckError{
+		Err: fmt.Errorf("%w: param %s not found", ErrUnknownParam, field),
+	}
+}
+
+// This mutates the expression's IsClauseRequired field.
+func checkExpr(tableCtx TableContext, scope Scope, expr *Expression) (*Expression, []CheckError) {
+	var errors []CheckError
+
+	switch expr.Type {
+	case ExpressionTypeForLoop:
+		newScope := scope
+		param, e := checkParam(newScope, expr.ForLoopVarName)
+		if e.Err != nil {
+			errors = append(errors, e)
+		}
+		if !param.IsList {
+			errors = append(errors, CheckError{Err: fmt.Errorf("expected range variable %s to be a list", param.Name)})
+		}
+		expr.ForLoopIsOptional = param.IsList && !param.Required
+
+		// add for loop iterator to local scope for subexpressions
+		LocalIndex++
+		iteratorName := fmt.Sprintf("local%d_%s", LocalIndex, expr.ForLoopIteratorName)
+
+		newScope.Locals = make([]Param, len(scope.Locals)+1)
+		// todo: see about avoiding copies
+		copy(newScope.Locals, scope.Locals)
+		newScope.Locals[len(newScope.Locals)-1] = Param{
+			Name:       expr.ForLoopIteratorName,
+			Type:       param.Type,
+			Required:   true,
+			GlobalName: iteratorName,
+		}
+
+		expr.ForLoopIteratorName = iteratorName
+
+		exprLeft, exprLeftErrors := checkExpr(tableCtx, newScope, expr.Left)
+		expr.Left = exprLeft
+
+		errors = append(errors, exprLeftErrors...)
+
+	case ExpressionTypeIf:
+		for _, elseif := range expr.ElseIfs {
+			ifExpr, ifExprErrs := checkTemplateCondition(tableCtx, scope, elseif.IfExpr)
+			errors = append(errors, ifExprErrs...)
+			elseif.IfExpr = ifExpr
+
+			if elseif.BodyExpr != nil {
+				bodyExpr, bodyExprErrs := checkExpr(tableCtx, scope, elseif.BodyExpr)
+				errors = append(errors, bodyExprErrs...)
+				elseif.BodyExpr = bodyExpr
+			}
+		}
+
+		if expr.ElseBody != nil {
+			bodyExpr, bodyExprErrs := checkExpr(tableCtx, scope, expr.ElseBody)
+			errors = append(errors, bodyExprErrs...)
+			expr.ElseBody = bodyExpr
+		}
+
+	case ExpressionTypeFragment:
+		fragment, e := checkFragment(scope, expr.FragmentName)
+		if e.Err != nil {
+			errors = append(errors, e)
+			return expr, errors
+		}
+
+		if len(fragment.Params) != len(expr.FragmentArgs) {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: number of params do not match", ErrFragmentParamMismatch)})
+			return expr, errors
+		}
+		for i := range fragment.Params {
+			expressionArg, e := checkParam(scope, expr.FragmentArgs[i])
+			if e.Err != nil {
+				errors = append(errors, e)
+			}
+
+			if fragment.Params[i].Type != expressionArg.Type || fragment.Params[i].GoType != expressionArg.GoType {
+				errors = append(errors, CheckError{Err: fmt.Errorf("%w: param type mismatch", ErrFragmentParamMismatch)})
+				return expr, errors
+			}
+			// a fragment that takes a required list wouldn't handle a nil one
+			if fragment.Params[i].IsList != expressionArg.IsList || (expressionArg.IsList && fragment.Params[i].Required && !expressionArg.Required) {
+				errors = append(errors, CheckError{Err: fmt.Errorf("%w: %s needs to be a list of the same type, and can't be optional if the fragment's isn't", ErrFragmentParamMismatch, fragment.Params[i].Name)})
+				return expr, errors
+			}
+		}
+
+		// at this point, fragment is validated.
+		// need to create a mapping between fragment params and global names,
+		// then traverse fragment (getting back an expression), and return this expression to the parent.
+		// parent needs to replace the expression it passed in with any expression returned,
+		// since it could be a fragment call we want to substitute
+
+		newScope := Scope{
+			Fragments:              scope.Fragments,
+			QueryParamToGlobalName: make(map[string]string),
+			Locals:                 []Param{},
+			QueryParams:            fragment.Params,
+		}
+
+		for i, param := range fragment.Params {
+			// todo: this is already called above
+			inputArg, _ := checkParam(scope, expr.FragmentArgs[i])
+			if inputArg.GlobalName == "" {
+				panic("expected global name to be set")
+			}
+			// todo: is this needed, or should we populate the global name of the QueryParams instead
+			newScope.QueryParamToGlobalName[param.Name] = inputArg.GlobalName
+		}
+
+		fragmentExpr, exprErrors := checkExpr(tableCtx, newScope, &fragment.FragmentExpression)
+		errors = append(errors, exprErrors...)
+
+		expr = fragmentExpr
+
+	case ExpressionTypeBinary:
+		exprLeft, exprLeftErrors := checkExpr(tableCtx, scope, expr.Left)
+		exprRight, exprRightErrors := checkExpr(tableCtx, scope, expr.Right)
+
+		errors = append(errors, exprLeftErrors...)
+		errors = append(errors, exprRightErrors...)
+
+		// replace expressions - a fragment may have been substituted by a new expression with rewritten variables
+		expr.Left = exprLeft
+		expr.Right = exprRight
+
+		// list params can only be used as the right side of IN/NOT IN, or be
+		// compared to NULL in a template condition
+		isInOp := expr.Op == OpTypeIn || expr.Op == OpTypeNotIn
+		isNullCheck := (expr.Op == OpTypeIs || expr.Op == OpTypeIsNot) && expr.Right.LiteralType == LiteralTypeNull && scope.IsTemplateCondition
+		isSubquery := expr.Right.Type == ExpressionTypeSubquery && !expr.Right.SubqueryExists
+		if isInOp && !expr.Right.IsListParam && !isSubquery {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: expected list param or subquery on right side of %s", ErrInvalidListParam, expr.Op)})
+		}
+		if (expr.Left.IsListParam && !isNullCheck) || (!isInOp && expr.Right.IsListParam) {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: list params can only be used with IN or NOT IN", ErrInvalidListParam)})
+		}
+		// a required list is never nil, only empty
+		if expr.Left.IsListParam && isNullCheck && expr.Left.IsClauseRequired {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: %s is a required list, so check it with IS EMPTY instead", ErrInvalidListParam, expr.Left.LiteralVariableName)})
+		}
+
+		if expr.Op == OpTypeIs || expr.Op == OpTypeIsNot {
+			switch expr.Right.LiteralType {
+			case LiteralTypeNull, LiteralTypeBool, LiteralTypeUnknown:
+			default:
+				errors = append(errors, CheckError{Err: fmt.Errorf("%w: %s must be followed by NULL, TRUE, FALSE or UNKNOWN", ErrInvalidOperand, expr.Op)})
+			}
+		}
+
+		// todo: other similar validations
+
+		expr.IsClauseRequired = expr.Left.IsClauseRequired || expr.Right.IsClauseRequired
+
+		notNull := expr.Left.ValueType.NotNull && expr.Right.ValueType.NotNull
+		switch expr.Op {
+		case OpTypeAdd, OpTypeSubtract, OpTypeMultiply, OpTypeDivide, OpTypeModulo:
+			if !isArithmeticOperand(expr.Left.ValueType) || !isArithmeticOperand(expr.Right.ValueType) {
+				errors = append(errors, CheckError{Err: fmt.Errorf("%w: %s can't be applied to %s and %s", ErrInvalidOperand, expr.Op, expr.Left.ValueType.Type, expr.Right.ValueType.Type)})
+			}
+			expr.ValueType = expr.Left.ValueType
+			if expr.ValueType.Type == TableFieldTypeNone {
+				expr.ValueType = expr.Right.ValueType
+			}
+			expr.ValueType.NotNull = notNull
+		case OpTypeConcat:
+			expr.ValueType = valueType("text", notNull)
+		case OpTypeIs, OpTypeIsNot, OpTypeIsDistinctFrom, OpTypeIsNotDistinctFrom:
+			expr.ValueType = valueType("boolean", true)
+		default:
+			expr.ValueType = valueType("boolean", notNull)
+		}
+	case ExpressionTypeUnary:
+		operand, operandErrors := checkExpr(tableCtx, scope, expr.Left)
+		errors = append(errors, operandErrors...)
+		expr.Left = operand
+
+		expr.IsClauseRequired = expr.Left.IsClauseRequired
+
+		if expr.Op == OpTypeNot {
+			expr.ValueType = valueType("boolean", expr.Left.ValueType.NotNull)
+		} else {
+			expr.ValueType = expr.Left.ValueType
+		}
+	case ExpressionTypeBetween:
+		exprLeft, exprLeftErrors := checkExpr(tableCtx, scope, expr.Left)
+		exprLower, exprLowerErrors := checkExpr(tableCtx, scope, expr.Right)
+		exprUpper, exprUpperErrors := checkExpr(tableCtx, scope, expr.BetweenUpper)
+
+		errors = append(errors, exprLeftErrors...)
+		errors = append(errors, exprLowerErrors...)
+		errors = append(errors, exprUpperErrors...)
+
+		expr.Left = exprLeft
+		expr.Right = exprLower
+		expr.BetweenUpper = exprUpper
+
+		if expr.Left.IsListParam || expr.Right.IsListParam || expr.BetweenUpper.IsListParam {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: list params can only be used with IN or NOT IN", ErrInvalidListParam)})
+		}
+
+		expr.IsClauseRequired = expr.Left.IsClauseRequired || expr.Right.IsClauseRequired || expr.BetweenUpper.IsClauseRequired
+
+		notNull := expr.Left.ValueType.NotNull && expr.Right.ValueType.NotNull && expr.BetweenUpper.ValueType.NotNull
+		expr.ValueType = valueType("boolean", notNull)
+	case ExpressionTypeFunction:
+		for i := range expr.FunctionArgs {
+			arg, argErrors := checkExpr(tableCtx, scope, &expr.FunctionArgs[i])
+			errors = append(errors, argErrors...)
+			expr.FunctionArgs[i] = *arg
+
+			if arg.IsListParam {
+				errors = append(errors, CheckError{Err: fmt.Errorf("%w: list params can only be used with IN or NOT IN", ErrInvalidListParam)})
+			}
+
+			expr.IsClauseRequired = expr.IsClauseRequired || arg.IsClauseRequired
+		}
+
+		if expr.Over != nil {
+			errors = append(errors, checkWindow(tableCtx, scope, expr.Over)...)
+
+			for i := range expr.FunctionArgs {
+				if containsWindowCall(&expr.FunctionArgs[i]) {
+					errors = append(errors, CheckError{Err: fmt.Errorf("%w: window calls can't be nested", ErrInvalidWindow)})
+				}
+			}
+			if expr.FunctionDistinct {
+				errors = append(errors, CheckError{Err: fmt.Errorf("%w: DISTINCT can't be used in a window call", ErrInvalidWindow)})
+			}
+		}
+
+		f, ok := findBuiltinFunction(expr.FunctionName)
+		if !ok {
+			// untyped, but allowed since the schema may define its own functions
+			expr.ValueType = TableField{}
+			break
+		}
+
+		if f.IsWindow && expr.Over == nil {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: %s needs an OVER clause", ErrInvalidWindow, f.Name)})
+		}
+		if !f.IsWindow && !f.IsAggregate && expr.Over != nil {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: %s isn't a window function or aggregate", ErrInvalidWindow, f.Name)})
+		}
+
+		// count(*) counts rows, so takes the place of its arg
+		argCount := len(expr.FunctionArgs)
+		if expr.FunctionStar {
+			argCount = 1
+			if f.Name != "count" {
+				errors = append(errors, CheckError{Err: fmt.Errorf("%w: only count can be called with *", ErrInvalidFunctionArgs)})
+			}
+		}
+		if argCount < f.MinArgs || (f.MaxArgs >= 0 && argCount > f.MaxArgs) {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: unexpected number of args for %s", ErrInvalidFunctionArgs, f.Name)})
+		}
+		if expr.FunctionDistinct && !f.IsAggregate {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: DISTINCT can only be used in aggregates", ErrInvalidFunctionArgs)})
+		}
+
+		if f.IsAggregate && expr.Over == nil {
+			for i := range expr.FunctionArgs {
+				if containsAggregate(&expr.FunctionArgs[i]) {
+					errors = append(errors, CheckError{Err: fmt.Errorf("%w: aggregate calls can't be nested", ErrInvalidAggregate)})
+				}
+			}
+		}
+
+		hasRows := tableCtx.IsGrouped
+		if expr.Over != nil {
+			// every row is in its own partition, so a frame that has the current row isn't empty
+			frame := resolveWindowFrame(tableCtx, expr.Over)
+			hasRows = frame == nil || frame.IncludesCurrentRow()
+		}
+
+		expr.ValueType = functionValueType(f, expr, hasRows)
+	case ExpressionTypeCase:
+		if expr.CaseOperand != nil {
+			operand, operandErrors := checkExpr(tableCtx, scope, expr.CaseOperand)
+			errors = append(errors, operandErrors...)
+			expr.CaseOperand = operand
+		}
+
+		// the result is typed by the first branch, and can only be not-null
+		// if every branch is not-null, including an else
+		notNull := expr.CaseElse != nil
+		for i, caseWhen := range expr.CaseWhens {
+			when, whenErrors := checkExpr(tableCtx, scope, caseWhen.When)
+			then, thenErrors := checkExpr(tableCtx, scope, caseWhen.Then)
+			errors = append(errors, whenErrors...)
+			errors = append(errors, thenErrors...)
+
+			expr.CaseWhens[i] = CaseWhen{When: when, Then: then}
+
+			if i == 0 {
+				expr.ValueType = then.ValueType
+			}
+			notNull = notNull && then.ValueType.NotNull
+			expr.IsClauseRequired = expr.IsClauseRequired || when.IsClauseRequired || then.IsClauseRequired
+		}
+
+		if expr.CaseElse != nil {
+			elseExpr, elseErrors := checkExpr(tableCtx, scope, expr.CaseElse)
+			errors = append(errors, elseErrors...)
+			expr.CaseElse = elseExpr
+
+			notNull = notNull && elseExpr.ValueType.NotNull
+		}
+
+		expr.ValueType.NotNull = notNull
+	case ExpressionTypeCast:
+		operand, operandErrors := checkExpr(tableCtx, scope, expr.Left)
+		errors = append(errors, operandErrors...)
+		expr.Left = operand
+
+		if expr.Left.IsListParam {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: list params can only be used with IN or NOT IN", ErrInvalidListParam)})
+		}
+
+		expr.IsClauseRequired = expr.Left.IsClauseRequired
+		expr.ValueType = castValueType(expr.CastTypeName, expr.Left.ValueType.NotNull)
+	case ExpressionTypeCursor:
+		// nulls would never compare as greater, so rows would be skipped
+		for _, field := range expr.Paginate.Fields {
+			fieldDef, e := checkField(tableCtx, field)
+			if e.Err != nil {
+				errors = append(errors, e)
+				continue
+			}
+			if !fieldDef.NotNull && !fieldDef.PrimaryKey {
+				errors = append(errors, CheckError{Err: fmt.Errorf("%w: %s must be NOT NULL to paginate by it", ErrInvalidPaginate, field.Name)})
+			}
+		}
+
+		// only applies once there's a cursor
+		expr.IsClauseRequired = false
+		expr.ValueType = valueType("boolean", true)
+	case ExpressionTypeSubquery:
+		columns, subqueryErrs := checkSelect(tableCtx, scope, expr.Subquery, nil)
+		errors = append(errors, subqueryErrs...)
+
+		// optional params inside drop the subquery's own clauses, not the
+		// clause it's used in
+		expr.IsClauseRequired = true
+
+		if expr.SubqueryExists {
+			expr.ValueType = valueType("boolean", true)
+			break
+		}
+		if len(columns) != 1 && len(subqueryErrs) == 0 {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: subquery must select exactly one column, got %d", ErrInvalidSubquery, len(columns))})
+		}
+		if len(columns) > 0 {
+			// null if there are no rows, unless it's an aggregate over every row,
+			// eg (SELECT count(*) ...), which always has one
+			sub := expr.Subquery
+			isSingleRow := isGroupedSelect(sub) && len(sub.GroupBy) == 0 && sub.Having.Type == ExpressionTypeNone &&
+				len(sub.SetOps) == 0 && sub.Limit == nil && sub.Offset == nil
+			expr.ValueType = columns[0].Field
+			expr.ValueType.Name = ""
+			expr.ValueType.NotNull = (expr.ValueType.NotNull || expr.ValueType.PrimaryKey) && isSingleRow
+			expr.ValueType.PrimaryKey = false
+		}
+	case ExpressionTypeListLength:
+		expr.IsClauseRequired = true
+		expr.ValueType = valueType("integer", true)
+
+		list, listErrs := checkExpr(tableCtx, scope, expr.Left)
+		expr.Left = list
+		errors = append(errors, listErrs...)
+
+		isVariable := list.Type == ExpressionTypeLiteral && list.LiteralType == LiteralTypeVariable
+		if len(listErrs) == 0 && (!isVariable || !list.IsListParam) {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: len and IS EMPTY need a list param", ErrInvalidListParam)})
+		}
+	case ExpressionTypeLiteral:
+		switch expr.LiteralType {
+		case LiteralTypeString:
+			typeName := expr.LiteralStringType
+			if typeName == "" {
+				typeName = "text"
+			}
+			expr.ValueType = valueType(typeName, true)
+		case LiteralTypeNumber:
+			expr.ValueType = valueType("integer", true)
+		case LiteralTypeBool:
+			expr.ValueType = valueType("boolean", true)
+		}
+
+		if expr.LiteralType == LiteralTypeFieldName {
+			expr.IsClauseRequired = true
+			fieldDef, e := checkField(tableCtx, expr.LiteralField)
+			if e.Err != nil {
+				errors = append(errors, e)
+			}
+			// the value is only known to be not null, since expressions
+			// that take its type can still be null, eg nullif(id, 5)
+			expr.ValueType = fieldDef
+			expr.ValueType.Name = ""
+			expr.ValueType.NotNull = fieldDef.NotNull || fieldDef.PrimaryKey
+			expr.ValueType.PrimaryKey = false
+		} else if expr.LiteralType == LiteralTypeVariable {
+			param, e := checkParam(scope, expr.LiteralVariableName)
+			if e.Err != nil {
+				errors = append(errors, e)
+			}
+			expr.IsClauseRequired = param.Required
+			expr.IsQueryScopedParam = param.IsQueryScoped
+			expr.IsListParam = param.IsList
+			expr.ValueType = paramValueType(param)
+			expr.LiteralVariableName = param.GlobalName
+			if expr.LiteralVariableName == "" {
+				expr.LiteralVariableName = scope.QueryParamToGlobalName[param.Name]
+			}
+			if expr.LiteralVariableName == "" {
+				panic("expected variable name to be set")
+			}
+
+			if expr.LiteralVariableField != "" && param.Fields != nil {
+				// a declared type. like a param, an optional field drops the
+				// clause when nil, and so does an optional struct
+				field, ok := findParam(param.Fields, expr.LiteralVariableField)
+				if !ok {
+					errors = append(errors, CheckError{Err: fmt.Errorf("%w: %s has no field %s", ErrUnknownParam, param.Name, expr.LiteralVariableField)})
+				}
+				expr.IsClauseRequired = field.Required
+				expr.ValueType = paramValueType(field)
+				if !param.Required {
+					expr.OptionalStructName = expr.LiteralVariableName
+				}
+				expr.LiteralVariableName += "." + expr.LiteralVariableField
+			} else if expr.LiteralVariableField != "" {
+				column, ok := findResultColumn(param.StructFields, expr.LiteralVariableField)
+				if !ok {
+					errors = append(errors, CheckError{Err: fmt.Errorf("%w: %s has no field %s", ErrUnknownParam, param.Name, expr.LiteralVariableField)})
+				}
+				expr.IsClauseRequired = true
+				expr.ValueType = column.Field
+				expr.ValueType.Name = ""
+				expr.ValueType.NotNull = column.Field.NotNull || column.Field.PrimaryKey
+				expr.LiteralVariableName += "." + expr.LiteralVariableField
+			} else if param.Type == ParamTypeStruct {
+				errors = append(errors, CheckError{Err: fmt.Errorf("%w: %s is a struct, use one of its fields", ErrInvalidOperand, param.Name)})
+			}
+		}
+	default:
+		panic("unhandled expression type")
+	}
+
+	return expr, errors
+}
+
+// checks the condition of an {if}, around a clause, a join, a set operation
+// or a locking option. it's written as go rather than sql.
+func checkTemplateCondition(tableCtx TableContext, scope Scope, condition *Expression) (*Expression, []CheckError) {
+	scope.IsTemplateCondition = true
+	condition, errors := checkExpr(tableCtx, scope, condition)
+	if len(errors) == 0 {
+		errors = checkTemplateOperands(condition)
+	}
+	return condition, errors
+}
+
+// template conditions are written as go, so a bare operand, including one
+// under NOT, AND or OR, has to be a bool that can't be nil, eg {if includeDeleted}.
+// anything else needs a comparison, eg {if minID IS NOT NULL}.
+func checkTemplateOperands(condition *Expression) []CheckError {
+	switch condition.Type {
+	case ExpressionTypeUnary:
+		if condition.Op == OpTypeNot {
+			return checkTemplateOperands(condition.Left)
+		}
+	case ExpressionTypeBinary:
+		if condition.Op == OpTypeAnd || condition.Op == OpTypeOr {
+			return append(checkTemplateOperands(condition.Left), checkTemplateOperands(condition.Right)...)
+		}
+		return nil
+	case ExpressionTypeLiteral:
+		switch condition.LiteralType {
+		case LiteralTypeBool:
+			return nil
+		case LiteralTypeVariable:
+			if condition.ValueType.Type == TableFieldTypeBoolean && condition.IsClauseRequired && condition.OptionalStructName == "" && !condition.IsListParam {
+				return nil
+			}
+			return []CheckError{{Err: fmt.Errorf("%w: %s needs to be a required bool to be used as a condition", ErrInvalidOperand, condition.LiteralVariableName)}}
+		}
+	}
+	return []CheckError{{Err: fmt.Errorf("%w: template conditions need to be a bool param or a comparison", ErrInvalidOperand)}}
+}
+
+// limit and offset take a non-negative number, or an int param
+func checkLimitOrOffset(tableCtx TableContext, scope Scope, expr *Expression, clause string) []CheckError {
+	if expr.Type == ExpressionTypeUnary && expr.Op == OpTypeNegate && expr.Left.LiteralType == LiteralTypeNumber {
+		return []CheckError{{Err: fmt.Errorf("%w: %s should not be negative", ErrInvalidOperand, clause)}}
+	}
+
+	if expr.Type == ExpressionTypeLiteral && expr.LiteralType == LiteralTypeNumber {
+		return nil
+	}
+
+	if expr.Type != ExpressionTypeLiteral || expr.LiteralType != LiteralTypeVariable {
+		return []CheckError{{Err: fmt.Errorf("%w: %s expects a number or an int param", ErrInvalidOperand, clause)}}
+	}
+
+	checked, errors := checkExpr(tableCtx, scope, expr)
+	if len(errors) > 0 {
+		return errors
+	}
+	if checked.IsListParam || checked.ValueType.Type != TableFieldTypeInteger {
+		return []CheckError{{Err: fmt.Errorf("%w: %s expects an int param", ErrInvalidOperand, clause)}}
+	}
+	return nil
+}
+
+func findWindow(tableCtx TableContext, name string) (WindowSpec, bool) {
+	for _, window := range tableCtx.Windows {
+		if window.Name == name {
+			return window.Spec, true
+		}
+	}
+	return WindowSpec{}, false
+}
+
+// the frame of a window, which may come from the named window it builds on.
+// nil for the default frame, which always has the current row.
+func resolveWindowFrame(tableCtx TableContext, spec *WindowSpec) *WindowFrame {
+	if spec.Frame != nil || spec.Name == "" {
+		return spec.Frame
+	}
+	base, ok := findWindow(tableCtx, spec.Name)
+	if !ok {
+		return nil
+	}
+	return resolveWindowFrame(tableCtx, &base)
+}
+
+// checks an OVER clause or a WINDOW definition. like postgres, a window
+// that builds on a named one can only add an ORDER BY or a frame to it.
+func checkWindow(tableCtx TableContext, scope Scope, spec *WindowSpec) []CheckError {
+	var errors []CheckError
+
+	if spec.Name != "" {
+		base, ok := findWindow(tableCtx, spec.Name)
+		if !ok {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: %s", ErrUnknownWindow, spec.Name)})
+		} else if !spec.IsNameOnly() {
+			if len(spec.PartitionBy) > 0 {
+				errors = append(errors, CheckError{Err: fmt.Errorf("%w: can't override PARTITION BY of window %s", ErrInvalidWindow, spec.Name)})
+			}
+			if len(spec.OrderBy) > 0 && len(base.OrderBy) > 0 {
+				errors = append(errors, CheckError{Err: fmt.Errorf("%w: can't override ORDER BY of window %s", ErrInvalidWindow, spec.Name)})
+			}
+			if base.Frame != nil {
+				errors = append(errors, CheckError{Err: fmt.Errorf("%w: can't build on window %s, since it has a frame", ErrInvalidWindow, spec.Name)})
+			}
+		}
+	}
+
+	for i := range spec.PartitionBy {
+		expr, exprErrs := checkExpr(tableCtx, scope, &spec.PartitionBy[i])
+		spec.PartitionBy[i] = *expr
+		errors = append(errors, exprErrs...)
+	}
+	for i := range spec.OrderBy {
+		expr, exprErrs := checkExpr(tableCtx, scope, &spec.OrderBy[i].Expr)
+		spec.OrderBy[i].Expr = *expr
+		errors = append(errors, exprErrs...)
+	}
+
+	if spec.Frame != nil {
+		for _, bound := range []*FrameBound{&spec.Frame.Start, spec.Frame.End} {
+			if bound == nil || bound.Offset == nil {
+				continue
+			}
+			offset, offsetErrs := checkExpr(tableCtx, scope, bound.Offset)
+			bound.Offset = offset
+			errors = append(errors, offsetErrs...)
+			if len(offsetErrs) == 0 && !isFrameOffset(*offset) {
+				errors = append(errors, CheckError{Err: fmt.Errorf("%w: frame offsets need to be a literal or a required param", ErrInvalidWindow)})
+			}
+		}
+	}
+
+	return errors
+}
+
+// the offset of a frame bound can't be dropped like a clause, so it can't be
+// an optional param. eg 3, {n} or '1 day'::interval
+func isFrameOffset(offset Expression) bool {
+	for offset.Type == ExpressionTypeCast {
+		offset = *offset.Left
+	}
+	if offset.Type != ExpressionTypeLiteral {
+		return false
+	}
+	switch offset.LiteralType {
+	case LiteralTypeNumber, LiteralTypeString:
+		return true
+	case LiteralTypeVariable:
+		return offset.IsClauseRequired && offset.OptionalStructName == "" && !offset.IsListParam
+	}
+	return false
+}
+
+// a CTE or subquery in FROM as a relation that can be selected from.
+// its columns are typed by its select list, and optionally renamed by names.
+// the table is still returned with an error, named by the select list, so
+// selecting from it doesn't report the same problem again.
+func derivedTable(name string, names []string, columns []ResultColumn) (Table, CheckError) {
+	var checkErr CheckError
+	isNamed := len(names) > 0
+	if isNamed && len(names) != len(columns) {
+		isNamed = false
+		checkErr = CheckError{
+			Err: fmt.Errorf("%w: %s names %d columns but selects %d", ErrColumnCountMismatch, name, len(names), len(columns)),
+		}
+	}
+
+	table := Table{Name: name}
+	for i, col := range columns {
+		field := col.Field
+		field.Name = col.Name
+		if isNamed {
+			field.Name = names[i]
+		}
+		// derived rows aren't known to be unique, so grouping by a column
+		// doesn't group the others
+		field.NotNull = field.NotNull || field.PrimaryKey
+		field.PrimaryKey = false
+		table.Fields = append(table.Fields, field)
+	}
+	return table, checkErr
+}
+
+// adds a relation to the schema, ahead of tables with the same name
+func withTable(schema Schema, table Table) Schema {
+	tables := make([]Table, 0, len(schema.Tables)+1)
+	tables = append(tables, table)
+	schema.Tables = append(tables, schema.Tables...)
+	return schema
+}
+
+// checks each CTE in turn, so a CTE can select from the ones before it, and
+// returns the schema that the main select is checked against
+func checkWith(schema Schema, scope Scope, stmt *SelectStmt) (Schema, []CheckError) {
+	var errors []CheckError
+
+	for i := range stmt.With {
+		cte := &stmt.With[i]
+
+		var self *CommonTableExpr
+		if stmt.WithRecursive {
+			self = cte
+		}
+
+		columns, cteErrs := checkSelect(TableContext{Schema: schema}, scope, &cte.Select, self)
+		errors = append(errors, cteErrs...)
+
+		// a recursive CTE has already reported a problem with its columns
+		table, checkErr := derivedTable(cte.Name, cte.Columns, columns)
+		if checkErr.Err != nil && self == nil {
+			errors = append(errors, checkErr)
+		}
+		schema = withTable(schema, table)
+	}
+
+	return schema, errors
+}
+
+// like postgres, a select is grouped if it has a GROUP BY, HAVING, or
+// aggregates in its select list. then every column referenced outside
+// of an aggregate must be grouped.
+func isGroupedSelect(stmt *SelectStmt) bool {
+	isGrouped := len(stmt.GroupBy) > 0 || stmt.Having.Type > 0
+	for _, f := range stmt.Fields {
+		isGrouped = isGrouped || (f.Expr != nil && containsAggregate(f.Expr))
+	}
+	return isGrouped
+}
+
+// checks a subquery in FROM or JOIN. outer only has the schema, unless
+// it's a lateral join, which can reference the tables before it.
+func checkSubqueryTable(outer TableContext, scope Scope, subquery *SelectStmt, alias string, clause string) (Table, []CheckError) {
+	columns, errors := checkSelect(outer, scope, subquery, nil)
+	if alias == "" {
+		errors = append(errors, CheckError{Err: fmt.Errorf("%w: subqueries in %s need an alias", ErrMissingAlias, clause)})
+	}
+
+	table, _ := derivedTable(alias, nil, columns)
+	return table, errors
+}
+
+// checks a select list, or RETURNING, and returns the columns it produces
+func checkSelectFields(tableCtx TableContext, scope Scope, fields []Field) ([]ResultColumn, []CheckError) {
+	var errors []CheckError
+	var columns []ResultColumn
+
+	for i, f := range fields {
+		if f.Expr != nil {
+			expr, exprErrs := checkExpr(tableCtx, scope, f.Expr)
+			fields[i].Expr = expr
+			errors = append(errors, exprErrs...)
+			errors = append(errors, checkNoOptionalStructs(expr)...)
+
+			column, checkErr := checkExprResultColumn(f)
+			if checkErr.Err != nil {
+				errors = append(errors, checkErr)
+				continue
+			}
+			columns = append(columns, column)
+			continue
+		}
+
+		_, checkErr := checkField(tableCtx, f)
+		if checkErr.Err != nil {
+			errors = append(errors, checkErr)
+			continue
+		}
+		columns = append(columns, checkResultColumns(tableCtx, f)...)
+	}
+
+	return columns, errors
+}
+
+// checks a single select, from the select list through HAVING, and returns
+// the tables it selects from along with its result columns. outer has the
+// schema, and the enclosing select's tables if this is a subquery.
+// ok is false if a table can't be found, since every field would then be reported.
+func checkSelectCore(outer TableContext, scope Scope, stmt *SelectStmt) (TableContext, []ResultColumn, bool, []CheckError) {
+	var errors []CheckError
+
+	schema := outer.Schema
+
+	var tableDef Table
+	var checkErr CheckError
+	if stmt.FromSubquery != nil {
+		var subqueryErrs []CheckError
+		tableDef, subqueryErrs = checkSubqueryTable(TableContext{Schema: schema}, scope, stmt.FromSubquery, stmt.FromAlias, "FROM")
+		errors = append(errors, subqueryErrs...)
+	} else {
+		tableDef, checkErr = checkTable(schema, stmt.From)
+		if checkErr.Err != nil {
+			errors = append(errors, checkErr)
+			// don't continue parsing if table is wrong,
+			// otherwise every field will be considered not found
+			return TableContext{}, nil, false, errors
+		}
+	}
+
+	tableCtx := TableContext{
+		Tables:         []Table{tableDef},
+		Aliases:        []string{stmt.FromAlias},
+		JoinConditions: []*Expression{nil},
+		Nullable:       []bool{false},
+		Schema:         schema,
+		StarColumns:    tableColumnRefs(0, tableDef),
+	}
+	if len(outer.Tables) > 0 {
+		tableCtx.Outer = &outer
+		tableCtx.OuterFields = &stmt.OuterFields
+	}
+
+	// select fields rely on join clause, so process join first
+	for i, j := range stmt.Joins {
+		// joins in the same {if} share their condition, so it's only checked once
+		if j.Condition != nil && (i == 0 || stmt.Joins[i-1].Condition != j.Condition) {
+			condition, conditionErrs := checkTemplateCondition(tableCtx, scope, j.Condition)
+			*j.Condition = *condition
+			errors = append(errors, conditionErrs...)
+		}
+
+		var tableDef Table
+		if j.Subquery != nil {
+			// a lateral subquery is correlated with the tables before it
+			subqueryOuter := TableContext{Schema: schema}
+			if j.Lateral {
+				subqueryOuter = tableCtx
+			}
+			var subqueryErrs []CheckError
+			tableDef, subqueryErrs = checkSubqueryTable(subqueryOuter, scope, j.Subquery, j.TableAlias, "JOIN")
+			errors = append(errors, subqueryErrs...)
+		} else {
+			tableDef, checkErr = checkTable(schema, j.Table)
+			if checkErr.Err != nil {
+				errors = append(errors, checkErr)
+				continue
+			}
+		}
+
+		// a right or full join keeps every row of the joined table, so the
+		// tables before it are NULL when there's no match, and the other way
+		// around for a left or full join
+		if j.JoinType == JoinTypeRight || j.JoinType == JoinTypeFull {
+			tableCtx.Nullable = make([]bool, len(tableCtx.Tables))
+			for k := range tableCtx.Nullable {
+				tableCtx.Nullable[k] = true
+			}
+		}
+		tableCtx.Tables = append(tableCtx.Tables, tableDef)
+		tableCtx.Aliases = append(tableCtx.Aliases, j.TableAlias)
+		tableCtx.JoinConditions = append(tableCtx.JoinConditions, j.Condition)
+		tableCtx.Nullable = append(tableCtx.Nullable, j.JoinType == JoinTypeLeft || j.JoinType == JoinTypeFull)
+		tableCtx.StarColumns = append(tableCtx.StarColumns, tableColumnRefs(len(tableCtx.Tables)-1, tableDef)...)
+
+		if j.Natural {
+			errors = append(errors, mergeJoinColumns(&tableCtx, naturalJoinColumns(tableCtx))...)
+		}
+		if len(j.Using) > 0 {
+			errors = append(errors, mergeJoinColumns(&tableCtx, j.Using)...)
+		}
+		if j.On.Type == ExpressionTypeNone {
+			continue
+		}
+
+		// check conditions with tables defined so far
+		expr, exprErrs := checkExpr(tableCtx, scope, &j.On)
+		stmt.Joins[i].On = *expr
+		errors = append(errors, exprErrs...)
+
+		if containsAggregate(expr) {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: aggregates are not allowed in JOIN conditions", ErrInvalidAggregate)})
+		}
+		if containsWindowCall(expr) {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: window calls are not allowed in JOIN conditions", ErrInvalidWindow)})
+		}
+	}
+
+	tableCtx.IsGrouped = len(stmt.GroupBy) > 0
+
+	// a window can build on the ones defined before it
+	for i, window := range stmt.Windows {
+		if _, ok := findWindow(tableCtx, window.Name); ok {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: window %s is defined more than once", ErrInvalidWindow, window.Name)})
+		}
+		errors = append(errors, checkWindow(tableCtx, scope, &stmt.Windows[i].Spec)...)
+		for _, child := range stmt.Windows[i].Spec.Children() {
+			errors = append(errors, checkNoOptionalStructs(child)...)
+		}
+		tableCtx.Windows = append(tableCtx.Windows, stmt.Windows[i])
+	}
+
+	columns, fieldErrs := checkSelectFields(tableCtx, scope, stmt.Fields)
+	errors = append(errors, fieldErrs...)
+
+	if stmt.Where.Type > 0 {
+		expr, exprErrs := checkExpr(tableCtx, scope, &stmt.Where)
+		stmt.Where = *expr
+		errors = append(errors, exprErrs...)
+
+		if containsAggregate(expr) {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: aggregates are not allowed in WHERE, use HAVING instead", ErrInvalidAggregate)})
+		}
+		if containsWindowCall(expr) {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: window calls are not allowed in WHERE", ErrInvalidWindow)})
+		}
+	}
+
+	for i := range stmt.GroupBy {
+		expr, exprErrs := checkExpr(tableCtx, scope, &stmt.GroupBy[i])
+		stmt.GroupBy[i] = *expr
+		errors = append(errors, exprErrs...)
+		errors = append(errors, checkNoOptionalStructs(expr)...)
+
+		if containsAggregate(expr) {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: aggregates are not allowed in GROUP BY", ErrInvalidAggregate)})
+		}
+		if containsWindowCall(expr) {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: window calls are not allowed in GROUP BY", ErrInvalidWindow)})
+		}
+	}
+
+	if stmt.Having.Type > 0 {
+		expr, exprErrs := checkExpr(tableCtx, scope, &stmt.Having)
+		stmt.Having = *expr
+		errors = append(errors, exprErrs...)
+
+		if containsWindowCall(expr) {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: window calls are not allowed in HAVING", ErrInvalidWindow)})
+		}
+	}
+
+	for i := range stmt.DistinctOn {
+		expr, exprErrs := checkExpr(tableCtx, scope, &stmt.DistinctOn[i])
+		stmt.DistinctOn[i] = *expr
+		errors = append(errors, exprErrs...)
+		errors = append(errors, checkNoOptionalStructs(expr)...)
+	}
+
+	if isGroupedSelect(stmt) {
+		grouping := newGroupingContext(tableCtx, stmt.GroupBy)
+		for _, f := range stmt.Fields {
+			if f.Expr != nil {
+				errors = append(errors, checkGroupedExpr(tableCtx, grouping, f.Expr)...)
+			} else {
+				errors = append(errors, checkGroupedField(tableCtx, grouping, f)...)
+			}
+		}
+		if stmt.Having.Type > 0 {
+			errors = append(errors, checkGroupedExpr(tableCtx, grouping, &stmt.Having)...)
+		}
+		for i := range stmt.Windows {
+			for _, child := range stmt.Windows[i].Spec.Children() {
+				errors = append(errors, checkGroupedExpr(tableCtx, grouping, child)...)
+			}
+		}
+	}
+
+	errors = append(errors, checkConditionalJoins(tableCtx, stmt, columns)...)
+
+	return tableCtx, columns, true, errors
+}
+
+// the tables joined in {if} that a field references
+func conditionalJoinRefs(tableCtx TableContext, field Field) []int {
+	var indexes []int
+	if field.All {
+		for i := range tableCtx.Tables {
+			if tableCtx.JoinConditions[i] != nil && (field.TableName == "" || field.TableName == tableCtx.Aliases[i]) {
+				indexes = append(indexes, i)
+			}
+		}
+		return indexes
+	}
+
+	ref, ok := resolveColumnRef(tableCtx, field)
+	if ok && tableCtx.JoinConditions[ref.TableIndex] != nil {
+		indexes = append(indexes, ref.TableIndex)
+	}
+	return indexes
+}
+
+func exprConditionalJoinRefs(tableCtx TableContext, expr *Expression) []int {
+	if expr.Type == ExpressionTypeLiteral && expr.LiteralType == LiteralTypeFieldName {
+		return conditionalJoinRefs(tableCtx, expr.LiteralField)
+	}
+	if expr.Type == ExpressionTypeSubquery {
+		return subqueryConditionalJoinRefs(tableCtx, expr.Subquery)
+	}
+	var indexes []int
+	for _, child := range expr.Children() {
+		indexes = append(indexes, exprConditionalJoinRefs(tableCtx, child)...)
+	}
+	return indexes
+}
+
+// the tables joined in {if} that a correlated or lateral subquery references
+func subqueryConditionalJoinRefs(tableCtx TableContext, stmt *SelectStmt) []int {
+	var indexes []int
+	for _, field := range stmt.OuterFields {
+		indexes = append(indexes, conditionalJoinRefs(tableCtx, field)...)
+	}
+	for i := range stmt.SetOps {
+		indexes = append(indexes, subqueryConditionalJoinRefs(tableCtx, &stmt.SetOps[i].Select)...)
+	}
+	return indexes
+}
+
+// the optional params in a clause, which drop it when any are nil
+func optionalVarNames(expr *Expression) []string {
+	if expr.Type == ExpressionTypeLiteral && expr.LiteralType == LiteralTypeVariable {
+		var names []string
+		if expr.OptionalStructName != "" {
+			names = append(names, expr.OptionalStructName)
+		}
+		if !expr.IsClauseRequired {
+			names = append(names, expr.LiteralVariableName)
+		}
+		return names
+	}
+	var names []string
+	for _, child := range expr.Children() {
+		names = append(names, optionalVarNames(child)...)
+	}
+	return names
+}
+
+// whether a clause is only written when a join's condition is true, because
+// it's in an {if} with the same condition, or because it's dropped when an
+// optional param is nil and the condition is that the param IS NOT NULL
+func isGuardedBy(condition *Expression, guards []*Expression, optionalVars []string) bool {
+	for _, guard := range guards {
+		if guard == condition || reflect.DeepEqual(*guard, *condition) {
+			return true
+		}
+	}
+
+	isNotNullCheck := condition.Type == ExpressionTypeBinary && condition.Op == OpTypeIsNot &&
+		condition.Left.LiteralType == LiteralTypeVariable && condition.Right.LiteralType == LiteralTypeNull
+	if isNotNullCheck {
+		for _, name := range optionalVars {
+			if name == condition.Left.LiteralVariableName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func conditionalJoinErrors(tableCtx TableContext, indexes []int, guards []*Expression, optionalVars []string) []CheckError {
+	var errors []CheckError
+	reported := map[int]bool{}
+	for _, i := range indexes {
+		if reported[i] || isGuardedBy(tableCtx.JoinConditions[i], guards, optionalVars) {
+			continue
+		}
+		reported[i] = true
+
+		name := tableCtx.Aliases[i]
+		if name == "" {
+			name = tableCtx.Tables[i].Name
+		}
+		errors = append(errors, CheckError{
+			Err: fmt.Errorf("%w: %s is only joined in {if}, so it can only be referenced by clauses with the same condition", ErrConditionalJoin, name),
+		})
+	}
+	return errors
+}
+
+// walks the clauses of a WHERE, HAVING or ON expression, along with the
+// {if} conditions each one is written under
+func checkConditionalJoinClause(tableCtx TableContext, expr *Expression, guards []*Expression) []CheckError {
+	var errors []CheckError
+
+	switch {
+	case expr.Type == ExpressionTypeIf:
+		for _, elseif := range expr.ElseIfs {
+			if elseif.BodyExpr != nil {
+				errors = append(errors, checkConditionalJoinClause(tableCtx, elseif.BodyExpr, append(guards, elseif.IfExpr))...)
+			}
+		}
+		if expr.ElseBody != nil {
+			errors = append(errors, checkConditionalJoinClause(tableCtx, expr.ElseBody, guards)...)
+		}
+		return errors
+	case expr.Type == ExpressionTypeForLoop:
+		return checkConditionalJoinClause(tableCtx, expr.Left, guards)
+	case expr.Type == ExpressionTypeBinary && expr.Op.IsLogical():
+		errors = append(errors, checkConditionalJoinClause(tableCtx, expr.Left, guards)...)
+		errors = append(errors, checkConditionalJoinClause(tableCtx, expr.Right, guards)...)
+		return errors
+	}
+
+	return conditionalJoinErrors(tableCtx, exprConditionalJoinRefs(tableCtx, expr), guards, optionalVarNames(expr))
+}
+
+// tables joined in {if} are only in the query when their condition is true,
+// so the clauses that are always written can't reference them
+func checkConditionalJoins(tableCtx TableContext, stmt *SelectStmt, columns []ResultColumn) []CheckError {
+	hasConditionalJoin := false
+	for _, j := range stmt.Joins {
+		hasConditionalJoin = hasConditionalJoin || j.Condition != nil
+	}
+	if !hasConditionalJoin {
+		return nil
+	}
+
+	var errors []CheckError
+
+	for _, f := range stmt.Fields {
+		if f.Expr != nil {
+			errors = append(errors, checkConditionalJoinClause(tableCtx, f.Expr, nil)...)
+		} else {
+			errors = append(errors, conditionalJoinErrors(tableCtx, conditionalJoinRefs(tableCtx, f), nil, nil)...)
+		}
+	}
+
+	// a join's ON and LATERAL subquery are written with it, so they can
+	// reference joins in the same {if}
+	for i, j := range stmt.Joins {
+		var guards []*Expression
+		if j.Condition != nil {
+			guards = append(guards, j.Condition)
+		}
+		if j.Subquery != nil && j.Lateral {
+			errors = append(errors, conditionalJoinErrors(tableCtx, subqueryConditionalJoinRefs(tableCtx, j.Subquery), guards, nil)...)
+		}
+		if j.On.Type == ExpressionTypeNone {
+			continue
+		}
+		errors = append(errors, checkConditionalJoinClause(tableCtx, &stmt.Joins[i].On, guards)...)
+	}
+
+	var exprs []*Expression
+	if stmt.Where.Type != ExpressionTypeNone {
+		exprs = append(exprs, &stmt.Where)
+	}
+	if stmt.Having.Type != ExpressionTypeNone {
+		exprs = append(exprs, &stmt.Having)
+	}
+	for i := range stmt.GroupBy {
+		exprs = append(exprs, &stmt.GroupBy[i])
+	}
+	for i := range stmt.DistinctOn {
+		exprs = append(exprs, &stmt.DistinctOn[i])
+	}
+	for i := range stmt.Windows {
+		exprs = append(exprs, stmt.Windows[i].Spec.Children()...)
+	}
+	for _, expr := range exprs {
+		errors = append(errors, checkConditionalJoinClause(tableCtx, expr, nil)...)
+	}
+
+	for i, item := range stmt.OrderBy {
+		for _, option := range item.SortOptions {
+			if !isOutputColumn(columns, option) {
+				errors = append(errors, conditionalJoinErrors(tableCtx, conditionalJoinRefs(tableCtx, option), nil, nil)...)
+			}
+		}
+		if item.SortParamName == "" && !(item.Expr.LiteralType == LiteralTypeFieldName && isOutputColumn(columns, item.Expr.LiteralField)) {
+			errors = append(errors, checkConditionalJoinClause(tableCtx, &stmt.OrderBy[i].Expr, nil)...)
+		}
+	}
+
+	return errors
+}
+
+// checks ORDER BY items against the tables selected from, or the select's
+// own result columns by name
+func checkOrderBy(tableCtx TableContext, scope Scope, stmt *SelectStmt, columns []ResultColumn) []CheckError {
+	var errors []CheckError
+
+	var checkErr CheckError
+	for i, item := range stmt.OrderBy {
+		if item.SortParamName != "" {
+			for _, option := range item.SortOptions {
+				if isOutputColumn(columns, option) {
+					continue
+				}
+				_, checkErr = checkField(tableCtx, option)
+				if checkErr.Err != nil {
+					errors = append(errors, checkErr)
+				}
+			}
+			continue
+		}
+
+		if item.Expr.Type == ExpressionTypeLiteral && item.Expr.LiteralType == LiteralTypeFieldName && isOutputColumn(columns, item.Expr.LiteralField) {
+			continue
+		}
+		expr, exprErrs := checkExpr(tableCtx, scope, &stmt.OrderBy[i].Expr)
+		stmt.OrderBy[i].Expr = *expr
+		errors = append(errors, exprErrs...)
+		errors = append(errors, checkNoOptionalStructs(expr)...)
+	}
+
+	return errors
+}
+
+func checkCompoundOrderBy(stmt *SelectStmt, columns []ResultColumn) []CheckError {
+	var errors []CheckError
+
+	for _, item := range stmt.OrderBy {
+		isField := item.Expr.Type == ExpressionTypeLiteral && item.Expr.LiteralType == LiteralTypeFieldName
+
+		fields := item.SortOptions
+		if item.SortParamName == "" && isField {
+			fields = []Field{item.Expr.LiteralField}
+		}
+
+		isValid := item.SortParamName != "" || isField
+		for _, field := range fields {
+			isValid = isValid && isOutputColumn(columns, field)
+		}
+		if !isValid {
+			errors = append(errors, CheckError{
+				Err: fmt.Errorf("%w: ORDER BY on %s must name a result column", ErrInvalidSetOp, stmt.SetOps[0].Type),
+			})
+		}
+	}
+
+	return errors
+}
+
+// checks a select statement and returns its result columns. outer has the
+// schema, and the enclosing select's tables if this is a subquery. self is set for
+// the body of a recursive CTE, which may select from itself after its first select.
+func checkSelect(outer TableContext, scope Scope, stmt *SelectStmt, self *CommonTableExpr) ([]ResultColumn, []CheckError) {
+	schema, errors := checkWith(outer.Schema, scope, stmt)
+	outer.Schema = schema
+
+	tableCtx, columns, ok, coreErrs := checkSelectCore(outer, scope, stmt)
+	errors = append(errors, coreErrs...)
+	if !ok {
+		return nil, errors
+	}
+
+	if self != nil && len(stmt.SetOps) > 0 {
+		table, checkErr := derivedTable(self.Name, self.Columns, columns)
+		if checkErr.Err != nil {
+			errors = append(errors, checkErr)
+		}
+		outer.Schema = withTable(outer.Schema, table)
+	}
+
+	for i, op := range stmt.SetOps {
+		// branches in the same {if} share their condition, so it's only checked once
+		if op.Condition != nil && (i == 0 || stmt.SetOps[i-1].Condition != op.Condition) {
+			condition, conditionErrs := checkTemplateCondition(tableCtx, scope, op.Condition)
+			*op.Condition = *condition
+			errors = append(errors, conditionErrs...)
+		}
+
+		_, opColumns, ok, opErrs := checkSelectCore(outer, scope, &stmt.SetOps[i].Select)
+		errors = append(errors, opErrs...)
+		if !ok {
+			continue
+		}
+		if len(opColumns) != len(columns) {
+			errors = append(errors, CheckError{
+				Err: fmt.Errorf("%w: each side of %s must select the same number of columns", ErrColumnCountMismatch, op.Type),
+			})
+			continue
+		}
+
+		for j := range columns {
+			field, ok := commonValueType(columns[j].Field, opColumns[j].Field)
+			if !ok {
+				errors = append(errors, CheckError{
+					Err: fmt.Errorf("%w: column %s is %s on one side of %s and %s on the other", ErrInvalidSetOp, columns[j].Name, columns[j].Field.Type, op.Type, opColumns[j].Field.Type),
+				})
+				continue
+			}
+			columns[j].Field = field
+		}
+	}
+
+	// like postgres, ORDER BY on a compound select can only name result columns,
+	// since there's no single table to resolve other fields against
+	if len(stmt.SetOps) > 0 {
+		errors = append(errors, checkCompoundOrderBy(stmt, columns)...)
+	} else {
+		errors = append(errors, checkOrderBy(tableCtx, scope, stmt, columns)...)
+	}
+
+	errors = append(errors, checkDistinct(tableCtx, stmt, columns)...)
+
+	if isGroupedSelect(stmt) {
+		grouping := newGroupingContext(tableCtx, stmt.GroupBy)
+		for i, item := range stmt.OrderBy {
+			for _, option := range item.SortOptions {
+				if !isOutputColumn(columns, option) {
+					errors = append(errors, checkGroupedField(tableCtx, grouping, option)...)
+				}
+			}
+			if item.SortParamName == "" && !(item.Expr.LiteralType == LiteralTypeFieldName && isOutputColumn(columns, item.Expr.LiteralField)) {
+				errors = append(errors, checkGroupedExpr(tableCtx, grouping, &stmt.OrderBy[i].Expr)...)
+			}
+		}
+	}
+
+	// the cursor is encoded from the last row, so it needs every column
+	if stmt.Paginate != nil {
+		for _, field := range stmt.Paginate.Fields {
+			if _, ok := findResultColumn(columns, field.Name); !ok {
+				errors = append(errors, CheckError{Err: fmt.Errorf("%w: %s must be selected to paginate by it", ErrInvalidPaginate, field.Name)})
+			}
+		}
+	}
+
+	if stmt.Limit != nil {
+		errors = append(errors, checkLimitOrOffset(tableCtx, scope, stmt.Limit, "limit")...)
+	}
+	if stmt.Offset != nil {
+		errors = append(errors, checkLimitOrOffset(tableCtx, scope, stmt.Offset, "offset")...)
+	}
+
+	for i := range stmt.Locking {
+		errors = append(errors, checkLocking(tableCtx, scope, stmt, &stmt.Locking[i])...)
+	}
+
+	return columns, errors
+}
+
+// like postgres, rows can only be locked when each result row comes from a
+// single row of each table, and OF has to name tables in the query
+func checkLocking(tableCtx TableContext, scope Scope, stmt *SelectStmt, clause *LockingClause) []CheckError {
+	var errors []CheckError
+
+	hasWindowCall := false
+	for _, f := range stmt.Fields {
+		hasWindowCall = hasWindowCall || (f.Expr != nil && containsWindowCall(f.Expr))
+	}
+
+	var reason string
+	switch {
+	case len(stmt.SetOps) > 0:
+		reason = stmt.SetOps[0].Type.String()
+	case stmt.Distinct:
+		reason = "DISTINCT"
+	case isGroupedSelect(stmt):
+		reason = "GROUP BY or aggregates"
+	case hasWindowCall:
+		reason = "window functions"
+	}
+	if reason != "" {
+		errors = append(errors, CheckError{Err: fmt.Errorf("%w: %s can't be used with %s", ErrInvalidLock, clause.Strength, reason)})
+	}
+
+	// like Postgres, rows that may be NULL from an outer join can't be locked,
+	// and without OF every table is locked
+	if len(clause.Of) == 0 {
+		for _, isNullable := range tableCtx.Nullable {
+			if isNullable {
+				errors = append(errors, CheckError{Err: fmt.Errorf("%w: %s can't be applied to the nullable side of an outer join, so it needs OF with the other tables", ErrInvalidLock, clause.Strength)})
+				break
+			}
+		}
+	}
+
+	// the locking clause is always written, so it can't name a conditional join
+	for _, name := range clause.Of {
+		found := false
+		isNullable := false
+		var conditional []int
+		for i, tableDef := range tableCtx.Tables {
+			if name == tableCtx.Aliases[i] || (tableCtx.Aliases[i] == "" && name == tableDef.Name) {
+				found = true
+				isNullable = isNullable || tableCtx.Nullable[i]
+				if tableCtx.JoinConditions[i] != nil {
+					conditional = append(conditional, i)
+				}
+			}
+		}
+		if !found {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: table %s in %s OF not found", ErrUnknownTable, name, clause.Strength)})
+		}
+		if isNullable {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: %s can't be applied to table %s on the nullable side of an outer join", ErrInvalidLock, clause.Strength, name)})
+		}
+		errors = append(errors, conditionalJoinErrors(tableCtx, conditional, nil, nil)...)
+	}
+
+	if clause.WaitCondition != nil {
+		condition, conditionErrs := checkTemplateCondition(tableCtx, scope, clause.WaitCondition)
+		*clause.WaitCondition = *condition
+		errors = append(errors, conditionErrs...)
+	}
+
+	return errors
+}
+
+// whether the columns are exactly a primary key or unique constraint, in any order
+func isTableKey(tableDef Table, columns []string) bool {
+	for _, key := range tableDef.Keys {
+		if len(key) != len(columns) {
+			continue
+		}
+
+		matches := 0
+		for _, name := range key {
+			for _, column := range columns {
+				if column == name {
+					matches++
+					break
 				}
 			}
 		}
+		if matches == len(key) {
+			return true
+		}
 	}
+	return false
+}
 
-	if tableMatchCount == 0 {
-		return TableField{}, CheckError{
-			Err: fmt.Errorf("%w: table %s not found", ErrUnknownTable, field.TableName),
-		}
+// checks an insert, and returns the columns of RETURNING along with the fields
+// of the struct generated for the rows of {foreach row in rows: ,}
+func checkInsert(schema Schema, scope Scope, stmt *InsertStmt) ([]ResultColumn, []ResultColumn, []CheckError) {
+	var errors []CheckError
+
+	tableDef, checkErr := checkTable(schema, stmt.Table)
+	if checkErr.Err != nil {
+		return nil, nil, append(errors, checkErr)
 	}
 
-	if fieldMatchCount == 1 {
-		return fieldResult, CheckError{}
+	tableCtx := TableContext{
+		Tables:         []Table{tableDef},
+		Aliases:        []string{stmt.Table},
+		JoinConditions: []*Expression{nil},
+		Nullable:       []bool{false},
+		Schema:         schema,
+		StarColumns:    tableColumnRefs(0, tableDef),
 	}
 
-	if fieldMatchCount > 1 {
-		return TableField{}, CheckError{
-			Err: fmt.Errorf("%w: field %s found in multiple tables", ErrAmbiguousField, field.Name),
+	columnCount := len(tableDef.Fields)
+	if len(stmt.Columns) > 0 {
+		columnCount = len(stmt.Columns)
+	}
+	for _, name := range stmt.Columns {
+		if _, checkErr := checkField(tableCtx, Field{Name: name}); checkErr.Err != nil {
+			errors = append(errors, checkErr)
 		}
 	}
 
-	return TableField{}, CheckError{
-		Err: fmt.Errorf("%w: field %s not found", ErrUnknownField, field.Name),
+	rowScope := scope
+	var rowFields []ResultColumn
+	var iteratorName string
+	if stmt.ValuesLoop != nil {
+		iteratorName = stmt.ValuesLoop.IteratorName
+
+		columnNames := stmt.Columns
+		if len(columnNames) == 0 {
+			for _, fieldDef := range tableDef.Fields {
+				columnNames = append(columnNames, fieldDef.Name)
+			}
+		}
+
+		var loopErrs []CheckError
+		rowScope, rowFields, loopErrs = checkValuesLoop(tableDef, scope, stmt, columnNames)
+		errors = append(errors, loopErrs...)
 	}
-}
 
-func checkFragment(scope Scope, fragmentName string) (Query, CheckError) {
-	// todo: consider not looping
-	for _, fragment := range scope.Fragments {
-		if fragment.Name == fragmentName {
-			return fragment, CheckError{}
+	// values can't reference the table being inserted into
+	valuesCtx := TableContext{Schema: schema}
+	for i, row := range stmt.Values {
+		if len(row) != columnCount {
+			errors = append(errors, CheckError{
+				Err: fmt.Errorf("%w: row %d of VALUES has %d values for %d columns", ErrColumnCountMismatch, i+1, len(row), columnCount),
+			})
+		}
+		for j := range row {
+			expr, exprErrs := checkExpr(valuesCtx, rowScope, &row[j])
+			row[j] = *expr
+			errors = append(errors, exprErrs...)
+			errors = append(errors, checkNoOptionalStructs(expr)...)
 		}
 	}
-	return Query{}, CheckError{
-		Err: fmt.Errorf("%w: fragment %s not found", ErrUnknownFragment, fragmentName),
+
+	// ON CONFLICT is written once, after every row
+	if iteratorName != "" && stmt.OnConflict != nil {
+		exprs := []*Expression{&stmt.OnConflict.Where}
+		for i := range stmt.OnConflict.Set {
+			exprs = append(exprs, &stmt.OnConflict.Set[i].Value)
+		}
+		for _, expr := range exprs {
+			for _, name := range rowFieldNames(expr, iteratorName) {
+				errors = append(errors, CheckError{
+					Err: fmt.Errorf("%w: ON CONFLICT applies to every row, so it can't reference %s.%s, use excluded instead", ErrInvalidOperand, iteratorName, name),
+				})
+			}
+		}
+	}
+
+	if stmt.OnConflict != nil {
+		errors = append(errors, checkOnConflict(tableCtx, rowScope, stmt.OnConflict)...)
 	}
+
+	columns, returningErrs := checkSelectFields(tableCtx, scope, stmt.Returning)
+	errors = append(errors, returningErrs...)
+
+	return columns, rowFields, errors
 }
 
-func checkParam(scope Scope, field string) (Param, CheckError) {
-	// todo: consider not looping
-	for _, param := range scope.Locals {
-		if param.Name == field {
-			return param, CheckError{}
-		}
+// excluded is a keyword in postgres, so it can be written in any case
+func normalizeExcluded(expr *Expression) {
+	if expr.Type == ExpressionTypeLiteral && expr.LiteralType == LiteralTypeFieldName && strings.EqualFold(expr.LiteralField.TableName, "excluded") {
+		expr.LiteralField.TableName = "excluded"
 	}
-	for _, param := range scope.QueryParams {
-		if param.Name == field {
-			return param, CheckError{}
-		}
+	for _, child := range expr.Children() {
+		normalizeExcluded(child)
 	}
-	return Param{}, CheckError{
-		Err: fmt.Errorf("%w: param %s not found", ErrUnknownParam, field),
+}
+
+// values and field lists, like the select list and ORDER BY, are written as
+// they are, where a nil param is NULL, so there's no clause to drop when an
+// optional struct is nil
+func checkNoOptionalStructs(expr *Expression) []CheckError {
+	if expr.OptionalStructName != "" {
+		return []CheckError{{Err: fmt.Errorf("%w: %s is a field of an optional struct, which can only be used in a condition", ErrInvalidOperand, expr.LiteralVariableName)}}
+	}
+	var errors []CheckError
+	for _, child := range expr.Children() {
+		errors = append(errors, checkNoOptionalStructs(child)...)
 	}
+	return errors
 }
 
-// This mutates the expression's IsClauseRequired field.
-func checkExpr(tableCtx TableContext, scope Scope, expr *Expression) (*Expression, []CheckError) {
+// generates the struct for the rows of {foreach row in rows: ,}, with a field
+// for each {row.field}, typed by the column it's inserted into, or by the
+// column of the expression it's in. returns the scope the row is checked in,
+// which has the iterator, along with the struct's fields.
+func checkValuesLoop(tableDef Table, scope Scope, stmt *InsertStmt, columnNames []string) (Scope, []ResultColumn, []CheckError) {
+	loop := stmt.ValuesLoop
+
 	var errors []CheckError
+	var structFields []ResultColumn
+	var fields []Param
+	var typeName string
+	iteratorType := ParamTypeNone
 
-	switch expr.Type {
-	case ExpressionTypeForLoop:
-		newScope := scope
-		param, e := checkParam(newScope, expr.ForLoopVarName)
-		if e.Err != nil {
-			errors = append(errors, e)
-		}
-		if !param.IsList {
-			errors = append(errors, CheckError{Err: fmt.Errorf("expected range variable %s to be a list", param.Name)})
+	paramIndex := -1
+	for i, param := range scope.QueryParams {
+		if param.Name == loop.ListName {
+			paramIndex = i
 		}
+	}
 
-		// add for loop iterator to local scope for subexpressions
-		LocalIndex++
-		iteratorName := fmt.Sprintf("local%d_%s", LocalIndex, expr.ForLoopIteratorName)
+	if paramIndex == -1 {
+		errors = append(errors, CheckError{Err: fmt.Errorf("%w: param %s not found", ErrUnknownParam, loop.ListName)})
+	} else if param := scope.QueryParams[paramIndex]; !param.IsList || param.Type != ParamTypeStruct {
+		errors = append(errors, CheckError{Err: fmt.Errorf("%w: %s needs to be a list of a struct type, eg [AuthorInsert]", ErrInvalidListParam, param.Name)})
+		iteratorType = param.Type
+	} else if param.Fields != nil {
+		// a declared type, so there's nothing to infer
+		fields = param.Fields
+		typeName = param.TypeName
+		iteratorType = ParamTypeStruct
+	} else {
+		typeName = param.TypeName
+		iteratorType = ParamTypeStruct
 
-		newScope.Locals = make([]Param, len(scope.Locals)+1)
-		// todo: see about avoiding copies
-		copy(newScope.Locals, scope.Locals)
-		newScope.Locals[len(newScope.Locals)-1] = Param{
-			Name:       expr.ForLoopIteratorName,
-			Type:       param.Type,
-			Required:   true,
-			GlobalName: iteratorName,
+		// the expressions of the row, and the column each one is for
+		var values []*Expression
+		var valueColumns []string
+		for i := range stmt.Values[0] {
+			if i < len(columnNames) {
+				values = append(values, &stmt.Values[0][i])
+				valueColumns = append(valueColumns, columnNames[i])
+			}
+		}
+		if stmt.OnConflict != nil {
+			for i, set := range stmt.OnConflict.Set {
+				values = append(values, &stmt.OnConflict.Set[i].Value)
+				valueColumns = append(valueColumns, set.Column)
+			}
 		}
 
-		expr.ForLoopIteratorName = iteratorName
+		// fields that are values on their own are typed first, since their
+		// column is their exact type
+		for _, isValue := range []bool{true, false} {
+			for i, value := range values {
+				if (value.Type == ExpressionTypeLiteral && value.LiteralType == LiteralTypeVariable) != isValue {
+					continue
+				}
+				for _, name := range rowFieldNames(value, loop.IteratorName) {
+					if _, ok := findResultColumn(structFields, name); ok {
+						continue
+					}
+					for _, fieldDef := range tableDef.Fields {
+						if fieldDef.Name == valueColumns[i] {
+							structFields = append(structFields, ResultColumn{
+								Name:      name,
+								TableName: tableDef.Name,
+								Field:     fieldDef,
+							})
+						}
+					}
+				}
+			}
+		}
+	}
 
-		exprLeft, exprLeftErrors := checkExpr(tableCtx, newScope, expr.Left)
-		expr.Left = exprLeft
+	LocalIndex++
+	iteratorName := fmt.Sprintf("local%d_%s", LocalIndex, loop.IteratorName)
 
-		errors = append(errors, exprLeftErrors...)
+	rowScope := scope
+	rowScope.Locals = make([]Param, len(scope.Locals)+1)
+	copy(rowScope.Locals, scope.Locals)
+	rowScope.Locals[len(rowScope.Locals)-1] = Param{
+		Name:         loop.IteratorName,
+		Type:         iteratorType,
+		TypeName:     typeName,
+		Required:     true,
+		GlobalName:   iteratorName,
+		StructFields: structFields,
+		Fields:       fields,
+	}
 
-	case ExpressionTypeIf:
-		for _, elseif := range expr.ElseIfs {
-			ifExpr, ifExprErrs := checkExpr(tableCtx, scope, elseif.IfExpr)
-			errors = append(errors, ifExprErrs...)
-			elseif.IfExpr = ifExpr
+	loop.IteratorName = iteratorName
 
-			if elseif.BodyExpr != nil {
-				bodyExpr, bodyExprErrs := checkExpr(tableCtx, scope, elseif.BodyExpr)
-				errors = append(errors, bodyExprErrs...)
-				elseif.BodyExpr = bodyExpr
-			}
-		}
+	return rowScope, structFields, errors
+}
 
-		if expr.ElseBody != nil {
-			bodyExpr, bodyExprErrs := checkExpr(tableCtx, scope, expr.ElseBody)
-			errors = append(errors, bodyExprErrs...)
-			expr.ElseBody = bodyExpr
+// the fields of every {row.field} in an expression
+func rowFieldNames(expr *Expression, iteratorName string) []string {
+	if expr.Type == ExpressionTypeLiteral && expr.LiteralType == LiteralTypeVariable {
+		if expr.LiteralVariableName == iteratorName && expr.LiteralVariableField != "" {
+			return []string{expr.LiteralVariableField}
 		}
+		return nil
+	}
+	var names []string
+	for _, child := range expr.Children() {
+		names = append(names, rowFieldNames(child, iteratorName)...)
+	}
+	return names
+}
 
-	case ExpressionTypeFragment:
-		fragment, e := checkFragment(scope, expr.FragmentName)
-		if e.Err != nil {
-			errors = append(errors, e)
-			return expr, errors
-		}
+// the conflict target has to match a key, like postgres, and DO UPDATE can
+// reference the row that was proposed for insertion as excluded
+func checkOnConflict(tableCtx TableContext, scope Scope, onConflict *OnConflict) []CheckError {
+	var errors []CheckError
 
-		if len(fragment.Params) != len(expr.FragmentArgs) {
-			errors = append(errors, CheckError{Err: fmt.Errorf("%w: number of params do not match", ErrFragmentParamMismatch)})
-			return expr, errors
-		}
-		for i := range fragment.Params {
-			expressionArg, e := checkParam(scope, expr.FragmentArgs[i])
-			if e.Err != nil {
-				errors = append(errors, e)
-			}
+	tableDef := tableCtx.Tables[0]
 
-			if fragment.Params[i].Type != expressionArg.Type {
-				errors = append(errors, CheckError{Err: fmt.Errorf("%w: param type mismatch", ErrFragmentParamMismatch)})
-				return expr, errors
-			}
+	targetFound := true
+	for _, name := range onConflict.Target {
+		if _, checkErr := checkField(tableCtx, Field{Name: name}); checkErr.Err != nil {
+			errors = append(errors, checkErr)
+			targetFound = false
 		}
+	}
+	if targetFound && len(onConflict.Target) > 0 && !isTableKey(tableDef, onConflict.Target) {
+		errors = append(errors, CheckError{
+			Err: fmt.Errorf("%w: (%s) isn't a primary key or unique constraint of %s", ErrInvalidConflictTarget, strings.Join(onConflict.Target, ", "), tableDef.Name),
+		})
+	}
+	if onConflict.DoUpdate && len(onConflict.Target) == 0 {
+		errors = append(errors, CheckError{
+			Err: fmt.Errorf("%w: ON CONFLICT DO UPDATE needs a conflict target", ErrInvalidConflictTarget),
+		})
+	}
 
-		// at this point, fragment is validated.
-		// need to create a mapping between fragment params and global names,
-		// then traverse fragment (getting back an expression), and return this expression to the parent.
-		// parent needs to replace the expression it passed in with any expression returned,
-		// since it could be a fragment call we want to substitute
-
-		newScope := Scope{
-			Fragments:              scope.Fragments,
-			QueryParamToGlobalName: make(map[string]string),
-			Locals:                 []Param{},
-			QueryParams:            fragment.Params,
-		}
+	// unqualified names resolve to the table, like postgres
+	excludedCtx := tableCtx
+	excludedCtx.Tables = []Table{tableDef, tableDef}
+	excludedCtx.Aliases = []string{tableCtx.Aliases[0], "excluded"}
+	excludedCtx.JoinConditions = []*Expression{nil, nil}
+	excludedCtx.Nullable = []bool{false, false}
+	excludedCtx.MergedColumns = tableColumnRefs(1, tableDef)
 
-		for i, param := range fragment.Params {
-			// todo: this is already called above
-			inputArg, _ := checkParam(scope, expr.FragmentArgs[i])
-			if inputArg.GlobalName == "" {
-				panic("expected global name to be set")
-			}
-			// todo: is this needed, or should we populate the global name of the QueryParams instead
-			newScope.QueryParamToGlobalName[param.Name] = inputArg.GlobalName
+	for i, set := range onConflict.Set {
+		if _, checkErr := checkField(tableCtx, Field{Name: set.Column}); checkErr.Err != nil {
+			errors = append(errors, checkErr)
 		}
 
-		fragmentExpr, exprErrors := checkExpr(tableCtx, newScope, &fragment.FragmentExpression)
-		errors = append(errors, exprErrors...)
+		normalizeExcluded(&onConflict.Set[i].Value)
+		expr, exprErrs := checkExpr(excludedCtx, scope, &onConflict.Set[i].Value)
+		onConflict.Set[i].Value = *expr
+		errors = append(errors, exprErrs...)
+		errors = append(errors, checkNoOptionalStructs(expr)...)
+	}
 
-		expr = fragmentExpr
+	if onConflict.Where.Type != ExpressionTypeNone {
+		normalizeExcluded(&onConflict.Where)
+		expr, exprErrs := checkExpr(excludedCtx, scope, &onConflict.Where)
+		onConflict.Where = *expr
+		errors = append(errors, exprErrs...)
+	}
 
-	case ExpressionTypeBinary:
-		exprLeft, exprLeftErrors := checkExpr(tableCtx, scope, expr.Left)
-		exprRight, exprRightErrors := checkExpr(tableCtx, scope, expr.Right)
+	return errors
+}
 
-		errors = append(errors, exprLeftErrors...)
-		errors = append(errors, exprRightErrors...)
+// the columns of a copy are the fields of the rows it loads
+func checkCopy(schema Schema, stmt CopyStmt) ([]ResultColumn, []CheckError) {
+	var errors []CheckError
 
-		// replace expressions - a fragment may have been substituted by a new expression with rewritten variables
-		expr.Left = exprLeft
-		expr.Right = exprRight
+	tableDef, checkErr := checkTable(schema, stmt.Table)
+	if checkErr.Err != nil {
+		return nil, append(errors, checkErr)
+	}
 
-		// todo: validate that IS/IS NOT is only with true, false, or null etc
-		// and other similar validations
+	names := stmt.Columns
+	if len(names) == 0 {
+		for _, fieldDef := range tableDef.Fields {
+			names = append(names, fieldDef.Name)
+		}
+	}
 
-		expr.IsClauseRequired = expr.Left.IsClauseRequired || expr.Right.IsClauseRequired
-	case ExpressionTypeLiteral:
-		if expr.LiteralType == LiteralTypeFieldName {
-			expr.IsClauseRequired = true
-			_, e := checkField(tableCtx, expr.LiteralField)
-			if e.Err != nil {
-				errors = append(errors, e)
+	var columns []ResultColumn
+	for _, name := range names {
+		found := false
+		for _, fieldDef := range tableDef.Fields {
+			if fieldDef.Name == name {
+				found = true
+				columns = append(columns, ResultColumn{
+					Name:      name,
+					TableName: tableDef.Name,
+					Field:     fieldDef,
+				})
 			}
-		} else if expr.LiteralType == LiteralTypeVariable {
-			param, e := checkParam(scope, expr.LiteralVariableName)
-			if e.Err != nil {
-				errors = append(errors, e)
+		}
+		if !found {
+			errors = append(errors, CheckError{
+				Err: fmt.Errorf("%w: field %s not found in %s", ErrUnknownField, name, tableDef.Name),
+			})
+		}
+	}
+
+	return columns, errors
+}
+
+// result columns are the fields of the row struct, so their names have to be
+// unique, eg the ids of a self join need an alias
+func checkUniqueResultColumns(columns []ResultColumn) []CheckError {
+	var errors []CheckError
+	count := map[string]int{}
+	for _, col := range columns {
+		count[col.Name]++
+		if count[col.Name] == 2 {
+			errors = append(errors, CheckError{Err: fmt.Errorf("%w: column %s is selected more than once, so it needs an alias", ErrMissingAlias, col.Name)})
+		}
+	}
+	return errors
+}
+
+// inferred row structs are declared once per file, so queries sharing a type
+// name have to infer the same fields for it
+func checkInferredTypes(queries []Query) []CheckError {
+	var errors []CheckError
+	type inferred struct {
+		queryName string
+		fields    []ResultColumn
+	}
+	seen := map[string]inferred{}
+	for _, q := range queries {
+		for _, param := range q.Params {
+			if param.Type != ParamTypeStruct || param.Fields != nil || param.StructFields == nil {
+				continue
 			}
-			expr.IsClauseRequired = param.Required
-			expr.IsQueryScopedParam = param.IsQueryScoped
-			expr.LiteralVariableName = param.GlobalName
-			if expr.LiteralVariableName == "" {
-				expr.LiteralVariableName = scope.QueryParamToGlobalName[param.Name]
+			prev, ok := seen[param.TypeName]
+			if !ok {
+				seen[param.TypeName] = inferred{queryName: q.Name, fields: param.StructFields}
+				continue
 			}
-			if expr.LiteralVariableName == "" {
-				panic("expected variable name to be set")
+			if !sameResultColumns(prev.fields, param.StructFields) {
+				errors = append(errors, CheckError{Err: fmt.Errorf("%w: %s is inferred with different fields by %s and %s, so it needs a type declaration or a different name", ErrInvalidTypeDef, param.TypeName, prev.queryName, q.Name)})
 			}
 		}
-	default:
-		panic("unhandled expression type")
 	}
+	return errors
+}
 
-	return expr, errors
+func sameResultColumns(a, b []ResultColumn) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
 }
 
 func checkQuery(schema Schema, fragments []Query, query *Query) []CheckError {
@@ -293,77 +2768,51 @@ func checkQuery(schema Schema, fragments []Query, query *Query) []CheckError {
 
 	switch query.StatementType {
 	case StatementTypeSelect:
+		columns, selectErrs := checkSelect(TableContext{Schema: schema}, scope, &query.Select, nil)
+		query.ResultColumns = columns
+		errors = append(errors, selectErrs...)
 
-		currentTable := query.Select.From
-		tableDef, checkErr := checkTable(schema, currentTable)
-		if checkErr.Err != nil {
-			errors = append(errors, checkErr)
-			// don't continue parsing if table is wrong,
-			// otherwise every field will be considered not found
-			return errors
-		}
-
-		tableCtx := TableContext{
-			Tables:  []Table{tableDef},
-			Aliases: []string{query.Select.FromAlias},
-		}
-
-		// select fields rely on join clause, so process join first
-		for i, j := range query.Select.Joins {
-			// note: join type is not currently used in checker
-
-			tableDef, checkErr := checkTable(schema, j.Table)
-			if checkErr.Err != nil {
-				errors = append(errors, checkErr)
-				continue
-			}
-
-			tableCtx.Tables = append(tableCtx.Tables, tableDef)
-			tableCtx.Aliases = append(tableCtx.Aliases, j.TableAlias)
-
-			// check conditions with tables defined so far
-			expr, exprErrs := checkExpr(tableCtx, scope, &j.On)
-			query.Select.Joins[i].On = *expr
-			errors = append(errors, exprErrs...)
-		}
-
-		for _, f := range query.Select.Fields {
-			_, checkErr = checkField(tableCtx, f)
-			if checkErr.Err != nil {
-				errors = append(errors, checkErr)
-			}
-		}
-
-		if query.Select.Where.Type > 0 {
-			expr, exprErrs := checkExpr(tableCtx, scope, &query.Select.Where)
-			query.Select.Where = *expr
-			errors = append(errors, exprErrs...)
-		}
-
-		if query.Select.Limit != nil && *query.Select.Limit < 0 {
-			errors = append(errors, CheckError{Err: fmt.Errorf("limit should not be negative")})
-		}
+	case StatementTypeInsert:
+		columns, rowFields, insertErrs := checkInsert(schema, scope, &query.Insert)
+		query.ResultColumns = columns
+		errors = append(errors, insertErrs...)
 
-		if len(query.Select.OrderByFields) > 0 {
-			for _, f := range query.Select.OrderByFields {
-				_, checkErr = checkField(tableCtx, f)
-				if checkErr.Err != nil {
-					errors = append(errors, checkErr)
+		if rowFields != nil {
+			for i, param := range query.Params {
+				if param.Name == query.Insert.ValuesLoop.ListName {
+					query.Params[i].StructFields = rowFields
 				}
 			}
 		}
 
+	case StatementTypeCopy:
+		columns, copyErrs := checkCopy(schema, query.Copy)
+		query.ResultColumns = columns
+		errors = append(errors, copyErrs...)
+
 	default:
 		panic("")
 	}
 
+	if query.StatementType != StatementTypeCopy {
+		errors = append(errors, checkUniqueResultColumns(query.ResultColumns)...)
+	}
+
 	return errors
 
 }
 
-func CheckQueries(schema Schema, queries Queries) []CheckError {
+func CheckQueries(schema Schema, queries Queries, types TypeMap) []CheckError {
 	var errors []CheckError
 
+	errors = append(errors, checkTypeMappings(schema, types)...)
+	errors = append(errors, checkTypeDefs(types, queries.Types)...)
+
+	// resolve param types first, since fragments are copied into each query's scope
+	for _, q := range queries.Queries {
+		errors = append(errors, checkParamTypes(types, queries.Types, q.Params)...)
+	}
+
 	fragments := make([]Query, 0, len(queries.Queries))
 	for _, q := range queries.Queries {
 		if q.IsFragment {
@@ -379,5 +2828,7 @@ func CheckQueries(schema Schema, queries Queries) []CheckError {
 		errors = append(errors, checkQuery(schema, fragments, &queries.Queries[i])...)
 	}
 
+	errors = append(errors, checkInferredTypes(queries.Queries)...)
+
 	return errors
 }
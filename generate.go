@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"go/format"
+	"sort"
 	"strings"
 )
 
@@ -10,6 +11,22 @@ import (
 type Generator struct {
 	PackageName string
 	Schema      Schema
+	Types       TypeMap
+	InListStyle InListStyle
+
+	// import paths needed by mapped types, collected while writing a query
+	Imports map[string]bool
+	// types written to the file so far, which its queries can share, like
+	// declared struct params and the interfaces of copy
+	Declared map[string]bool
+
+	// optional params in select fields and GROUP BY can't drop the field,
+	// so they're passed as pointers instead, and nil is sent as NULL
+	IsWritingFieldList bool
+
+	// set once a param is bound, since params like sort enums and bool flags
+	// only change the sql, and an unused argIndex wouldn't compile
+	IsArgIndexUsed bool
 
 	// Used for giving unique names to output of expressions
 	GroupIndex   int
@@ -17,11 +34,11 @@ type Generator struct {
 	LiteralIndex int
 
 	// If a where clause is added dynamically based on conditionals or presence of values,
-	// use this to add the WHERE keyword in top-level expressions.
+	// use this to add the WHERE (or HAVING) keyword in top-level expressions.
 	// TODO: this isn't the best way to do this, since expressions should ideally not care about what
 	// context it's used in. We could instead write end result to variable in all cases, and
 	// add the WHERE keyword if expression value is not empty.
-	GenPossiblyOptionalWhereClause bool
+	GenPossiblyOptionalClause string
 }
 
 // handles generating ops within template expressions (Go syntax).
@@ -53,12 +70,105 @@ func OpTypeToGoString(opType OpType) string {
 		op = "=="
 	case OpTypeIsNot:
 		op = "!="
+	case OpTypeNot:
+		op = "!"
+	case OpTypeAdd, OpTypeConcat:
+		op = "+"
+	case OpTypeSubtract, OpTypeNegate:
+		op = "-"
+	case OpTypeMultiply:
+		op = "*"
+	case OpTypeDivide:
+		op = "/"
+	case OpTypeModulo:
+		op = "%"
 	default:
 		panic("unhandled op")
 	}
 	return op
 }
 
+// a child needs parentheses if it binds looser than its parent, or equally
+// on the right side, since ops are left-associative (eg a - (b - c))
+func needsParens(parent Expression, child Expression, isRight bool) bool {
+	if child.Type != ExpressionTypeBinary {
+		return false
+	}
+	if child.Op.Precedence() < parent.Op.Precedence() {
+		return true
+	}
+	return isRight && child.Op.Precedence() == parent.Op.Precedence()
+}
+
+func (g *Generator) addImport(importPath string) {
+	if importPath == "" {
+		return
+	}
+	if g.Imports == nil {
+		g.Imports = map[string]bool{}
+	}
+	g.Imports[importPath] = true
+}
+
+// whether a shared type still needs to be written, in which case it's
+// considered written from now on
+func (g *Generator) declare(typeName string) bool {
+	if g.Declared == nil {
+		g.Declared = map[string]bool{}
+	}
+	if g.Declared[typeName] {
+		return false
+	}
+	g.Declared[typeName] = true
+	return true
+}
+
+func (g *Generator) paramGoType(p Param) string {
+	if p.Type == ParamTypeSortColumn || p.Type == ParamTypeSortDirection || p.Type == ParamTypeCursor {
+		return p.GoType.String()
+	}
+	if p.Type == ParamTypeCustom {
+		g.addImport(p.GoType.ImportPath)
+		return p.GoType.String()
+	}
+	if p.Type == ParamTypeStruct {
+		return p.TypeName
+	}
+	return p.Type.String()
+}
+
+// nullable columns are written as pointers, the same as optional params
+func (g *Generator) columnGoType(col ResultColumn) string {
+	goType, ok := g.Types.LookupColumn(col.TableName, col.Field)
+	if !ok {
+		if col.Field.Type == TableFieldTypeNone {
+			// untyped, eg an unknown function. interface{} can already hold nil
+			return "interface{}"
+		}
+		goType = col.Field.Type.GoType()
+	}
+	g.addImport(goType.ImportPath)
+
+	if col.Field.NotNull || col.Field.PrimaryKey {
+		return goType.String()
+	}
+	return "*" + goType.String()
+}
+
+func (g *Generator) writeImports(sb *strings.Builder) {
+	imports := make([]string, 0, len(g.Imports))
+	for importPath := range g.Imports {
+		imports = append(imports, importPath)
+	}
+	sort.Strings(imports)
+
+	sb.WriteString("import (\n")
+	for _, importPath := range imports {
+		sb.WriteString(fmt.Sprintf("%q\n", importPath))
+	}
+	sb.WriteString(")\n\n")
+}
+
 func (g *Generator) writeTemplateExpressionLiteral(sb *strings.Builder, params []Param, exp Expression, isPointerComparison bool) {
 	switch exp.LiteralType {
 	case LiteralTypeNull:
@@ -67,6 +177,8 @@ func (g *Generator) writeTemplateExpressionLiteral(sb *strings.Builder, params [
 		sb.WriteString(fmt.Sprintf("\"%s\"", exp.LiteralString))
 	case LiteralTypeNumber:
 		sb.WriteString(fmt.Sprintf("%d", exp.LiteralNumber))
+	case LiteralTypeBool:
+		sb.WriteString(fmt.Sprintf("%t", exp.LiteralBool))
 	case LiteralTypeFieldName:
 		panic("invalid literal type for template expression")
 	case LiteralTypeVariable:
@@ -81,6 +193,33 @@ func (g *Generator) writeTemplateExpressionLiteral(sb *strings.Builder, params [
 	}
 }
 
+func (g *Generator) writeTemplateExpression(sb *strings.Builder, params []Param, exp Expression, isPointerComparison bool) {
+	switch exp.Type {
+	case ExpressionTypeLiteral:
+		g.writeTemplateExpressionLiteral(sb, params, exp, isPointerComparison)
+	case ExpressionTypeBinary:
+		g.writeTemplateExpressionBinary(sb, params, exp)
+	case ExpressionTypeUnary:
+		sb.WriteString(OpTypeToGoString(exp.Op))
+		// go's unary ops bind tighter than any binary op, unlike NOT in sql
+		g.writeTemplateOperand(sb, params, *exp.Left, exp.Left.Type == ExpressionTypeBinary, false)
+	case ExpressionTypeListLength:
+		sb.WriteString(fmt.Sprintf("len(%s)", exp.Left.LiteralVariableName))
+	default:
+		panic("unhandled template expression type")
+	}
+}
+
+func (g *Generator) writeTemplateOperand(sb *strings.Builder, params []Param, exp Expression, parens bool, isPointerComparison bool) {
+	if parens {
+		sb.WriteString("(")
+	}
+	g.writeTemplateExpression(sb, params, exp, isPointerComparison)
+	if parens {
+		sb.WriteString(")")
+	}
+}
+
 func (g *Generator) writeTemplateExpressionBinary(sb *strings.Builder, params []Param, exp Expression) {
 	op := OpTypeToGoString(exp.Op)
 
@@ -89,11 +228,55 @@ func (g *Generator) writeTemplateExpressionBinary(sb *strings.Builder, params []
 		isPointerComparison = true
 	}
 
-	sb.WriteString("(")
-	g.writeTemplateExpressionLiteral(sb, params, *exp.Left, isPointerComparison)
+	// filter.minID IS NOT NULL, where filter may be nil too
+	if isPointerComparison && (exp.Op == OpTypeIs || exp.Op == OpTypeIsNot) {
+		variable := exp.Left
+		if variable.LiteralType == LiteralTypeNull {
+			variable = exp.Right
+		}
+		if variable.OptionalStructName != "" {
+			g.writeOptionalStructNullCheck(sb, *variable, exp.Op == OpTypeIs)
+			return
+		}
+	}
+
+	// arithmetic dereferences its optional variables, so a comparison of it is
+	// false when any are nil, like NULL in sql, eg (input.a != nil && *input.a+1 > input.b)
+	var conditions []string
+	if !exp.Op.IsLogical() && !isPointerComparison {
+		for _, operand := range []Expression{*exp.Left, *exp.Right} {
+			if operand.Type == ExpressionTypeBinary || operand.Type == ExpressionTypeUnary {
+				conditions = appendOptionalVarConditions(conditions, operand)
+			}
+		}
+	}
+	if len(conditions) > 0 {
+		sb.WriteString(fmt.Sprintf("(%s && ", strings.Join(conditions, " && ")))
+	}
+
+	// go and sql agree on the precedence of comparisons, AND and OR, so
+	// parentheses are only needed where the sql had them
+	g.writeTemplateOperand(sb, params, *exp.Left, needsParens(exp, *exp.Left, false), isPointerComparison)
 	sb.WriteString(fmt.Sprintf(" %s ", op))
-	g.writeTemplateExpressionLiteral(sb, params, *exp.Right, isPointerComparison)
-	sb.WriteString(")")
+	g.writeTemplateOperand(sb, params, *exp.Right, needsParens(exp, *exp.Right, true), isPointerComparison)
+
+	if len(conditions) > 0 {
+		sb.WriteString(")")
+	}
+}
+
+// a field of an optional struct is null when either is nil
+func (g *Generator) writeOptionalStructNullCheck(sb *strings.Builder, exp Expression, isNull bool) {
+	op, join := "!=", "&&"
+	if isNull {
+		op, join = "==", "||"
+	}
+
+	checks := []string{fmt.Sprintf("%s %s nil", exp.OptionalStructName, op)}
+	if !exp.IsClauseRequired {
+		checks = append(checks, fmt.Sprintf("%s %s nil", exp.LiteralVariableName, op))
+	}
+	sb.WriteString(fmt.Sprintf("(%s)", strings.Join(checks, " "+join+" ")))
 }
 
 func (g *Generator) startGroup(sb *strings.Builder) {
@@ -102,18 +285,109 @@ func (g *Generator) startGroup(sb *strings.Builder) {
 }
 
 func (g *Generator) endGroup(sb *strings.Builder, groupIndex int, op string, addToGroupClauseNum *int) {
+	g.endGroupWithPrefix(sb, groupIndex, op, "", addToGroupClauseNum)
+}
+
+// prefix is written before the group, eg "NOT ". groups with a prefix are
+// always wrapped in parentheses, including at the top level.
+func (g *Generator) endGroupWithPrefix(sb *strings.Builder, groupIndex int, op string, prefix string, addToGroupClauseNum *int) {
 	sb.WriteString(fmt.Sprintf("\tgroupClause%dResult := strings.Join(groupClause%d, \" %s \")\n", groupIndex, groupIndex, op))
 	sb.WriteString(fmt.Sprintf("\tif len(groupClause%dResult) > 0 {\n", groupIndex))
 	if addToGroupClauseNum != nil {
-		sb.WriteString(fmt.Sprintf("\t\tgroupClause%d = append(groupClause%d, fmt.Sprintf(\"(%%s)\", groupClause%dResult))\n", *addToGroupClauseNum, *addToGroupClauseNum, groupIndex))
+		sb.WriteString(fmt.Sprintf("\t\tgroupClause%d = append(groupClause%d, fmt.Sprintf(\"%s(%%s)\", groupClause%dResult))\n", *addToGroupClauseNum, *addToGroupClauseNum, prefix, groupIndex))
 	} else {
 		// this is the top level expression, so add the base where clause
 		possibleWhere := ""
-		if g.GenPossiblyOptionalWhereClause {
-			possibleWhere = " WHERE "
+		if g.GenPossiblyOptionalClause != "" {
+			possibleWhere = fmt.Sprintf(" %s ", g.GenPossiblyOptionalClause)
+		}
+		format := "%%s"
+		if prefix != "" {
+			format = prefix + "(%%s)"
 		}
-		sb.WriteString(fmt.Sprintf("sb.WriteString(fmt.Sprintf(\"%s%%s\", groupClause%dResult))", possibleWhere, groupIndex))
+		sb.WriteString(fmt.Sprintf("sb.WriteString(fmt.Sprintf(\"%s"+format+"\", groupClause%dResult))", possibleWhere, groupIndex))
+	}
+}
+
+// writes a completed clause either to its parent group, or directly to the query
+// if it's the top level expression
+func (g *Generator) writeExprResult(sb *strings.Builder, exprName string, addToGroupClauseNum *int) {
+	// todo: some duplication here with endGroup
+	if addToGroupClauseNum != nil {
+		sb.WriteString(fmt.Sprintf("\tgroupClause%d = append(groupClause%d, %s)\n", *addToGroupClauseNum, *addToGroupClauseNum, exprName))
+	} else {
+		possibleWhere := ""
+		if g.GenPossiblyOptionalClause != "" {
+			possibleWhere = fmt.Sprintf(" %s ", g.GenPossiblyOptionalClause)
+		}
+		sb.WriteString(fmt.Sprintf("sb.WriteString(fmt.Sprintf(\"%s%%s\", %s))\n\n", possibleWhere, exprName))
+	}
+}
+
+func appendUniqueCondition(conditions []string, condition string) []string {
+	for _, c := range conditions {
+		if c == condition {
+			return conditions
+		}
+	}
+	return append(conditions, condition)
+}
+
+// collects nil checks for optional variables in a value expression, including
+// variables nested in arithmetic. an optional struct is checked before its field.
+func appendOptionalVarConditions(conditions []string, exp Expression) []string {
+	switch exp.Type {
+	case ExpressionTypeLiteral:
+		if exp.LiteralType != LiteralTypeVariable {
+			break
+		}
+		if exp.OptionalStructName != "" {
+			conditions = appendUniqueCondition(conditions, fmt.Sprintf("%s != nil", exp.OptionalStructName))
+		}
+		if !exp.IsClauseRequired {
+			conditions = appendUniqueCondition(conditions, fmt.Sprintf("%s != nil", exp.LiteralVariableName))
+		}
+	case ExpressionTypeBinary, ExpressionTypeUnary, ExpressionTypeBetween, ExpressionTypeCast:
+		conditions = appendOptionalVarConditions(conditions, *exp.Left)
+		if exp.Right != nil {
+			conditions = appendOptionalVarConditions(conditions, *exp.Right)
+		}
+		if exp.BetweenUpper != nil {
+			conditions = appendOptionalVarConditions(conditions, *exp.BetweenUpper)
+		}
+	case ExpressionTypeFunction:
+		for _, arg := range exp.FunctionArgs {
+			conditions = appendOptionalVarConditions(conditions, arg)
+		}
+	case ExpressionTypeCase:
+		if exp.CaseOperand != nil {
+			conditions = appendOptionalVarConditions(conditions, *exp.CaseOperand)
+		}
+		for _, caseWhen := range exp.CaseWhens {
+			conditions = appendOptionalVarConditions(conditions, *caseWhen.When)
+			conditions = appendOptionalVarConditions(conditions, *caseWhen.Then)
+		}
+		if exp.CaseElse != nil {
+			conditions = appendOptionalVarConditions(conditions, *exp.CaseElse)
+		}
+	}
+	return conditions
+}
+
+// opens an if statement checking that every optional variable in the clause is set,
+// so the clause is dropped if any are nil. returns true if the caller needs to close it.
+func (g *Generator) writeOptionalVarCheck(sb *strings.Builder, exps ...Expression) bool {
+	var conditions []string
+	for _, exp := range exps {
+		conditions = appendOptionalVarConditions(conditions, exp)
+	}
+
+	if len(conditions) == 0 {
+		return false
 	}
+
+	sb.WriteString(fmt.Sprintf("\tif %s {\n", strings.Join(conditions, " && ")))
+	return true
 }
 
 func (g *Generator) writeLiteral(sb *strings.Builder, params []Param, exp Expression) {
@@ -123,10 +397,20 @@ func (g *Generator) writeLiteral(sb *strings.Builder, params []Param, exp Expres
 		sb.WriteString(fmt.Sprintf("\tlit%d := \"NULL\"\n", g.LiteralIndex))
 	case LiteralTypeString:
 		g.LiteralIndex++
-		sb.WriteString(fmt.Sprintf("\tlit%d := \"'%s'\"\n", g.LiteralIndex, exp.LiteralString))
+		typePrefix := ""
+		if exp.LiteralStringType != "" {
+			typePrefix = exp.LiteralStringType + " "
+		}
+		sb.WriteString(fmt.Sprintf("\tlit%d := \"%s'%s'\"\n", g.LiteralIndex, typePrefix, exp.LiteralString))
 	case LiteralTypeNumber:
 		g.LiteralIndex++
 		sb.WriteString(fmt.Sprintf("\tlit%d := \"%d\"\n", g.LiteralIndex, exp.LiteralNumber))
+	case LiteralTypeBool:
+		g.LiteralIndex++
+		sb.WriteString(fmt.Sprintf("\tlit%d := \"%s\"\n", g.LiteralIndex, strings.ToUpper(BoolLiteral(exp.LiteralBool).String())))
+	case LiteralTypeUnknown:
+		g.LiteralIndex++
+		sb.WriteString(fmt.Sprintf("\tlit%d := \"UNKNOWN\"\n", g.LiteralIndex))
 	case LiteralTypeFieldName:
 		g.LiteralIndex++
 		sb.WriteString(fmt.Sprintf("\tlit%d := \"", g.LiteralIndex))
@@ -148,11 +432,12 @@ func (g *Generator) writeLiteral(sb *strings.Builder, params []Param, exp Expres
 		sb.WriteString("\"\n")
 	case LiteralTypeVariable:
 		maybePointer := ""
-		if !exp.IsClauseRequired {
+		if !exp.IsClauseRequired && !g.IsWritingFieldList {
 			maybePointer = "*"
 		}
 		g.LiteralIndex++
 
+		g.IsArgIndexUsed = true
 		sb.WriteString(fmt.Sprintf("\tlit%d := fmt.Sprintf(\"$%%d\", argIndex)\n", g.LiteralIndex))
 		sb.WriteString(fmt.Sprintf("\targs = append(args, %s", maybePointer))
 		sb.WriteString(exp.LiteralVariableName)
@@ -162,43 +447,294 @@ func (g *Generator) writeLiteral(sb *strings.Builder, params []Param, exp Expres
 	}
 }
 
+// writes a value (a literal, or arithmetic on literals) to a lit variable
+// and returns its index
+func (g *Generator) writeScalar(sb *strings.Builder, params []Param, exp Expression) int {
+	switch exp.Type {
+	case ExpressionTypeLiteral:
+		g.writeLiteral(sb, params, exp)
+		return g.LiteralIndex
+	case ExpressionTypeUnary:
+		operand := g.writeScalar(sb, params, *exp.Left)
+
+		// a negated negative would otherwise be written as --, which starts a comment
+		isNegative := (exp.Left.Type == ExpressionTypeUnary && exp.Left.Op == OpTypeNegate) ||
+			(exp.Left.LiteralType == LiteralTypeNumber && exp.Left.LiteralNumber < 0)
+		format := exp.Op.String() + "%s"
+		if exp.Left.Type == ExpressionTypeBinary || (exp.Op == OpTypeNegate && isNegative) {
+			format = exp.Op.String() + "(%s)"
+		}
+
+		g.LiteralIndex++
+		sb.WriteString(fmt.Sprintf("\tlit%d := fmt.Sprintf(%q, lit%d)\n", g.LiteralIndex, format, operand))
+		return g.LiteralIndex
+	case ExpressionTypeBinary:
+		left := g.writeScalar(sb, params, *exp.Left)
+		right := g.writeScalar(sb, params, *exp.Right)
+
+		leftFormat := "%s"
+		if needsParens(exp, *exp.Left, false) {
+			leftFormat = "(%s)"
+		}
+		rightFormat := "%s"
+		if needsParens(exp, *exp.Right, true) {
+			rightFormat = "(%s)"
+		}
+
+		// escape modulo, since the op is written into a format string
+		op := strings.ReplaceAll(exp.Op.String(), "%", "%%")
+
+		g.LiteralIndex++
+		format := fmt.Sprintf("%s %s %s", leftFormat, op, rightFormat)
+		sb.WriteString(fmt.Sprintf("\tlit%d := fmt.Sprintf(%q, lit%d, lit%d)\n", g.LiteralIndex, format, left, right))
+		return g.LiteralIndex
+	case ExpressionTypeBetween:
+		value := g.writeScalar(sb, params, *exp.Left)
+		lower := g.writeScalar(sb, params, *exp.Right)
+		upper := g.writeScalar(sb, params, *exp.BetweenUpper)
+
+		g.LiteralIndex++
+		format := fmt.Sprintf("%%s %s %%s AND %%s", exp.Op)
+		sb.WriteString(fmt.Sprintf("\tlit%d := fmt.Sprintf(%q, lit%d, lit%d, lit%d)\n", g.LiteralIndex, format, value, lower, upper))
+		return g.LiteralIndex
+	case ExpressionTypeCast:
+		operand := g.writeScalar(sb, params, *exp.Left)
+
+		var format string
+		switch {
+		case exp.CastIsFunction:
+			format = fmt.Sprintf("CAST(%%s AS %s)", exp.CastTypeName)
+		case exp.Left.Type == ExpressionTypeBinary, exp.Left.Type == ExpressionTypeUnary, exp.Left.Type == ExpressionTypeBetween:
+			// :: binds tighter than any operator
+			format = fmt.Sprintf("(%%s)::%s", exp.CastTypeName)
+		default:
+			format = fmt.Sprintf("%%s::%s", exp.CastTypeName)
+		}
+
+		g.LiteralIndex++
+		sb.WriteString(fmt.Sprintf("\tlit%d := fmt.Sprintf(%q, lit%d)\n", g.LiteralIndex, format, operand))
+		return g.LiteralIndex
+	case ExpressionTypeFunction:
+		distinct := ""
+		if exp.FunctionDistinct {
+			distinct = "DISTINCT "
+		}
+
+		argLits := make([]string, 0, len(exp.FunctionArgs))
+		argFormats := make([]string, 0, len(exp.FunctionArgs))
+		for _, arg := range exp.FunctionArgs {
+			argLits = append(argLits, fmt.Sprintf("lit%d", g.writeScalar(sb, params, arg)))
+			argFormats = append(argFormats, "%s")
+		}
+
+		format := fmt.Sprintf("%s(%s%s)", exp.FunctionName, distinct, strings.Join(argFormats, ", "))
+		if exp.FunctionStar {
+			format = exp.FunctionName + "(*)"
+		}
+
+		if exp.Over != nil {
+			overFormat, overLits := g.writeWindow(sb, params, *exp.Over)
+			if exp.Over.IsNameOnly() {
+				overFormat = exp.Over.Name
+			}
+			format += " OVER " + overFormat
+			argLits = append(argLits, overLits...)
+		}
+
+		g.LiteralIndex++
+		if len(argLits) == 0 {
+			sb.WriteString(fmt.Sprintf("\tlit%d := %q\n", g.LiteralIndex, format))
+		} else {
+			sb.WriteString(fmt.Sprintf("\tlit%d := fmt.Sprintf(%q, %s)\n", g.LiteralIndex, format, strings.Join(argLits, ", ")))
+		}
+		return g.LiteralIndex
+	case ExpressionTypeCase:
+		format := strings.Builder{}
+		var lits []string
+
+		format.WriteString("CASE")
+		if exp.CaseOperand != nil {
+			format.WriteString(" %s")
+			lits = append(lits, fmt.Sprintf("lit%d", g.writeScalar(sb, params, *exp.CaseOperand)))
+		}
+		for _, caseWhen := range exp.CaseWhens {
+			format.WriteString(" WHEN %s THEN %s")
+			lits = append(lits, fmt.Sprintf("lit%d", g.writeScalar(sb, params, *caseWhen.When)))
+			lits = append(lits, fmt.Sprintf("lit%d", g.writeScalar(sb, params, *caseWhen.Then)))
+		}
+		if exp.CaseElse != nil {
+			format.WriteString(" ELSE %s")
+			lits = append(lits, fmt.Sprintf("lit%d", g.writeScalar(sb, params, *exp.CaseElse)))
+		}
+		format.WriteString(" END")
+
+		g.LiteralIndex++
+		sb.WriteString(fmt.Sprintf("\tlit%d := fmt.Sprintf(%q, %s)\n", g.LiteralIndex, format.String(), strings.Join(lits, ", ")))
+		return g.LiteralIndex
+	case ExpressionTypeSubquery:
+		format := "(%s)"
+		if exp.SubqueryExists {
+			format = "EXISTS (%s)"
+		}
+		return g.writeSubquery(sb, params, *exp.Subquery, format)
+	default:
+		panic("unexpected value expression type")
+	}
+}
+
+// writes a subquery to a lit variable and returns its index. it's built in its
+// own block with a shadowed sb, so optional params inside it drop its own
+// clauses, while args keep being numbered in the order they're written.
+func (g *Generator) writeSubquery(sb *strings.Builder, params []Param, stmt SelectStmt, format string) int {
+	g.LiteralIndex++
+	lit := g.LiteralIndex
+
+	sb.WriteString(fmt.Sprintf("\tvar lit%d string\n", lit))
+	sb.WriteString("\t{\n")
+	sb.WriteString("\tsb := strings.Builder{}\n\n")
+
+	clause, isWritingFieldList := g.GenPossiblyOptionalClause, g.IsWritingFieldList
+	g.GenPossiblyOptionalClause, g.IsWritingFieldList = "", false
+
+	g.writeSelect(sb, params, stmt)
+
+	g.GenPossiblyOptionalClause, g.IsWritingFieldList = clause, isWritingFieldList
+
+	sb.WriteString(fmt.Sprintf("\n\tlit%d = fmt.Sprintf(%q, sb.String())\n", lit, format))
+	sb.WriteString("\t}\n")
+
+	return lit
+}
+
+// returns the DISTINCT or DISTINCT ON prefix of the select list as a format
+// string, and the lit variables for any DISTINCT ON expressions
+func (g *Generator) writeDistinct(sb *strings.Builder, params []Param, stmt SelectStmt) (string, []string) {
+	if !stmt.Distinct {
+		return "", nil
+	}
+	if len(stmt.DistinctOn) == 0 {
+		return "DISTINCT ", nil
+	}
+
+	lits := make([]string, 0, len(stmt.DistinctOn))
+	formats := make([]string, 0, len(stmt.DistinctOn))
+
+	g.IsWritingFieldList = true
+	for _, expr := range stmt.DistinctOn {
+		lits = append(lits, fmt.Sprintf("lit%d", g.writeScalar(sb, params, expr)))
+		formats = append(formats, "%s")
+	}
+	g.IsWritingFieldList = false
+
+	return fmt.Sprintf("DISTINCT ON (%s) ", strings.Join(formats, ", ")), lits
+}
+
+// returns the select list as a format string, with a %s for each field that's
+// an expression. expression fields are first written to lit variables, and
+// their names are returned in order.
+func (g *Generator) writeSelectFields(sb *strings.Builder, params []Param, fields []Field) (string, []string) {
+	format := strings.Builder{}
+	var lits []string
+
+	g.IsWritingFieldList = true
+	for i, f := range fields {
+		if i > 0 {
+			format.WriteString(", ")
+		}
+		if f.Expr != nil {
+			lits = append(lits, fmt.Sprintf("lit%d", g.writeScalar(sb, params, *f.Expr)))
+			format.WriteString("%s")
+		} else {
+			if f.TableName != "" {
+				format.WriteString(fmt.Sprintf("%s.", f.TableName))
+			}
+			if f.All {
+				format.WriteString("*")
+			} else {
+				format.WriteString(f.Name)
+			}
+		}
+		if f.Alias != "" {
+			format.WriteString(fmt.Sprintf(" %s", f.Alias))
+		}
+	}
+	g.IsWritingFieldList = false
+
+	return format.String(), lits
+}
+
+// writes `left IN {list}` to an expression variable and returns its name.
+// an empty list can't be written as `IN ()`, so the expression becomes
+// FALSE for IN and TRUE for NOT IN.
+func (g *Generator) writeIn(sb *strings.Builder, params []Param, exp Expression) string {
+	leftLit := g.writeScalar(sb, params, *exp.Left)
+
+	listName := exp.Right.LiteralVariableName
+
+	g.ExprIndex++
+	exprName := fmt.Sprintf("expr%d", g.ExprIndex)
+
+	emptyResult := "FALSE"
+	if exp.Op == OpTypeNotIn {
+		emptyResult = "TRUE"
+	}
+
+	sb.WriteString(fmt.Sprintf("\t%s := \"%s\"\n", exprName, emptyResult))
+	sb.WriteString(fmt.Sprintf("\tif len(%s) > 0 {\n", listName))
+	g.IsArgIndexUsed = true
+
+	switch g.InListStyle {
+	case InListStyleAny:
+		op := "= ANY"
+		if exp.Op == OpTypeNotIn {
+			op = "<> ALL"
+		}
+		sb.WriteString(fmt.Sprintf("\t\t%s = fmt.Sprintf(\"%%s %s($%%d)\", lit%d, argIndex)\n", exprName, op, leftLit))
+		sb.WriteString(fmt.Sprintf("\t\targs = append(args, %s)\n", listName))
+		sb.WriteString("\t\targIndex++\n")
+	default:
+		g.LiteralIndex++
+		placeholders := fmt.Sprintf("lit%d", g.LiteralIndex)
+		sb.WriteString(fmt.Sprintf("\t\t%s := make([]string, 0, len(%s))\n", placeholders, listName))
+		sb.WriteString(fmt.Sprintf("\t\tfor _, item := range %s {\n", listName))
+		sb.WriteString(fmt.Sprintf("\t\t\t%s = append(%s, fmt.Sprintf(\"$%%d\", argIndex))\n", placeholders, placeholders))
+		sb.WriteString("\t\t\targs = append(args, item)\n")
+		sb.WriteString("\t\t\targIndex++\n")
+		sb.WriteString("\t\t}\n")
+		sb.WriteString(fmt.Sprintf("\t\t%s = fmt.Sprintf(\"%%s %s (%%s)\", lit%d, strings.Join(%s, \", \"))\n", exprName, exp.Op, leftLit, placeholders))
+	}
+
+	sb.WriteString("\t}\n")
+
+	return exprName
+}
+
 func (g *Generator) writeBinary(sb *strings.Builder, params []Param, exp Expression, addToGroupClauseNum *int) {
 	// todo: consider using a lexeme we should already have validated
 	op := exp.Op.String()
 
-	if exp.Left.Type == ExpressionTypeLiteral && exp.Right.Type == ExpressionTypeLiteral {
+	if !exp.Op.IsLogical() {
 		// should not start a group, but clause may not be required.
 		// need to know whether one of children has a variable so we can add fmt.Sprintf
 
-		usesVar := false
-		if exp.Left.LiteralType == LiteralTypeVariable && !exp.Left.IsClauseRequired {
-			usesVar = true
-			sb.WriteString(fmt.Sprintf("\tif %s != nil {\n", exp.Left.LiteralVariableName))
-		} else if exp.Right.LiteralType == LiteralTypeVariable && !exp.Right.IsClauseRequired {
-			usesVar = true
-			sb.WriteString(fmt.Sprintf("\tif %s != nil {\n", exp.Right.LiteralVariableName))
-		}
-
-		g.writeLiteral(sb, params, *exp.Left)
-		g.writeLiteral(sb, params, *exp.Right)
-
-		// literal needs to be able to write arg either in first position or second
-		// so the literals each generate separate string variable for now, and compose them here
-		g.ExprIndex++
-		exprName := fmt.Sprintf("expr%d", g.ExprIndex)
-		sb.WriteString(fmt.Sprintf("\t%s := fmt.Sprintf(\"%%s %s %%s\", lit%d, lit%d)\n", exprName, op, g.LiteralIndex-1, g.LiteralIndex))
+		usesVar := g.writeOptionalVarCheck(sb, *exp.Left, *exp.Right)
 
-		// todo: some duplication here with endGroup
-		if addToGroupClauseNum != nil {
-			sb.WriteString(fmt.Sprintf("\tgroupClause%d = append(groupClause%d, %s)\n", *addToGroupClauseNum, *addToGroupClauseNum, exprName))
+		var exprName string
+		if (exp.Op == OpTypeIn || exp.Op == OpTypeNotIn) && exp.Right.Type != ExpressionTypeSubquery {
+			exprName = g.writeIn(sb, params, exp)
 		} else {
-			possibleWhere := ""
-			if g.GenPossiblyOptionalWhereClause {
-				possibleWhere = " WHERE "
-			}
-			sb.WriteString(fmt.Sprintf("sb.WriteString(fmt.Sprintf(\"%s%%s\", %s))\n\n", possibleWhere, exprName))
+			left := g.writeScalar(sb, params, *exp.Left)
+			right := g.writeScalar(sb, params, *exp.Right)
+
+			// literal needs to be able to write arg either in first position or second
+			// so the literals each generate separate string variable for now, and compose them here
+			g.ExprIndex++
+			exprName = fmt.Sprintf("expr%d", g.ExprIndex)
+			sb.WriteString(fmt.Sprintf("\t%s := fmt.Sprintf(\"%%s %s %%s\", lit%d, lit%d)\n", exprName, op, left, right))
 		}
 
+		g.writeExprResult(sb, exprName, addToGroupClauseNum)
+
 		if usesVar {
 			sb.WriteString("\t}\n\n")
 		}
@@ -218,7 +754,44 @@ func (g *Generator) writeBinary(sb *strings.Builder, params []Param, exp Express
 	}
 }
 
+func (g *Generator) writeUnary(sb *strings.Builder, params []Param, exp Expression, addToGroupClauseNum *int) {
+	// the operand is written to its own group, so NOT is dropped along with it
+	// if the operand is dropped
+	g.startGroup(sb)
+
+	groupIndex := g.GroupIndex
+
+	g.writeExpression(sb, params, *exp.Left, &groupIndex)
+
+	g.endGroupWithPrefix(sb, groupIndex, "AND", exp.Op.String()+" ", addToGroupClauseNum)
+
+	sb.WriteString("\t}\n\n")
+}
+
+func (g *Generator) writeBetween(sb *strings.Builder, params []Param, exp Expression, addToGroupClauseNum *int) {
+	usesVar := g.writeOptionalVarCheck(sb, *exp.Left, *exp.Right, *exp.BetweenUpper)
+
+	value := g.writeScalar(sb, params, *exp.Left)
+	lower := g.writeScalar(sb, params, *exp.Right)
+	upper := g.writeScalar(sb, params, *exp.BetweenUpper)
+
+	g.ExprIndex++
+	exprName := fmt.Sprintf("expr%d", g.ExprIndex)
+	sb.WriteString(fmt.Sprintf("\t%s := fmt.Sprintf(\"%%s %s %%s AND %%s\", lit%d, lit%d, lit%d)\n", exprName, exp.Op, value, lower, upper))
+
+	g.writeExprResult(sb, exprName, addToGroupClauseNum)
+
+	if usesVar {
+		sb.WriteString("\t}\n\n")
+	}
+}
+
 func (g *Generator) writeForLoop(sb *strings.Builder, params []Param, exp Expression, addToGroupClauseNum *int) {
+	// a nil optional list drops the loop, like a nil param
+	if exp.ForLoopIsOptional {
+		sb.WriteString(fmt.Sprintf("\tif input.%s != nil {\n", exp.ForLoopVarName))
+	}
+
 	g.GroupIndex++
 	sb.WriteString(fmt.Sprintf("\tgroupClause%d := make([]string, 0, len(input.%s))\n\n", g.GroupIndex, exp.ForLoopVarName))
 
@@ -237,9 +810,23 @@ func (g *Generator) writeForLoop(sb *strings.Builder, params []Param, exp Expres
 
 	sb.WriteString("\t}\n\n")
 
+	// an empty list joined by AND is no filter, so the empty group is dropped
+	// like a nil param. joined by OR it matches nothing, like IN with an empty
+	// list, rather than dropping the clause and matching everything. an
+	// optional list can be nil for no filter, so empty always matches nothing.
+	if exp.ForLoopJoinByOr || exp.ForLoopIsOptional {
+		sb.WriteString(fmt.Sprintf("\tif len(input.%s) == 0 {\n", exp.ForLoopVarName))
+		sb.WriteString(fmt.Sprintf("\t\tgroupClause%d = append(groupClause%d, \"FALSE\")\n", groupIndex, groupIndex))
+		sb.WriteString("\t}\n\n")
+	}
+
 	g.endGroup(sb, groupIndex, op, addToGroupClauseNum)
 
 	sb.WriteString("\t}\n\n")
+
+	if exp.ForLoopIsOptional {
+		sb.WriteString("\t}\n\n")
+	}
 }
 
 func (g *Generator) writeIf(sb *strings.Builder, params []Param, exp Expression, addToGroupClauseNum *int) {
@@ -253,7 +840,7 @@ func (g *Generator) writeIf(sb *strings.Builder, params []Param, exp Expression,
 		// write literals to string
 		// could be a deeply nested expression
 		// kind of want to call writeBinary but output to a static string
-		g.writeTemplateExpressionBinary(sb, params, *elseif.IfExpr)
+		g.writeTemplateExpression(sb, params, *elseif.IfExpr, false)
 
 		sb.WriteString(" {\n")
 
@@ -274,14 +861,26 @@ func (g *Generator) writeIf(sb *strings.Builder, params []Param, exp Expression,
 // of params, current table, etc
 func (g *Generator) writeExpression(sb *strings.Builder, params []Param, exp Expression, addToGroupClauseNum *int) {
 	switch exp.Type {
-	case ExpressionTypeLiteral:
-		g.writeLiteral(sb, params, exp)
+	case ExpressionTypeLiteral, ExpressionTypeFunction, ExpressionTypeCase, ExpressionTypeCast, ExpressionTypeSubquery:
+		// a value on its own is a clause, eg a boolean column or EXISTS
+		usesVar := g.writeOptionalVarCheck(sb, exp)
+		lit := g.writeScalar(sb, params, exp)
+		g.writeExprResult(sb, fmt.Sprintf("lit%d", lit), addToGroupClauseNum)
+		if usesVar {
+			sb.WriteString("\t}\n\n")
+		}
 	case ExpressionTypeBinary:
 		g.writeBinary(sb, params, exp, addToGroupClauseNum)
+	case ExpressionTypeUnary:
+		g.writeUnary(sb, params, exp, addToGroupClauseNum)
+	case ExpressionTypeBetween:
+		g.writeBetween(sb, params, exp, addToGroupClauseNum)
 	case ExpressionTypeForLoop:
 		g.writeForLoop(sb, params, exp, addToGroupClauseNum)
 	case ExpressionTypeIf:
 		g.writeIf(sb, params, exp, addToGroupClauseNum)
+	case ExpressionTypeCursor:
+		g.writeCursorCondition(sb, params, exp, addToGroupClauseNum)
 	case ExpressionTypeFragment:
 		panic("expected fragment to be expanded into expression")
 	default:
@@ -289,12 +888,653 @@ func (g *Generator) writeExpression(sb *strings.Builder, params []Param, exp Exp
 	}
 }
 
-func (g *Generator) generateQuery(query Query) ([]byte, error) {
+// an optional param drops the clause when nil
+func (g *Generator) writeLimitOrOffset(sb *strings.Builder, params []Param, exp Expression, clause string) {
+	if exp.LiteralType == LiteralTypeNumber {
+		sb.WriteString(fmt.Sprintf("\tsb.WriteString(\" %s %d\")\n", clause, exp.LiteralNumber))
+		return
+	}
+
+	usesVar := g.writeOptionalVarCheck(sb, exp)
+	lit := g.writeScalar(sb, params, exp)
+	sb.WriteString(fmt.Sprintf("\tsb.WriteString(fmt.Sprintf(\" %s %%s\", lit%d))\n", clause, lit))
+	if usesVar {
+		sb.WriteString("\t}\n\n")
+	}
+}
+
+// matches rows after the cursor with a row-value comparison,
+// eg (created_at, id) > ($1, $2). dropped when there's no cursor.
+func (g *Generator) writeCursorCondition(sb *strings.Builder, params []Param, exp Expression, addToGroupClauseNum *int) {
+	paginate := exp.Paginate
+	sb.WriteString(fmt.Sprintf("\tif input.%s != nil {\n", paginate.CursorParamName))
+
+	columns := make([]string, 0, len(paginate.Fields))
+	formats := make([]string, 0, len(paginate.Fields))
+	lits := make([]string, 0, len(paginate.Fields))
+	for _, field := range paginate.Fields {
+		g.writeLiteral(sb, params, Expression{
+			Type:                ExpressionTypeLiteral,
+			LiteralType:         LiteralTypeVariable,
+			LiteralVariableName: fmt.Sprintf("input.%s.%s", paginate.CursorParamName, field.Name),
+			IsClauseRequired:    true,
+		})
+		columns = append(columns, fieldSQL(field))
+		formats = append(formats, "%s")
+		lits = append(lits, fmt.Sprintf("lit%d", g.LiteralIndex))
+	}
+
+	op := ">"
+	if paginate.Desc {
+		op = "<"
+	}
+
+	g.ExprIndex++
+	exprName := fmt.Sprintf("expr%d", g.ExprIndex)
+	format := fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), op, strings.Join(formats, ", "))
+	sb.WriteString(fmt.Sprintf("\t%s := fmt.Sprintf(%q, %s)\n", exprName, format, strings.Join(lits, ", ")))
+
+	g.writeExprResult(sb, exprName, addToGroupClauseNum)
+
+	sb.WriteString("\t}\n\n")
+}
+
+// writes the cursor struct for {paginate}, and functions to encode it from
+// the last row of a page and decode it from the next request. the encoding
+// is base64 json, which is opaque to callers but not tamper-proof.
+func (g *Generator) writeCursorType(sb *strings.Builder, query Query) {
+	paginate := query.Select.Paginate
+	typeName := query.Name + "Cursor"
+
+	g.addImport("encoding/base64")
+	g.addImport("encoding/json")
+
+	sb.WriteString(fmt.Sprintf("type %s struct {\n", typeName))
+	values := make([]string, 0, len(paginate.Fields))
+	for _, field := range paginate.Fields {
+		for _, col := range query.ResultColumns {
+			if col.Name == field.Name {
+				sb.WriteString(fmt.Sprintf("\t%s %s\n", col.Name, g.columnGoType(col)))
+			}
+		}
+		values = append(values, fmt.Sprintf("row.%s", field.Name))
+	}
+	sb.WriteString("}\n\n")
+
+	sb.WriteString("// returns the cursor for the page after row\n")
+	sb.WriteString(fmt.Sprintf("func Encode%s(row %sRow) string {\n", typeName, query.Name))
+	sb.WriteString(fmt.Sprintf("\tdata, _ := json.Marshal([]interface{}{%s})\n", strings.Join(values, ", ")))
+	sb.WriteString("\treturn base64.RawURLEncoding.EncodeToString(data)\n")
+	sb.WriteString("}\n\n")
+
+	sb.WriteString(fmt.Sprintf("func Decode%s(cursor string) (%s, error) {\n", typeName, typeName))
+	sb.WriteString(fmt.Sprintf("\tvar c %s\n", typeName))
+	sb.WriteString("\tdata, err := base64.RawURLEncoding.DecodeString(cursor)\n")
+	sb.WriteString("\tif err != nil {\n")
+	sb.WriteString("\t\treturn c, fmt.Errorf(\"invalid cursor: %w\", err)\n")
+	sb.WriteString("\t}\n")
+	sb.WriteString("\tvar values []json.RawMessage\n")
+	sb.WriteString("\tif err := json.Unmarshal(data, &values); err != nil {\n")
+	sb.WriteString("\t\treturn c, fmt.Errorf(\"invalid cursor: %w\", err)\n")
+	sb.WriteString("\t}\n")
+	sb.WriteString(fmt.Sprintf("\tif len(values) != %d {\n", len(paginate.Fields)))
+	sb.WriteString(fmt.Sprintf("\t\treturn c, fmt.Errorf(\"invalid cursor: expected %d values, got %%d\", len(values))\n", len(paginate.Fields)))
+	sb.WriteString("\t}\n")
+	for i, field := range paginate.Fields {
+		sb.WriteString(fmt.Sprintf("\tif err := json.Unmarshal(values[%d], &c.%s); err != nil {\n", i, field.Name))
+		sb.WriteString("\t\treturn c, fmt.Errorf(\"invalid cursor: %w\", err)\n")
+		sb.WriteString("\t}\n")
+	}
+	sb.WriteString("\treturn c, nil\n")
+	sb.WriteString("}\n\n")
+}
+
+func fieldSQL(f Field) string {
+	if f.TableName != "" {
+		return fmt.Sprintf("%s.%s", f.TableName, f.Name)
+	}
+	return f.Name
+}
+
+func sortOptionConstName(typeName string, option Field) string {
+	return typeName + goExportedName(fieldSQL(option))
+}
+
+// writes the enums for dynamic ORDER BY params. user input picks a
+// constant, so only whitelisted sql is ever written.
+func (g *Generator) writeSortEnums(sb *strings.Builder, query Query) {
+	var orderBy []OrderByItem
+	for _, cte := range query.Select.With {
+		orderBy = append(orderBy, cte.Select.OrderBy...)
+	}
+	orderBy = append(orderBy, query.Select.OrderBy...)
+
+	for _, item := range orderBy {
+		if item.SortParamName != "" {
+			typeName := query.Name + goExportedName(item.SortParamName)
+			sb.WriteString(fmt.Sprintf("type %s int\n\n", typeName))
+			sb.WriteString("const (\n")
+			for i, option := range item.SortOptions {
+				if i == 0 {
+					sb.WriteString(fmt.Sprintf("\t%s %s = iota\n", sortOptionConstName(typeName, option), typeName))
+				} else {
+					sb.WriteString(fmt.Sprintf("\t%s\n", sortOptionConstName(typeName, option)))
+				}
+			}
+			sb.WriteString(")\n\n")
+		}
+		if item.DirectionParamName != "" {
+			typeName := query.Name + goExportedName(item.DirectionParamName)
+			sb.WriteString(fmt.Sprintf("type %s int\n\n", typeName))
+			sb.WriteString("const (\n")
+			sb.WriteString(fmt.Sprintf("\t%sAsc %s = iota\n", typeName, typeName))
+			sb.WriteString(fmt.Sprintf("\t%sDesc\n", typeName))
+			sb.WriteString(")\n\n")
+		}
+	}
+}
+
+func (g *Generator) writeOrderBy(sb *strings.Builder, params []Param, orderBy []OrderByItem) {
+	format, lits := g.writeOrderByItems(sb, params, orderBy)
+	sb.WriteString(fmt.Sprintf("\tsb.WriteString(fmt.Sprintf(%q, %s))\n\n", " ORDER BY "+format, strings.Join(lits, ", ")))
+}
+
+// returns the ORDER BY items as a format string, without the ORDER BY,
+// and the lit variables for each %s in it
+func (g *Generator) writeOrderByItems(sb *strings.Builder, params []Param, orderBy []OrderByItem) (string, []string) {
+	lits := make([]string, 0, len(orderBy))
+	formats := make([]string, 0, len(orderBy))
+
+	// window ORDER BY is written inside the select list
+	isWritingFieldList := g.IsWritingFieldList
+	g.IsWritingFieldList = true
+	for _, item := range orderBy {
+		format := "%s"
+
+		if item.SortParamName != "" {
+			typeName := ""
+			for _, p := range params {
+				if p.Name == item.SortParamName {
+					typeName = p.GoType.Name
+				}
+			}
+
+			// unknown values fall back to the first option, same as the zero value
+			g.LiteralIndex++
+			sb.WriteString(fmt.Sprintf("\tvar lit%d string\n", g.LiteralIndex))
+			sb.WriteString(fmt.Sprintf("\tswitch input.%s {\n", item.SortParamName))
+			for _, option := range item.SortOptions[1:] {
+				sb.WriteString(fmt.Sprintf("\tcase %s:\n", sortOptionConstName(typeName, option)))
+				sb.WriteString(fmt.Sprintf("\t\tlit%d = %q\n", g.LiteralIndex, fieldSQL(option)))
+			}
+			sb.WriteString("\tdefault:\n")
+			sb.WriteString(fmt.Sprintf("\t\tlit%d = %q\n", g.LiteralIndex, fieldSQL(item.SortOptions[0])))
+			sb.WriteString("\t}\n")
+			lits = append(lits, fmt.Sprintf("lit%d", g.LiteralIndex))
+		} else {
+			lits = append(lits, fmt.Sprintf("lit%d", g.writeScalar(sb, params, item.Expr)))
+		}
+
+		if item.DirectionParamName != "" {
+			typeName := ""
+			for _, p := range params {
+				if p.Name == item.DirectionParamName {
+					typeName = p.GoType.Name
+				}
+			}
+
+			g.LiteralIndex++
+			sb.WriteString(fmt.Sprintf("\tlit%d := \"ASC\"\n", g.LiteralIndex))
+			sb.WriteString(fmt.Sprintf("\tif input.%s == %sDesc {\n", item.DirectionParamName, typeName))
+			sb.WriteString(fmt.Sprintf("\t\tlit%d = \"DESC\"\n", g.LiteralIndex))
+			sb.WriteString("\t}\n")
+			lits = append(lits, fmt.Sprintf("lit%d", g.LiteralIndex))
+			format += " %s"
+		} else if item.Direction != SortDirectionNone {
+			format += " " + item.Direction.String()
+		}
+
+		if item.Nulls != NullsOrderNone {
+			format += " " + item.Nulls.String()
+		}
+
+		formats = append(formats, format)
+	}
+	g.IsWritingFieldList = isWritingFieldList
+
+	return strings.Join(formats, ", "), lits
+}
+
+// returns the parenthesized window of an OVER clause or WINDOW definition as
+// a format string, and the lit variables for each %s in it
+func (g *Generator) writeWindow(sb *strings.Builder, params []Param, spec WindowSpec) (string, []string) {
+	var parts []string
+	var lits []string
+
+	if spec.Name != "" {
+		parts = append(parts, spec.Name)
+	}
+
+	if len(spec.PartitionBy) > 0 {
+		isWritingFieldList := g.IsWritingFieldList
+		g.IsWritingFieldList = true
+		formats := make([]string, 0, len(spec.PartitionBy))
+		for _, expr := range spec.PartitionBy {
+			lits = append(lits, fmt.Sprintf("lit%d", g.writeScalar(sb, params, expr)))
+			formats = append(formats, "%s")
+		}
+		g.IsWritingFieldList = isWritingFieldList
+
+		parts = append(parts, "PARTITION BY "+strings.Join(formats, ", "))
+	}
+
+	if len(spec.OrderBy) > 0 {
+		format, orderByLits := g.writeOrderByItems(sb, params, spec.OrderBy)
+		lits = append(lits, orderByLits...)
+		parts = append(parts, "ORDER BY "+format)
+	}
+
+	if spec.Frame != nil {
+		bounds := []FrameBound{spec.Frame.Start}
+		if spec.Frame.End != nil {
+			bounds = append(bounds, *spec.Frame.End)
+		}
+
+		formats := make([]string, 0, len(bounds))
+		for _, bound := range bounds {
+			if bound.Offset == nil {
+				formats = append(formats, bound.Type.String())
+				continue
+			}
+			lits = append(lits, fmt.Sprintf("lit%d", g.writeScalar(sb, params, *bound.Offset)))
+			formats = append(formats, "%s "+bound.Type.String())
+		}
+
+		if spec.Frame.End != nil {
+			parts = append(parts, fmt.Sprintf("%s BETWEEN %s AND %s", spec.Frame.Mode, formats[0], formats[1]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s %s", spec.Frame.Mode, formats[0]))
+		}
+	}
+
+	return "(" + strings.Join(parts, " ") + ")", lits
+}
+
+// writes a WITH clause, each CTE followed by the text before the next one.
+// CTE bodies are written like the main select, so optional params work the
+// same way and args are numbered in the order they appear.
+func (g *Generator) writeWith(sb *strings.Builder, params []Param, stmt SelectStmt) {
+	prefix := "WITH "
+	if stmt.WithRecursive {
+		prefix = "WITH RECURSIVE "
+	}
+
+	for _, cte := range stmt.With {
+		name := cte.Name
+		if len(cte.Columns) > 0 {
+			name += fmt.Sprintf("(%s)", strings.Join(cte.Columns, ", "))
+		}
+		sb.WriteString(fmt.Sprintf("\tsb.WriteString(%q)\n\n", prefix+name+" AS ("))
+
+		g.writeSelect(sb, params, cte.Select)
+
+		prefix = "), "
+	}
+
+	sb.WriteString("\tsb.WriteString(\") \")\n\n")
+}
+
+// writes a single select, from the select list through HAVING
+func (g *Generator) writeSelectCore(sb *strings.Builder, params []Param, stmt SelectStmt) {
+	distinct, distinctLits := g.writeDistinct(sb, params, stmt)
+	selectFormat, selectLits := g.writeSelectFields(sb, params, stmt.Fields)
+	selectFormat = distinct + selectFormat
+	selectLits = append(distinctLits, selectLits...)
+
+	from := " FROM " + stmt.From
+	if stmt.FromSubquery != nil {
+		from = " FROM %s"
+		selectLits = append(selectLits, fmt.Sprintf("lit%d", g.writeSubquery(sb, params, *stmt.FromSubquery, "(%s)")))
+	}
+	if stmt.FromAlias != "" {
+		from += fmt.Sprintf(" %s", stmt.FromAlias)
+	}
+
+	if len(selectLits) == 0 {
+		sb.WriteString(fmt.Sprintf("\tsb.WriteString(\"SELECT %s%s\")\n\n", selectFormat, from))
+	} else {
+		sb.WriteString(fmt.Sprintf("\tsb.WriteString(fmt.Sprintf(%q, %s))\n\n", "SELECT "+selectFormat+from, strings.Join(selectLits, ", ")))
+	}
+
+	for i, j := range stmt.Joins {
+		// joins in the same {if} share one if statement
+		isFirstInCondition := j.Condition != nil && (i == 0 || stmt.Joins[i-1].Condition != j.Condition)
+		isLastInCondition := j.Condition != nil && (i == len(stmt.Joins)-1 || stmt.Joins[i+1].Condition != j.Condition)
+
+		if isFirstInCondition {
+			sb.WriteString("\tif ")
+			g.writeTemplateExpression(sb, params, *j.Condition, false)
+			sb.WriteString(" {\n")
+		}
+
+		join := " " + j.JoinType.String()
+		if j.Natural {
+			join = " NATURAL " + j.JoinType.String()
+		}
+
+		var lits []string
+		if j.Subquery != nil {
+			format := "(%s)"
+			if j.Lateral {
+				format = "LATERAL (%s)"
+			}
+			lits = append(lits, fmt.Sprintf("lit%d", g.writeSubquery(sb, params, *j.Subquery, format)))
+			join += " %s"
+		} else {
+			join += " " + j.Table
+		}
+		if j.TableAlias != "" {
+			join += " " + j.TableAlias
+		}
+		if len(j.Using) > 0 {
+			join += fmt.Sprintf(" USING (%s)", strings.Join(j.Using, ", "))
+		}
+		if j.On.Type != ExpressionTypeNone {
+			join += " ON "
+		}
+
+		if len(lits) == 0 {
+			sb.WriteString(fmt.Sprintf("\tsb.WriteString(%q)\n", join))
+		} else {
+			sb.WriteString(fmt.Sprintf("\tsb.WriteString(fmt.Sprintf(%q, %s))\n", join, strings.Join(lits, ", ")))
+		}
+
+		if j.On.Type == ExpressionTypeNone {
+			sb.WriteString("\n")
+		} else {
+			g.writeExpression(sb, params, j.On, nil)
+		}
+
+		if isLastInCondition {
+			sb.WriteString("\n\t}\n\n")
+		}
+	}
+
+	if stmt.Where.Type != ExpressionTypeNone {
+		g.GenPossiblyOptionalClause = "WHERE"
+		g.writeExpression(sb, params, stmt.Where, nil)
+		g.GenPossiblyOptionalClause = ""
+	}
+
+	if len(stmt.GroupBy) > 0 {
+		g.IsWritingFieldList = true
+		lits := make([]string, 0, len(stmt.GroupBy))
+		formats := make([]string, 0, len(stmt.GroupBy))
+		for _, expr := range stmt.GroupBy {
+			lits = append(lits, fmt.Sprintf("lit%d", g.writeScalar(sb, params, expr)))
+			formats = append(formats, "%s")
+		}
+		g.IsWritingFieldList = false
+
+		format := " GROUP BY " + strings.Join(formats, ", ")
+		sb.WriteString(fmt.Sprintf("\tsb.WriteString(fmt.Sprintf(%q, %s))\n\n", format, strings.Join(lits, ", ")))
+	}
+
+	if stmt.Having.Type != ExpressionTypeNone {
+		g.GenPossiblyOptionalClause = "HAVING"
+		g.writeExpression(sb, params, stmt.Having, nil)
+		g.GenPossiblyOptionalClause = ""
+	}
+
+	if len(stmt.Windows) > 0 {
+		var lits []string
+		formats := make([]string, 0, len(stmt.Windows))
+		for _, window := range stmt.Windows {
+			format, windowLits := g.writeWindow(sb, params, window.Spec)
+			lits = append(lits, windowLits...)
+			formats = append(formats, window.Name+" AS "+format)
+		}
+
+		format := " WINDOW " + strings.Join(formats, ", ")
+		if len(lits) == 0 {
+			sb.WriteString(fmt.Sprintf("\tsb.WriteString(%q)\n\n", format))
+		} else {
+			sb.WriteString(fmt.Sprintf("\tsb.WriteString(fmt.Sprintf(%q, %s))\n\n", format, strings.Join(lits, ", ")))
+		}
+	}
+}
+
+// writes a select statement, without the trailing semicolon
+func (g *Generator) writeSelect(sb *strings.Builder, params []Param, stmt SelectStmt) {
+	if len(stmt.With) > 0 {
+		g.writeWith(sb, params, stmt)
+	}
+
+	g.writeSelectCore(sb, params, stmt)
+
+	for i, op := range stmt.SetOps {
+		// branches in the same {if} share one if statement
+		isFirstInCondition := op.Condition != nil && (i == 0 || stmt.SetOps[i-1].Condition != op.Condition)
+		isLastInCondition := op.Condition != nil && (i == len(stmt.SetOps)-1 || stmt.SetOps[i+1].Condition != op.Condition)
+
+		if isFirstInCondition {
+			sb.WriteString("\tif ")
+			g.writeTemplateExpression(sb, params, *op.Condition, false)
+			sb.WriteString(" {\n")
+		}
+
+		keyword := op.Type.String()
+		if op.All {
+			keyword += " ALL"
+		}
+		sb.WriteString(fmt.Sprintf("\tsb.WriteString(\" %s \")\n\n", keyword))
+
+		g.writeSelectCore(sb, params, op.Select)
+
+		if isLastInCondition {
+			sb.WriteString("\n\t}\n\n")
+		}
+	}
+
+	if len(stmt.OrderBy) > 0 {
+		g.writeOrderBy(sb, params, stmt.OrderBy)
+	}
+
+	if stmt.Limit != nil {
+		g.writeLimitOrOffset(sb, params, *stmt.Limit, "LIMIT")
+	}
+	if stmt.Offset != nil {
+		g.writeLimitOrOffset(sb, params, *stmt.Offset, "OFFSET")
+	}
+
+	for _, lock := range stmt.Locking {
+		clause := " " + lock.Strength.String()
+		if len(lock.Of) > 0 {
+			clause += " OF " + strings.Join(lock.Of, ", ")
+		}
+		if lock.Wait != LockWaitNone && lock.WaitCondition == nil {
+			clause += " " + lock.Wait.String()
+		}
+		sb.WriteString(fmt.Sprintf("\tsb.WriteString(%q)\n", clause))
+
+		if lock.WaitCondition != nil {
+			sb.WriteString("\tif ")
+			g.writeTemplateExpression(sb, params, *lock.WaitCondition, false)
+			sb.WriteString(" {\n")
+			sb.WriteString(fmt.Sprintf("\t\tsb.WriteString(%q)\n", " "+lock.Wait.String()))
+			sb.WriteString("\t}\n\n")
+		}
+	}
+}
+
+func insertIntoSQL(stmt InsertStmt) string {
+	insert := "INSERT INTO " + stmt.Table
+	if len(stmt.Columns) > 0 {
+		insert += fmt.Sprintf(" (%s)", strings.Join(stmt.Columns, ", "))
+	}
+	return insert + " VALUES "
+}
+
+// writes the values of a row to lit variables, and returns the row as a
+// format string with their names. optional params are inserted as NULL
+// when they're nil.
+func (g *Generator) writeValuesRow(sb *strings.Builder, params []Param, row []Expression) (string, []string) {
+	lits := make([]string, 0, len(row))
+	formats := make([]string, 0, len(row))
+
+	g.IsWritingFieldList = true
+	for _, expr := range row {
+		lits = append(lits, fmt.Sprintf("lit%d", g.writeScalar(sb, params, expr)))
+		formats = append(formats, "%s")
+	}
+	g.IsWritingFieldList = false
+
+	return fmt.Sprintf("(%s)", strings.Join(formats, ", ")), lits
+}
+
+// writes an insert statement, without the trailing semicolon
+func (g *Generator) writeInsert(sb *strings.Builder, params []Param, stmt InsertStmt) {
+	var lits []string
+	rows := make([]string, 0, len(stmt.Values))
+	for _, row := range stmt.Values {
+		format, rowLits := g.writeValuesRow(sb, params, row)
+		rows = append(rows, format)
+		lits = append(lits, rowLits...)
+	}
+
+	format := insertIntoSQL(stmt) + strings.Join(rows, ", ")
+	sb.WriteString(fmt.Sprintf("\tsb.WriteString(fmt.Sprintf(%q, %s))\n\n", format, strings.Join(lits, ", ")))
+
+	g.writeInsertSuffix(sb, params, stmt)
+}
+
+// writes the rows of {foreach row in rows: ,} to as many statements as needed
+// to stay under postgres's limit of 65535 bind params per statement. the rest
+// of the statement is written first, and the rows are numbered after it, so
+// it's known how many rows fit as they're written.
+func (g *Generator) writeBulkInsert(sb *strings.Builder, params []Param, stmt InsertStmt) {
+	loop := stmt.ValuesLoop
+
+	sb.WriteString("\tvar queries []string\n")
+	sb.WriteString("\tvar batches [][]interface{}\n\n")
+
+	sb.WriteString(fmt.Sprintf("\trows := input.%s\n", loop.ListName))
+	sb.WriteString("\tfor len(rows) > 0 {\n")
+	sb.WriteString("\tsb := strings.Builder{}\n")
+	sb.WriteString("\targs := []interface{}{}\n")
+	sb.WriteString("\targIndex := 1\n\n")
+
+	sb.WriteString("\tvar suffix string\n")
+	sb.WriteString("\t{\n")
+	sb.WriteString("\tsb := strings.Builder{}\n\n")
+	g.writeInsertSuffix(sb, params, stmt)
+	sb.WriteString("\tsuffix = sb.String()\n")
+	sb.WriteString("\t}\n\n")
+
+	sb.WriteString(fmt.Sprintf("\tsb.WriteString(%q)\n\n", insertIntoSQL(stmt)))
+
+	sb.WriteString("\tn := 0\n")
+	sb.WriteString("\trowArgs := 0\n")
+	sb.WriteString(fmt.Sprintf("\tfor _, %s := range rows {\n", loop.IteratorName))
+	sb.WriteString("\tif n > 0 && len(args)+rowArgs > 65535 {\n")
+	sb.WriteString("\t\tbreak\n")
+	sb.WriteString("\t}\n")
+	sb.WriteString("\tif n > 0 {\n")
+	sb.WriteString("\t\tsb.WriteString(\", \")\n")
+	sb.WriteString("\t}\n\n")
+	sb.WriteString("\tstart := len(args)\n")
+
+	format, lits := g.writeValuesRow(sb, params, stmt.Values[0])
+	sb.WriteString(fmt.Sprintf("\tsb.WriteString(fmt.Sprintf(%q, %s))\n", format, strings.Join(lits, ", ")))
+
+	sb.WriteString("\trowArgs = len(args) - start\n")
+	sb.WriteString("\tn++\n")
+	sb.WriteString("\t}\n")
+	sb.WriteString("\trows = rows[n:]\n\n")
+
+	sb.WriteString("\tsb.WriteString(suffix)\n")
+	sb.WriteString("\tsb.WriteString(\";\")\n\n")
+	sb.WriteString("\tqueries = append(queries, sb.String())\n")
+	sb.WriteString("\tbatches = append(batches, args)\n")
+	sb.WriteString("\t}\n\n")
+}
+
+// ON CONFLICT and RETURNING
+func (g *Generator) writeInsertSuffix(sb *strings.Builder, params []Param, stmt InsertStmt) {
+	if stmt.OnConflict != nil {
+		g.writeOnConflict(sb, params, *stmt.OnConflict)
+	}
+
+	if len(stmt.Returning) > 0 {
+		format, lits := g.writeSelectFields(sb, params, stmt.Returning)
+		format = " RETURNING " + format
+		if len(lits) == 0 {
+			sb.WriteString(fmt.Sprintf("\tsb.WriteString(%q)\n\n", format))
+		} else {
+			sb.WriteString(fmt.Sprintf("\tsb.WriteString(fmt.Sprintf(%q, %s))\n\n", format, strings.Join(lits, ", ")))
+		}
+	}
+}
+
+func (g *Generator) writeOnConflict(sb *strings.Builder, params []Param, onConflict OnConflict) {
+	clause := " ON CONFLICT"
+	if len(onConflict.Target) > 0 {
+		clause += fmt.Sprintf(" (%s)", strings.Join(onConflict.Target, ", "))
+	}
+
+	if !onConflict.DoUpdate {
+		sb.WriteString(fmt.Sprintf("\tsb.WriteString(%q)\n\n", clause+" DO NOTHING"))
+		return
+	}
+
+	g.IsWritingFieldList = true
+	lits := make([]string, 0, len(onConflict.Set))
+	formats := make([]string, 0, len(onConflict.Set))
+	for _, set := range onConflict.Set {
+		lits = append(lits, fmt.Sprintf("lit%d", g.writeScalar(sb, params, set.Value)))
+		formats = append(formats, set.Column+" = %s")
+	}
+	g.IsWritingFieldList = false
+
+	format := clause + " DO UPDATE SET " + strings.Join(formats, ", ")
+	sb.WriteString(fmt.Sprintf("\tsb.WriteString(fmt.Sprintf(%q, %s))\n\n", format, strings.Join(lits, ", ")))
+
+	if onConflict.Where.Type != ExpressionTypeNone {
+		g.GenPossiblyOptionalClause = "WHERE"
+		g.writeExpression(sb, params, onConflict.Where, nil)
+		g.GenPossiblyOptionalClause = ""
+	}
+}
+
+func (g *Generator) generateQuery(query Query) (string, error) {
 
 	sb := strings.Builder{}
 
-	sb.WriteString(fmt.Sprintf("package %s\n\n", g.PackageName))
-	sb.WriteString("import (\n\"strings\"\n\"fmt\"\n)\n\n")
+	if query.StatementType == StatementTypeSelect {
+		g.writeSortEnums(&sb, query)
+	}
+
+	for _, p := range query.Params {
+		if p.Type != ParamTypeStruct || !g.declare(p.TypeName) {
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf("type %s struct {\n", p.TypeName))
+		if p.Fields != nil {
+			// a declared type, where optional fields are pointers like params
+			for _, field := range p.Fields {
+				maybePointer := ""
+				if !field.Required {
+					maybePointer = "*"
+				}
+				sb.WriteString(fmt.Sprintf("\t%s %s%s\n", field.Name, maybePointer, g.paramGoType(field)))
+			}
+		}
+		for _, col := range p.StructFields {
+			sb.WriteString(fmt.Sprintf("\t%s %s\n", col.Name, g.columnGoType(col)))
+		}
+		sb.WriteString("}\n\n")
+	}
 
 	if len(query.Params) > 0 {
 		sb.WriteString("type ")
@@ -309,12 +1549,35 @@ func (g *Generator) generateQuery(query Query) ([]byte, error) {
 			} else if !p.Required {
 				sb.WriteString("*")
 			}
-			sb.WriteString(p.Type.String())
+			sb.WriteString(g.paramGoType(p))
 			sb.WriteString("\n")
 		}
 		sb.WriteString("}\n\n")
 	}
 
+	if len(query.ResultColumns) > 0 {
+		sb.WriteString("type ")
+		sb.WriteString(query.Name)
+		sb.WriteString("Row struct {\n")
+		for _, col := range query.ResultColumns {
+			sb.WriteString(fmt.Sprintf("\t%s %s\n", col.Name, g.columnGoType(col)))
+		}
+		sb.WriteString("}\n\n")
+	}
+
+	if query.StatementType == StatementTypeSelect && query.Select.Paginate != nil {
+		g.writeCursorType(&sb, query)
+	}
+
+	if query.StatementType == StatementTypeCopy {
+		g.writeCopy(&sb, query)
+		return sb.String(), nil
+	}
+
+	// query functions build the query with both
+	g.addImport("fmt")
+	g.addImport("strings")
+
 	sb.WriteString("func Query")
 
 	sb.WriteString(query.Name)
@@ -327,95 +1590,147 @@ func (g *Generator) generateQuery(query Query) ([]byte, error) {
 	} else {
 		sb.WriteString("()")
 	}
-	sb.WriteString(" (string, []interface{}) {\n")
-	sb.WriteString("\tsb := strings.Builder{}\n")
-	sb.WriteString("\targs := []interface{}{}\n\n")
 
-	if len(query.Params) > 0 {
-		sb.WriteString("\targIndex := 1\n\n")
+	// a bulk insert may need several statements
+	isBulkInsert := query.StatementType == StatementTypeInsert && query.Insert.ValuesLoop != nil
+
+	if isBulkInsert {
+		sb.WriteString(" ([]string, [][]interface{}) {\n")
+	} else {
+		sb.WriteString(" (string, []interface{}) {\n")
+		sb.WriteString("\tsb := strings.Builder{}\n")
+		sb.WriteString("\targs := []interface{}{}\n\n")
 	}
 
-	writeFields := func(fields []Field) {
-		for i, f := range fields {
-			if i > 0 {
-				sb.WriteString(", ")
-			}
-			if f.TableName != "" {
-				sb.WriteString(fmt.Sprintf("%s.", f.TableName))
-			}
-			sb.WriteString(f.Name)
-			if f.Alias != "" {
-				sb.WriteString(fmt.Sprintf(" %s", f.Alias))
-			}
-		}
+	// argIndex is declared before the statement, but it's only known whether
+	// it's used once the statement is written
+	body := strings.Builder{}
+
+	switch {
+	case query.StatementType == StatementTypeSelect:
+		g.writeSelect(&body, query.Params, query.Select)
+
+		body.WriteString("sb.WriteString(\";\")\n\n")
+
+	case isBulkInsert:
+		g.writeBulkInsert(&body, query.Params, query.Insert)
+
+	case query.StatementType == StatementTypeInsert:
+		g.writeInsert(&body, query.Params, query.Insert)
+
+		body.WriteString("sb.WriteString(\";\")\n\n")
+
+	default:
+		panic("only selects and inserts are supported")
 	}
 
-	switch query.StatementType {
-	case StatementTypeSelect:
-		sb.WriteString("\tsb.WriteString(\"SELECT ")
-		writeFields(query.Select.Fields)
+	if !isBulkInsert && g.IsArgIndexUsed {
+		sb.WriteString("\targIndex := 1\n\n")
+	}
+	sb.WriteString(body.String())
 
-		sb.WriteString(" FROM ")
-		sb.WriteString(query.Select.From)
-		if query.Select.FromAlias != "" {
-			sb.WriteString(fmt.Sprintf(" %s", query.Select.FromAlias))
-		}
-		sb.WriteString("\")\n\n")
+	if isBulkInsert {
+		sb.WriteString("\treturn queries, batches\n")
+	} else {
+		sb.WriteString("\treturn sb.String(), args\n")
+	}
+	sb.WriteString("}\n")
 
-		for _, j := range query.Select.Joins {
-			joinType := j.JoinType
-			sb.WriteString(fmt.Sprintf("\tsb.WriteString(\" %s %s %s ON \")\n", joinType, j.Table, j.TableAlias))
+	return sb.String(), nil
+}
 
-			g.writeExpression(&sb, query.Params, j.On, nil)
-		}
+// imports are only known once types have been written
+func (g *Generator) withHeader(body string) []byte {
+	header := strings.Builder{}
+	header.WriteString(fmt.Sprintf("package %s\n\n", g.PackageName))
+	g.writeImports(&header)
 
-		if query.Select.Where.Type != ExpressionTypeNone {
-			g.GenPossiblyOptionalWhereClause = true
-			g.writeExpression(&sb, query.Params, query.Select.Where, nil)
-			g.GenPossiblyOptionalWhereClause = false
-		}
+	return []byte(header.String() + body)
+}
 
-		if len(query.Select.OrderByFields) > 0 {
-			sb.WriteString(" ORDER BY ")
-			writeFields(query.Select.OrderByFields)
-		}
+// writes a function that loads rows with COPY FROM. the interfaces match
+// pgx's CopyFrom, so a pgx conn only needs a one line adapter, and they're
+// written once for every copy in the file
+func (g *Generator) writeCopy(sb *strings.Builder, query Query) {
+	g.addImport("context")
 
-		if query.Select.Limit != nil {
-			sb.WriteString("\tsb.WriteString(\" LIMIT ")
-			sb.WriteString(fmt.Sprintf("%d", *query.Select.Limit))
-			sb.WriteString("\")\n")
-		}
+	sourceName := strings.ToLower(query.Name[:1]) + query.Name[1:] + "Source"
 
-		sb.WriteString("sb.WriteString(\";\")\n\n")
+	columns := make([]string, 0, len(query.ResultColumns))
+	values := make([]string, 0, len(query.ResultColumns))
+	for _, col := range query.ResultColumns {
+		columns = append(columns, fmt.Sprintf("%q", col.Field.Name))
+		values = append(values, "row."+col.Name)
+	}
 
-	default:
-		panic("only selects are supported")
+	if g.declare("CopyFromSource") {
+		sb.WriteString("type CopyFromSource interface {\n")
+		sb.WriteString("\tNext() bool\n")
+		sb.WriteString("\tValues() ([]interface{}, error)\n")
+		sb.WriteString("\tErr() error\n")
+		sb.WriteString("}\n\n")
+
+		sb.WriteString("type CopyFromer interface {\n")
+		sb.WriteString("\tCopyFrom(ctx context.Context, table string, columns []string, source CopyFromSource) (int64, error)\n")
+		sb.WriteString("}\n\n")
 	}
 
-	sb.WriteString("\treturn sb.String(), args\n")
-	sb.WriteString("}\n")
+	// rows are converted to values one at a time, as they're copied
+	sb.WriteString(fmt.Sprintf("type %s struct {\n", sourceName))
+	sb.WriteString(fmt.Sprintf("\trows  []%sRow\n", query.Name))
+	sb.WriteString("\tindex int\n")
+	sb.WriteString("}\n\n")
+
+	sb.WriteString(fmt.Sprintf("func (s *%s) Next() bool {\n", sourceName))
+	sb.WriteString("\ts.index++\n")
+	sb.WriteString("\treturn s.index <= len(s.rows)\n")
+	sb.WriteString("}\n\n")
+
+	sb.WriteString(fmt.Sprintf("func (s *%s) Values() ([]interface{}, error) {\n", sourceName))
+	sb.WriteString("\trow := s.rows[s.index-1]\n")
+	sb.WriteString(fmt.Sprintf("\treturn []interface{}{%s}, nil\n", strings.Join(values, ", ")))
+	sb.WriteString("}\n\n")
+
+	sb.WriteString(fmt.Sprintf("func (s *%s) Err() error {\n", sourceName))
+	sb.WriteString("\treturn nil\n")
+	sb.WriteString("}\n\n")
 
-	return []byte(sb.String()), nil
+	sb.WriteString(fmt.Sprintf("func Copy%s(ctx context.Context, db CopyFromer, rows []%sRow) (int64, error) {\n", query.Name, query.Name))
+	sb.WriteString(fmt.Sprintf("\treturn db.CopyFrom(ctx, %q, []string{%s}, &%s{rows: rows})\n", query.Copy.Table, strings.Join(columns, ", "), sourceName))
+	sb.WriteString("}\n")
 }
 
-func Generate(schema Schema, queries Queries, outputPackage string) (string, error) {
-	// todo: preallocate
-	result := []byte{}
-	var err error
+func Generate(schema Schema, queries Queries, config Config) (string, error) {
+	// every query is written to one file, so they share its imports and types
+	file := Generator{
+		PackageName: config.OutputPackage,
+		Imports:     map[string]bool{},
+		Declared:    map[string]bool{},
+	}
+	body := strings.Builder{}
 
 	for _, q := range queries.Queries {
 		if q.IsFragment {
 			continue
 		}
 		g := Generator{}
-		g.PackageName = outputPackage
+		g.PackageName = config.OutputPackage
 		g.Schema = schema
-		result, err = g.generateQuery(q)
+		g.Types = config.Types
+		g.InListStyle = config.InListStyle
+		g.Imports = file.Imports
+		g.Declared = file.Declared
+		generated, err := g.generateQuery(q)
 		if err != nil {
 			panic("")
 		}
+		if body.Len() > 0 {
+			body.WriteString("\n")
+		}
+		body.WriteString(generated)
 	}
 
+	result := file.withHeader(body.String())
 	formattedResult, err := format.Source(result)
 	if err != nil {
 		// return unformatted result for tests
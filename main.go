@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strings"
 )
 
 // todo
@@ -15,7 +16,6 @@ import (
 // - performance pass
 
 // todo - template features
-// - type definitions
 // - table refs - some way to pass table names to fragments
 // - allow putting a select query etc in a fragment
 // - see if escape hatch is doable
@@ -23,18 +23,153 @@ import (
 // todo - more sql (postgres) support
 // - join / multiple tables in a query - check field names against correct tables
 //  - test that we error if unqualified field is in multiple tables
-// - add test for order by, and test that order by fields are checked
-// - group clause
-// - with queries
 // - save and output schema qualifiers
-// - "not" unary: https://www.postgresql.org/docs/current/functions-logical.html
-// - comparison predicates: https://www.postgresql.org/docs/current/functions-comparison.html
+
+// a go type referenced by generated code, eg "int64", or
+// "AuthorID" from "github.com/acme/ids"
+type GoType struct {
+	ImportPath string // empty for builtin types
+	Name       string
+}
+
+// parses "int64", "time.Time", or "github.com/acme/ids.AuthorID"
+func ParseGoType(s string) GoType {
+	lastSlash := strings.LastIndex(s, "/")
+	lastDot := strings.LastIndex(s, ".")
+	if lastDot <= lastSlash {
+		return GoType{Name: s}
+	}
+	return GoType{ImportPath: s[:lastDot], Name: s[lastDot+1:]}
+}
+
+// returns the package qualifier used in generated code.
+// assumes the package name matches the last element of the import path,
+// without a major version, eg "yaml" for "gopkg.in/yaml.v3", and "chi"
+// for "github.com/go-chi/chi/v5"
+func (t GoType) PackageName() string {
+	name := path.Base(t.ImportPath)
+	if isMajorVersion(name) && path.Dir(t.ImportPath) != "." {
+		name = path.Base(path.Dir(t.ImportPath))
+	}
+	if i := strings.LastIndex(name, ".v"); i > 0 && isMajorVersion(name[i+1:]) {
+		name = name[:i]
+	}
+	return name
+}
+
+// eg "v2"
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	for _, c := range s[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// the name has to be an identifier, and the import path, if any, can't have
+// empty elements or spaces, eg "github.com/acme/ids." is missing the name
+func isValidGoType(t GoType) bool {
+	if t.ImportPath != "" {
+		if strings.ContainsAny(t.ImportPath, " \t") {
+			return false
+		}
+		for _, element := range strings.Split(t.ImportPath, "/") {
+			if element == "" {
+				return false
+			}
+		}
+	}
+	if t.Name == "" {
+		return false
+	}
+	for i, c := range t.Name {
+		isLetter := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		isDigit := c >= '0' && c <= '9'
+		if !isLetter && (i == 0 || !isDigit) {
+			return false
+		}
+	}
+	return true
+}
+
+// returns the qualified type as written in generated code
+func (t GoType) String() string {
+	if t.ImportPath == "" {
+		return t.Name
+	}
+	return t.PackageName() + "." + t.Name
+}
+
+// maps either a postgres column type (eg "uuid"), or a specific
+// table.column pair, to a go type
+type TypeMapping struct {
+	ColumnType string
+	Table      string
+	Column     string
+	GoType     GoType
+}
+
+type TypeMap struct {
+	Mappings []TypeMapping
+}
+
+// table.column mappings take priority over column type mappings
+func (m TypeMap) LookupColumn(table string, field TableField) (GoType, bool) {
+	for _, mapping := range m.Mappings {
+		if mapping.Table != "" && mapping.Table == table && mapping.Column == field.Name {
+			return mapping.GoType, true
+		}
+	}
+	for _, mapping := range m.Mappings {
+		if mapping.ColumnType != "" && mapping.ColumnType == field.TypeName {
+			return mapping.GoType, true
+		}
+	}
+	return GoType{}, false
+}
+
+// finds the mapped go types with an unqualified name, for use as a param type.
+// a type mapped more than once is only returned once, so more than one match
+// means the name is ambiguous.
+func (m TypeMap) LookupName(name string) []GoType {
+	var matches []GoType
+	for _, mapping := range m.Mappings {
+		if mapping.GoType.Name != name {
+			continue
+		}
+		isDuplicate := false
+		for _, match := range matches {
+			isDuplicate = isDuplicate || match == mapping.GoType
+		}
+		if !isDuplicate {
+			matches = append(matches, mapping.GoType)
+		}
+	}
+	return matches
+}
+
+// controls how `IN {list}` is rendered
+type InListStyle int
+
+const (
+	// IN ($1, $2, ...), with one arg per item
+	InListStylePlaceholders InListStyle = iota
+	// = ANY($1), with the list passed as a single array arg
+	InListStyleAny
+)
 
 type Config struct {
 	SchemaPath    string
 	QueryPath     string
 	OutputPath    string
 	OutputPackage string
+	InListStyle   InListStyle
+
+	Types TypeMap
 }
 
 func (c *Config) Set(key string, val string) error {
@@ -47,12 +182,44 @@ func (c *Config) Set(key string, val string) error {
 		c.OutputPath = val
 	case "output_package":
 		c.OutputPackage = val
+	case "in_list_style":
+		switch val {
+		case "placeholders":
+			c.InListStyle = InListStylePlaceholders
+		case "any":
+			c.InListStyle = InListStyleAny
+		default:
+			return fmt.Errorf("unknown in_list_style: %s", val)
+		}
 	default:
 		return fmt.Errorf("unknown key: %s", key)
 	}
 	return nil
 }
 
+// key is either a column type, or a table.column pair
+func (c *Config) SetType(key string, val string) error {
+	if val == "" {
+		return fmt.Errorf("missing go type for %s", key)
+	}
+
+	mapping := TypeMapping{GoType: ParseGoType(val)}
+	if !isValidGoType(mapping.GoType) {
+		return fmt.Errorf("invalid go type for %s: %s", key, val)
+	}
+
+	table, column, found := strings.Cut(key, ".")
+	if found {
+		mapping.Table = table
+		mapping.Column = column
+	} else {
+		mapping.ColumnType = strings.ToLower(key)
+	}
+
+	c.Types.Mappings = append(c.Types.Mappings, mapping)
+	return nil
+}
+
 func (c Config) Validate() error {
 	if c.QueryPath == "" {
 		return fmt.Errorf("missing query_path")
@@ -72,6 +239,14 @@ func (c Config) Validate() error {
 func parseConfig(input string) (Config, error) {
 	result := Config{}
 
+	// the top-level section has no header. keys after a [types] header
+	// are type mappings:
+	//
+	// [types]
+	// uuid = "github.com/google/uuid.UUID"
+	// authors.id = "github.com/acme/ids.AuthorID"
+	section := ""
+
 	scanner := NewScanner(input)
 	for scanner.HasNextToken() {
 
@@ -80,11 +255,55 @@ func parseConfig(input string) (Config, error) {
 			return result, err
 		}
 
+		if tokenKey.Type == LeftBracket {
+			tokenSection, err := scanner.EatToken()
+			if err != nil {
+				return result, err
+			}
+			if tokenSection.Type != Identifier {
+				return result, fmt.Errorf("expected section name")
+			}
+			if tokenSection.LexemeLowered != "types" {
+				return result, fmt.Errorf("unknown section: %s", tokenSection.Lexeme)
+			}
+			section = tokenSection.LexemeLowered
+
+			token, err := scanner.EatToken()
+			if err != nil {
+				return result, err
+			}
+			if token.Type != RightBracket {
+				return result, fmt.Errorf("expected closing bracket")
+			}
+			continue
+		}
+
 		if tokenKey.Type != Identifier {
 			return result, fmt.Errorf("expected identifier")
 		}
 
-		token, err := scanner.EatToken()
+		key := tokenKey.Lexeme
+
+		token, err := scanner.PeekToken()
+		if err != nil {
+			return result, err
+		}
+
+		// table.column keys
+		if token.Type == Dot {
+			_, _ = scanner.EatToken()
+
+			tokenColumn, err := scanner.EatToken()
+			if err != nil {
+				return result, err
+			}
+			if tokenColumn.Type != Identifier {
+				return result, fmt.Errorf("expected column name after %s.", key)
+			}
+			key = key + "." + tokenColumn.Lexeme
+		}
+
+		token, err = scanner.EatToken()
 		if err != nil {
 			return result, err
 		}
@@ -102,9 +321,13 @@ func parseConfig(input string) (Config, error) {
 			return result, fmt.Errorf("expected string value")
 		}
 
-		err = result.Set(tokenKey.Lexeme, tokenValue.Literal.String())
+		if section == "types" {
+			err = result.SetType(key, tokenValue.Literal.String())
+		} else {
+			err = result.Set(key, tokenValue.Literal.String())
+		}
 		if err != nil {
-			return result, nil
+			return result, err
 		}
 	}
 
@@ -145,16 +368,16 @@ func run() error {
 		return fmt.Errorf("error parsing queries: %w", err)
 	}
 
-	checkErrors := CheckQueries(schema, queryParser)
+	checkErrors := CheckQueries(schema, queryParser, config.Types)
 	for _, e := range checkErrors {
-		fmt.Fprintf(os.Stderr, "%s", e.Err.Error())
+		fmt.Fprintf(os.Stderr, "%s", e.Error())
 	}
 
 	if len(checkErrors) > 0 {
 		return fmt.Errorf("checks failed")
 	}
 
-	generated, err := Generate(schema, queryParser, config.OutputPackage)
+	generated, err := Generate(schema, queryParser, config)
 	if err != nil {
 		return fmt.Errorf("error generating: %w", err)
 	}
@@ -3,15 +3,20 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 )
 
 // precedence high to low:
 // literal/grouping
-// multiply/divide
+// cast (::)
+// unary minus
+// multiply/divide/modulo
 // add/subtract
+// concatenation (||)
 // comparison (<, >, <=, >=)
 // equality (=, !=, like)
 // dynamic clause - {foreach}, {include}, {if}
+// not
 // and
 // or
 
@@ -33,6 +38,28 @@ const (
 	OpTypeNotLike
 	OpTypeIs
 	OpTypeIsNot
+	OpTypeIn
+	OpTypeNotIn
+	OpTypeILike
+	OpTypeNotILike
+	OpTypeSimilarTo
+	OpTypeNotSimilarTo
+	OpTypeIsDistinctFrom
+	OpTypeIsNotDistinctFrom
+	OpTypeBetween
+	OpTypeNotBetween
+
+	// arithmetic
+	OpTypeAdd
+	OpTypeSubtract
+	OpTypeMultiply
+	OpTypeDivide
+	OpTypeModulo
+	OpTypeConcat
+
+	// unary
+	OpTypeNot
+	OpTypeNegate
 )
 
 func (opType OpType) String() string {
@@ -64,17 +91,80 @@ func (opType OpType) String() string {
 		op = "IS"
 	case OpTypeIsNot:
 		op = "IS NOT"
+	case OpTypeIn:
+		op = "IN"
+	case OpTypeNotIn:
+		op = "NOT IN"
+	case OpTypeILike:
+		op = "ILIKE"
+	case OpTypeNotILike:
+		op = "NOT ILIKE"
+	case OpTypeSimilarTo:
+		op = "SIMILAR TO"
+	case OpTypeNotSimilarTo:
+		op = "NOT SIMILAR TO"
+	case OpTypeIsDistinctFrom:
+		op = "IS DISTINCT FROM"
+	case OpTypeIsNotDistinctFrom:
+		op = "IS NOT DISTINCT FROM"
+	case OpTypeBetween:
+		op = "BETWEEN"
+	case OpTypeNotBetween:
+		op = "NOT BETWEEN"
+
+	case OpTypeAdd:
+		op = "+"
+	case OpTypeSubtract:
+		op = "-"
+	case OpTypeMultiply:
+		op = "*"
+	case OpTypeDivide:
+		op = "/"
+	case OpTypeModulo:
+		op = "%"
+	case OpTypeConcat:
+		op = "||"
+
+	case OpTypeNot:
+		op = "NOT"
+	case OpTypeNegate:
+		op = "-"
 	default:
 		panic("unhandled op")
 	}
 	return op
 }
 
+// logical ops join clauses, which may be dropped dynamically.
+// all other ops produce a single clause or value.
+func (opType OpType) IsLogical() bool {
+	return opType == OpTypeAnd || opType == OpTypeOr
+}
+
+// precedence of ops that produce values, used to decide where parentheses
+// are needed when writing nested expressions. higher binds tighter.
+func (opType OpType) Precedence() int {
+	switch opType {
+	case OpTypeNegate:
+		return 5
+	case OpTypeMultiply, OpTypeDivide, OpTypeModulo:
+		return 4
+	case OpTypeAdd, OpTypeSubtract:
+		return 3
+	case OpTypeConcat:
+		return 2
+	default:
+		return 1
+	}
+}
+
 type StatementType int
 
 const (
 	StatementTypeNone StatementType = iota
 	StatementTypeSelect
+	StatementTypeInsert
+	StatementTypeCopy
 )
 
 type ExpressionType int
@@ -82,10 +172,18 @@ type ExpressionType int
 const (
 	ExpressionTypeNone ExpressionType = iota
 	ExpressionTypeBinary
+	ExpressionTypeUnary   // operand is in Left
+	ExpressionTypeBetween // Left BETWEEN Right AND BetweenUpper
+	ExpressionTypeFunction
+	ExpressionTypeCase
+	ExpressionTypeCast // operand is in Left
 	ExpressionTypeLiteral
 	ExpressionTypeIf
 	ExpressionTypeForLoop
 	ExpressionTypeFragment
+	ExpressionTypeCursor // keyset pagination condition, see Paginate
+	ExpressionTypeSubquery
+	ExpressionTypeListLength // len(ids) in a template condition, the list is in Left
 )
 
 type LiteralType int
@@ -97,8 +195,15 @@ const (
 	LiteralTypeFieldName
 	LiteralTypeVariable
 	LiteralTypeNull
+	LiteralTypeBool
+	LiteralTypeUnknown // only valid after IS / IS NOT
 )
 
+type CaseWhen struct {
+	When *Expression
+	Then *Expression
+}
+
 type ElseIf struct {
 	IfExpr   *Expression
 	BodyExpr *Expression
@@ -109,15 +214,23 @@ type Expression struct {
 	Left  *Expression
 	Right *Expression
 
+	// upper bound for between expression type
+	BetweenUpper *Expression
+
 	Type ExpressionType
 
 	// literal expression type
-	LiteralType         LiteralType
-	LiteralNumber       int
-	LiteralString       string
-	LiteralField        Field
-	LiteralVariableName string // this will get rewritten by checker to reference a globally unique name (including across fragments)
-	IsQueryScopedParam  bool
+	LiteralType          LiteralType
+	LiteralNumber        int
+	LiteralString        string
+	LiteralStringType    string // for typed string literals, eg interval '1 day'
+	LiteralBool          bool
+	LiteralField         Field
+	LiteralVariableName  string // this will get rewritten by checker to reference a globally unique name (including across fragments)
+	LiteralVariableField string // for {row.field}, where the variable is a struct
+	OptionalStructName   string // set by checker for {filter.field} when filter is optional, it's nil checked first
+	IsQueryScopedParam   bool
+	IsListParam          bool // set by checker
 
 	// set to true while checking if any children in its left/right subtrees
 	// are also required, or if it's an expression that can be determined as required.
@@ -129,16 +242,77 @@ type Expression struct {
 	ForLoopIteratorName string // the name for each item in the list. this may be rewitten by checker
 	ForLoopVarName      string // the list param we're ranging over. this may be rewritten by checker
 	ForLoopJoinByOr     bool   // defaults to AND, set to true for OR
+	ForLoopIsOptional   bool   // set by checker when ranging over an optional list, which drops the loop when nil
+
+	// set by checker for expressions that produce a value. Name is not set.
+	ValueType TableField
 
 	// If expression
 	ElseIfs  []ElseIf
 	ElseBody *Expression
 
+	// function expression type
+	FunctionName     string // lowercased
+	FunctionArgs     []Expression
+	FunctionStar     bool // eg count(*)
+	FunctionDistinct bool // eg count(DISTINCT x)
+
+	// case expression type
+	CaseOperand *Expression // only set for simple case, eg CASE x WHEN 1 THEN ...
+	CaseWhens   []CaseWhen
+	CaseElse    *Expression
+
+	// cast expression type
+	CastTypeName   string // lowercased, eg varchar(255) or int[]
+	CastIsFunction bool   // written as CAST(x AS type) rather than x::type
+
+	// window call, eg rank() OVER (PARTITION BY x ORDER BY y)
+	Over *WindowSpec
+
+	// cursor expression type
+	Paginate *Paginate
+
+	// subquery expression type, eg (SELECT ...) or EXISTS (SELECT ...).
+	// its own clauses are written separately, so it isn't walked by Children
+	Subquery       *SelectStmt
+	SubqueryExists bool
+
 	// fragment expression type
 	FragmentName string
 	FragmentArgs []string
 }
 
+// the direct subexpressions that are written as sql, for walking the tree.
+// template conditions in {if} are written as go, so aren't included.
+func (expr *Expression) Children() []*Expression {
+	var children []*Expression
+	for _, child := range []*Expression{expr.Left, expr.Right, expr.BetweenUpper, expr.CaseOperand} {
+		if child != nil {
+			children = append(children, child)
+		}
+	}
+	for i := range expr.FunctionArgs {
+		children = append(children, &expr.FunctionArgs[i])
+	}
+	for _, caseWhen := range expr.CaseWhens {
+		children = append(children, caseWhen.When, caseWhen.Then)
+	}
+	if expr.Over != nil {
+		children = append(children, expr.Over.Children()...)
+	}
+	for _, elseif := range expr.ElseIfs {
+		if elseif.BodyExpr != nil {
+			children = append(children, elseif.BodyExpr)
+		}
+	}
+	for _, child := range []*Expression{expr.CaseElse, expr.ElseBody} {
+		if child != nil {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
 type Field struct {
 	// valid fields:
 	// "tablename".id
@@ -152,6 +326,9 @@ type Field struct {
 	Name      string
 	TableName string
 	Alias     string
+
+	// set instead of Name for select fields that are expressions, eg lower(name)
+	Expr *Expression
 }
 
 type JoinType int
@@ -185,23 +362,368 @@ func (t JoinType) String() string {
 
 type Join struct {
 	Table      string
+	Subquery   *SelectStmt // set instead of Table for JOIN (SELECT ...) alias
+	Lateral    bool        // the subquery can reference the tables before it
 	TableAlias string
 	JoinType   JoinType
-	On         Expression
+
+	// a join has one of ON, USING, or NATURAL, except for a cross join,
+	// which has none. USING and NATURAL merge the columns they join on.
+	On      Expression
+	Using   []string
+	Natural bool
+
+	// set for joins wrapped in {if ...}{end}, which are only included
+	// when the template condition is true. joins in the same {if} share it
+	Condition *Expression
+}
+
+// a common table expression in a WITH clause, eg `recent AS (SELECT ...)`
+type CommonTableExpr struct {
+	Name string
+	// optional column names, otherwise they're named by the select list
+	Columns []string
+	Select  SelectStmt
+}
+
+type SetOpType int
+
+const (
+	SetOpTypeNone SetOpType = iota
+	SetOpTypeUnion
+	SetOpTypeIntersect
+	SetOpTypeExcept
+)
+
+func (t SetOpType) String() string {
+	switch t {
+	case SetOpTypeUnion:
+		return "UNION"
+	case SetOpTypeIntersect:
+		return "INTERSECT"
+	case SetOpTypeExcept:
+		return "EXCEPT"
+	default:
+		return "Unknown"
+	}
+}
+
+// a select combined with the one before it, eg UNION ALL SELECT ...
+type SetOp struct {
+	Type   SetOpType
+	All    bool
+	Select SelectStmt // only has the fields through HAVING
+
+	// set for branches wrapped in {if ...}{end}, which are only
+	// included when the template condition is true
+	Condition *Expression
+}
+
+type WindowFrameMode int
+
+const (
+	WindowFrameModeNone WindowFrameMode = iota
+	WindowFrameModeRows
+	WindowFrameModeRange
+	WindowFrameModeGroups
+)
+
+func (m WindowFrameMode) String() string {
+	switch m {
+	case WindowFrameModeRows:
+		return "ROWS"
+	case WindowFrameModeRange:
+		return "RANGE"
+	case WindowFrameModeGroups:
+		return "GROUPS"
+	default:
+		return "Unknown"
+	}
+}
+
+// ordered from the start of the partition to its end
+type FrameBoundType int
+
+const (
+	FrameBoundTypeNone FrameBoundType = iota
+	FrameBoundTypeUnboundedPreceding
+	FrameBoundTypePreceding
+	FrameBoundTypeCurrentRow
+	FrameBoundTypeFollowing
+	FrameBoundTypeUnboundedFollowing
+)
+
+func (t FrameBoundType) String() string {
+	switch t {
+	case FrameBoundTypeUnboundedPreceding:
+		return "UNBOUNDED PRECEDING"
+	case FrameBoundTypePreceding:
+		return "PRECEDING"
+	case FrameBoundTypeCurrentRow:
+		return "CURRENT ROW"
+	case FrameBoundTypeFollowing:
+		return "FOLLOWING"
+	case FrameBoundTypeUnboundedFollowing:
+		return "UNBOUNDED FOLLOWING"
+	default:
+		return "Unknown"
+	}
+}
+
+type FrameBound struct {
+	Type   FrameBoundType
+	Offset *Expression // for PRECEDING and FOLLOWING, eg 3 PRECEDING
+}
+
+// eg ROWS BETWEEN 3 PRECEDING AND CURRENT ROW
+type WindowFrame struct {
+	Mode  WindowFrameMode
+	Start FrameBound
+	End   *FrameBound // nil without BETWEEN, which ends at the current row
+}
+
+// whether the frame always has at least the current row in it
+func (f *WindowFrame) IncludesCurrentRow() bool {
+	if f.Start.Type > FrameBoundTypeCurrentRow {
+		return false
+	}
+	return f.End == nil || f.End.Type >= FrameBoundTypeCurrentRow
+}
+
+// the window of an OVER clause or a WINDOW definition
+type WindowSpec struct {
+	// an existing window to build on, eg OVER w or OVER (w ORDER BY x)
+	Name string
+
+	PartitionBy []Expression
+	OrderBy     []OrderByItem // dynamic sort isn't supported here
+	Frame       *WindowFrame
+}
+
+// an OVER clause written as a bare window name, eg OVER w
+func (spec *WindowSpec) IsNameOnly() bool {
+	return spec.Name != "" && len(spec.PartitionBy) == 0 && len(spec.OrderBy) == 0 && spec.Frame == nil
+}
+
+func (spec *WindowSpec) Children() []*Expression {
+	var children []*Expression
+	for i := range spec.PartitionBy {
+		children = append(children, &spec.PartitionBy[i])
+	}
+	for i := range spec.OrderBy {
+		children = append(children, &spec.OrderBy[i].Expr)
+	}
+	if spec.Frame != nil {
+		if spec.Frame.Start.Offset != nil {
+			children = append(children, spec.Frame.Start.Offset)
+		}
+		if spec.Frame.End != nil && spec.Frame.End.Offset != nil {
+			children = append(children, spec.Frame.End.Offset)
+		}
+	}
+	return children
+}
+
+// a window definition in a WINDOW clause, eg `w AS (PARTITION BY x)`
+type NamedWindow struct {
+	Name string
+	Spec WindowSpec
+}
+
+// column = value, in ON CONFLICT DO UPDATE SET
+type SetClause struct {
+	Column string
+	Value  Expression
+}
+
+type OnConflict struct {
+	// has to match a primary key or unique constraint. DO NOTHING can
+	// leave it out, to skip rows that conflict with any of them
+	Target   []string
+	DoUpdate bool // otherwise DO NOTHING
+	Set      []SetClause
+	Where    Expression
+}
+
+type InsertStmt struct {
+	Table string
+	// the table's columns are used in order if not listed
+	Columns []string
+	// one list of values per row
+	Values     [][]Expression
+	ValuesLoop *ValuesLoop
+	OnConflict *OnConflict
+	Returning  []Field
+}
+
+// {foreach row in rows: ,} in VALUES, which writes the single row in
+// Values for each item of the list
+type ValuesLoop struct {
+	IteratorName string // rewritten by checker
+	ListName     string
+}
+
+// copy LoadAuthors INTO authors (first_name, bio)
+type CopyStmt struct {
+	Table string
+	// the table's columns are used in order if not listed
+	Columns []string
+}
+
+type LockStrength int
+
+const (
+	LockStrengthNone LockStrength = iota
+	LockStrengthUpdate
+	LockStrengthNoKeyUpdate
+	LockStrengthShare
+	LockStrengthKeyShare
+)
+
+func (s LockStrength) String() string {
+	switch s {
+	case LockStrengthUpdate:
+		return "FOR UPDATE"
+	case LockStrengthNoKeyUpdate:
+		return "FOR NO KEY UPDATE"
+	case LockStrengthShare:
+		return "FOR SHARE"
+	case LockStrengthKeyShare:
+		return "FOR KEY SHARE"
+	default:
+		return "Unknown"
+	}
+}
+
+// what to do when a row is already locked. by default it waits
+type LockWait int
+
+const (
+	LockWaitNone LockWait = iota
+	LockWaitNowait
+	LockWaitSkipLocked
+)
+
+func (w LockWait) String() string {
+	switch w {
+	case LockWaitNowait:
+		return "NOWAIT"
+	case LockWaitSkipLocked:
+		return "SKIP LOCKED"
+	default:
+		return ""
+	}
+}
+
+// row locking, eg FOR UPDATE OF jobs SKIP LOCKED
+type LockingClause struct {
+	Strength LockStrength
+	Of       []string // tables or aliases, otherwise every table is locked
+	Wait     LockWait
+
+	// set for {if skipLocked} SKIP LOCKED {end}, where the wait
+	// policy is only used when the template condition is true
+	WaitCondition *Expression
 }
 
-// todo: maybe needs to be more general expression type
-// for being able to select from sub tables etc
 type SelectStmt struct {
+	With []CommonTableExpr
+	// in a recursive WITH, a CTE can select from itself after a UNION
+	WithRecursive bool
+
+	Distinct bool
+	// postgres DISTINCT ON (expr, ...), which implies Distinct
+	DistinctOn     []Expression
+	DistinctOnLine int
+
 	Fields []Field
 
-	From      string
-	FromAlias string
+	From         string
+	FromSubquery *SelectStmt // set instead of From for FROM (SELECT ...) alias
+	FromAlias    string
+
+	Joins   []Join
+	Where   Expression
+	GroupBy []Expression
+	Having  Expression
+	Windows []NamedWindow
+
+	// ORDER BY, LIMIT and OFFSET below apply to the combined result
+	SetOps []SetOp
+
+	Limit   *Expression // a number or an int param
+	Offset  *Expression
+	OrderBy []OrderByItem
+
+	// set by {paginate}, which also sets Where, OrderBy and Limit
+	Paginate *Paginate
+
+	Locking []LockingClause
+
+	// set by checker for a correlated subquery, the fields that reference
+	// the enclosing select's tables
+	OuterFields []Field
+}
+
+// keyset pagination, eg {paginate after cursor by created_at, id limit pageSize}.
+// rows after the cursor are matched with a row-value comparison, which needs
+// every column sorted in the same direction.
+type Paginate struct {
+	CursorParamName string
+	Fields          []Field
+	Desc            bool
+	Limit           *Expression // a number or an int param
+}
+
+type SortDirection int
+
+const (
+	SortDirectionNone SortDirection = iota
+	SortDirectionAsc
+	SortDirectionDesc
+)
+
+func (d SortDirection) String() string {
+	switch d {
+	case SortDirectionAsc:
+		return "ASC"
+	case SortDirectionDesc:
+		return "DESC"
+	default:
+		return ""
+	}
+}
+
+type NullsOrder int
+
+const (
+	NullsOrderNone NullsOrder = iota
+	NullsOrderFirst
+	NullsOrderLast
+)
+
+func (n NullsOrder) String() string {
+	switch n {
+	case NullsOrderFirst:
+		return "NULLS FIRST"
+	case NullsOrderLast:
+		return "NULLS LAST"
+	default:
+		return ""
+	}
+}
+
+type OrderByItem struct {
+	Expr Expression // not set for a dynamic sort column
 
-	Joins         []Join
-	Where         Expression
-	Limit         *int
-	OrderByFields []Field // ignores `Alias`
+	// dynamic sort column, eg {sort in (first_name, last_name)}. the param is
+	// an enum in generated code, and its zero value is the first option.
+	SortParamName string
+	SortOptions   []Field
+
+	Direction          SortDirection
+	DirectionParamName string // dynamic direction, eg {dir}
+	Nulls              NullsOrder
 }
 
 type ParamType int
@@ -210,6 +732,16 @@ const (
 	ParamTypeNone ParamType = iota
 	ParamTypeString
 	ParamTypeNumber
+	ParamTypeBool
+	// a go type from the type mapping config, resolved by checker
+	ParamTypeCustom
+	// enums declared by ORDER BY, eg {sort in (first_name, last_name)} {dir}
+	ParamTypeSortColumn
+	ParamTypeSortDirection
+	// the decoded cursor declared by {paginate}
+	ParamTypeCursor
+	// a struct generated for the query, eg the rows of a bulk insert
+	ParamTypeStruct
 )
 
 // returns the go type to be used in codegen
@@ -219,6 +751,8 @@ func (p ParamType) String() string {
 		return "string"
 	case ParamTypeNumber:
 		return "int"
+	case ParamTypeBool:
+		return "bool"
 	default:
 		panic("unexpected type")
 	}
@@ -227,6 +761,8 @@ func (p ParamType) String() string {
 type Param struct {
 	Name     string
 	Type     ParamType
+	TypeName string // set for ParamTypeCustom
+	GoType   GoType // set by checker for ParamTypeCustom, or parser for sort and cursor params
 	Required bool
 	IsList   bool
 	// helps identify to codegen if param comes from input struct or not
@@ -234,6 +770,20 @@ type Param struct {
 	// todo: maybe this replaces IsQueryScoped
 	// this provides a globally unique name for params to avoid collisions between fragments with reused names
 	GlobalName string
+
+	// set by checker for ParamTypeStruct, in order. Name is the go field name
+	StructFields []ResultColumn
+	// set by checker instead of StructFields when the struct is a declared type
+	Fields []Param
+}
+
+// a column in the result of a query, populated by checker
+type ResultColumn struct {
+	Name string // alias if given, otherwise the field name
+	// source table for the column, for resolving table.column type mappings.
+	// empty if the column isn't directly from a table
+	TableName string
+	Field     TableField
 }
 
 // note: fragments only can contain an expression currently,
@@ -246,13 +796,24 @@ type Query struct {
 	// used when IsFragment=false
 	StatementType StatementType
 	Select        SelectStmt
+	Insert        InsertStmt
+	Copy          CopyStmt
+	ResultColumns []ResultColumn
 
 	// used when IsFragment=true
 	FragmentExpression Expression
 }
 
+// a struct type declared in the query file, which params can use.
+// eg type AuthorFilter { name: string?, minID: int? }
+type TypeDef struct {
+	Name   string
+	Fields []Param
+}
+
 type Queries struct {
 	Queries []Query
+	Types   []TypeDef
 }
 
 type QueryParser struct {
@@ -386,44 +947,233 @@ func (p *QueryParser) parseMaybeQuotedName() string {
 }
 
 // next token is `limit`
-func (p *QueryParser) parseLimit() int {
-	token := p.EatIdentifier("limit")
+// next token is `limit` or `offset`. the value is validated by the checker
+func (p *QueryParser) parseLimitOrOffset() Expression {
+	_ = p.EatToken()
+	return p.parseUnary()
+}
+
+// next token is `{`, followed by `paginate`
+func (p *QueryParser) parsePaginate() Paginate {
+	var paginate Paginate
+
+	_ = p.EatTokenOfType(LeftBrace)
+	_ = p.EatIdentifier("paginate")
+	_ = p.EatIdentifier("after")
+	paginate.CursorParamName = p.EatTokenOfType(Identifier).Lexeme
+	_ = p.EatIdentifier(KeywordBy)
+
+	for i := 0; ; i++ {
+		paginate.Fields = append(paginate.Fields, p.parseFieldName())
+
+		desc := false
+		token := p.PeekToken()
+		if token.IsKeyword(KeywordAsc, KeywordDesc) {
+			_ = p.EatToken()
+			desc = token.IsKeyword(KeywordDesc)
+		}
+		if i == 0 {
+			paginate.Desc = desc
+		} else if desc != paginate.Desc {
+			p.AddError(fmt.Errorf("paginate columns must all be sorted in the same direction"))
+		}
+
+		if p.PeekToken().Type != Comma {
+			break
+		}
+		_ = p.EatToken()
+	}
+
+	token := p.PeekToken()
+	if token.IsKeyword(KeywordLimit) {
+		_ = p.EatToken()
+		token = p.PeekToken()
+		if token.Type == Identifier {
+			token = p.EatToken()
+			paginate.Limit = &Expression{
+				Type:                ExpressionTypeLiteral,
+				LiteralType:         LiteralTypeVariable,
+				LiteralVariableName: token.Lexeme,
+			}
+		} else {
+			limit := p.parseLiteral()
+			paginate.Limit = &limit
+		}
+	}
+
+	_ = p.EatTokenOfType(RightBrace)
+
+	return paginate
+}
+
+// adds the cursor condition, ORDER BY and LIMIT for {paginate}
+func (stmt *SelectStmt) expandPaginate() {
+	cursor := Expression{
+		Type:     ExpressionTypeCursor,
+		Paginate: stmt.Paginate,
+	}
+	if stmt.Where.Type == ExpressionTypeNone {
+		stmt.Where = cursor
+	} else {
+		where := stmt.Where
+		stmt.Where = Expression{
+			Type:  ExpressionTypeBinary,
+			Op:    OpTypeAnd,
+			Left:  &where,
+			Right: &cursor,
+		}
+	}
+
+	direction := SortDirectionNone
+	if stmt.Paginate.Desc {
+		direction = SortDirectionDesc
+	}
+	for _, field := range stmt.Paginate.Fields {
+		stmt.OrderBy = append(stmt.OrderBy, OrderByItem{
+			Expr: Expression{
+				Type:         ExpressionTypeLiteral,
+				LiteralType:  LiteralTypeFieldName,
+				LiteralField: field,
+			},
+			Direction: direction,
+		})
+	}
+
+	stmt.Limit = stmt.Paginate.Limit
+}
+
+// next token is `group`
+func (p *QueryParser) parseGroupBy() []Expression {
+	var res []Expression
+
+	_ = p.EatIdentifier(KeywordGroup)
+	_ = p.EatIdentifier(KeywordBy)
 
-	token = p.EatTokenOfType(Number)
+	for {
+		expr := p.parseConcat()
+		res = append(res, expr)
 
-	n, ok := token.Literal.(NumberLiteral)
-	if !ok {
-		panic("expected literal to be NumberLiteral")
+		if p.PeekToken().Type != Comma {
+			break
+		}
+		_ = p.EatToken()
 	}
 
-	return int(n)
+	return res
 }
 
 // next token is `order`
-func (p *QueryParser) parseOrderBy() []Field {
-	var res []Field
+func (p *QueryParser) parseOrderBy() []OrderByItem {
+	var res []OrderByItem
 
 	_ = p.EatIdentifier(KeywordOrder)
 	_ = p.EatIdentifier(KeywordBy)
-	token := p.PeekToken()
 
-	for token.Type != RightBrace {
+	for {
+		var item OrderByItem
 
-		field := p.parseFieldName()
-		res = append(res, field)
+		token := p.PeekToken()
+		if token.Type == LeftBrace {
+			// {sort in (first_name, last_name)}
+			_ = p.EatToken()
+			item.SortParamName = p.EatTokenOfType(Identifier).Lexeme
+			_ = p.EatIdentifier(KeywordIn)
+			_ = p.EatTokenOfType(LeftParen)
+			for p.PeekToken().Type != RightParen {
+				if len(item.SortOptions) > 0 {
+					_ = p.EatTokenOfType(Comma)
+				}
+				item.SortOptions = append(item.SortOptions, p.parseFieldName())
+			}
+			_ = p.EatTokenOfType(RightParen)
+			_ = p.EatTokenOfType(RightBrace)
+
+			if len(item.SortOptions) == 0 {
+				p.AddError(fmt.Errorf("expected at least one sort column for %s", item.SortParamName))
+			}
+		} else {
+			item.Expr = p.parseConcat()
+		}
 
 		token = p.PeekToken()
-		if token.Type != Comma {
-			break
+		if token.IsKeyword(KeywordAsc) {
+			_ = p.EatToken()
+			item.Direction = SortDirectionAsc
+		} else if token.IsKeyword(KeywordDesc) {
+			_ = p.EatToken()
+			item.Direction = SortDirectionDesc
+		} else if token.Type == LeftBrace {
+			// {dir}
+			_ = p.EatToken()
+			item.DirectionParamName = p.EatTokenOfType(Identifier).Lexeme
+			_ = p.EatTokenOfType(RightBrace)
 		}
 
-		token = p.EatTokenOfType(Comma)
 		token = p.PeekToken()
+		if token.IsKeyword(KeywordNulls) {
+			_ = p.EatToken()
+			token = p.EatTokenOfType(Identifier)
+			switch token.LexemeLowered {
+			case KeywordFirst:
+				item.Nulls = NullsOrderFirst
+			case KeywordLast:
+				item.Nulls = NullsOrderLast
+			default:
+				p.AddError(fmt.Errorf("expected FIRST or LAST after NULLS"))
+			}
+		}
+
+		res = append(res, item)
+
+		if p.PeekToken().Type != Comma {
+			break
+		}
+		_ = p.EatToken()
 	}
 
 	return res
 }
 
+// converts eg first_name or a.first_name to FirstName or AFirstName,
+// for naming generated types and constants
+func goExportedName(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '_' || r == '.'
+	})
+	for i, part := range parts {
+		parts[i] = strings.ToUpper(part[:1]) + part[1:]
+	}
+	return strings.Join(parts, "")
+}
+
+// the enum params declared by ORDER BY
+func orderByParams(queryName string, orderBy []OrderByItem) []Param {
+	var params []Param
+	for _, item := range orderBy {
+		if item.SortParamName != "" {
+			params = append(params, Param{
+				Name:          item.SortParamName,
+				Type:          ParamTypeSortColumn,
+				GoType:        GoType{Name: queryName + goExportedName(item.SortParamName)},
+				Required:      true,
+				IsQueryScoped: true,
+				GlobalName:    "input." + item.SortParamName,
+			})
+		}
+		if item.DirectionParamName != "" {
+			params = append(params, Param{
+				Name:          item.DirectionParamName,
+				Type:          ParamTypeSortDirection,
+				GoType:        GoType{Name: queryName + goExportedName(item.DirectionParamName)},
+				Required:      true,
+				IsQueryScoped: true,
+				GlobalName:    "input." + item.DirectionParamName,
+			})
+		}
+	}
+	return params
+}
+
 func (p *QueryParser) parseLiteral() Expression {
 	var expr Expression
 
@@ -431,7 +1181,7 @@ func (p *QueryParser) parseLiteral() Expression {
 
 	isNonTemplateSingleQuotedString := token.Type == String && !token.SingleQuoted && !p.IsParsingTemplate
 	// todo: will want to check any keyword literal, not just null
-	isNonTemplateNonKeywordIdentifier := token.Type == Identifier && token.LexemeLowered != KeywordNull && !p.IsParsingTemplate
+	isNonTemplateNonKeywordIdentifier := token.Type == Identifier && !token.IsKeyword(KeywordNull, KeywordTrue, KeywordFalse) && !p.IsParsingTemplate
 
 	if isNonTemplateSingleQuotedString || isNonTemplateNonKeywordIdentifier {
 		field := p.parseFieldName()
@@ -449,6 +1199,14 @@ func (p *QueryParser) parseLiteral() Expression {
 				LiteralType:      LiteralTypeNull,
 				IsClauseRequired: true,
 			}
+		} else if token.IsKeyword(KeywordTrue, KeywordFalse) {
+			token = p.EatToken()
+			expr = Expression{
+				Type:             ExpressionTypeLiteral,
+				LiteralType:      LiteralTypeBool,
+				LiteralBool:      token.LexemeLowered == KeywordTrue,
+				IsClauseRequired: true,
+			}
 		} else if p.IsParsingTemplate {
 			token = p.EatToken()
 			expr = Expression{
@@ -457,6 +1215,12 @@ func (p *QueryParser) parseLiteral() Expression {
 				LiteralVariableName: token.Lexeme,
 				IsClauseRequired:    true,
 			}
+
+			// filter.minID
+			if p.PeekToken().Type == Dot {
+				_ = p.EatToken()
+				expr.LiteralVariableField = p.EatTokenOfType(Identifier).Lexeme
+			}
 		}
 	} else if token.Type == Number {
 		token = p.EatToken()
@@ -479,6 +1243,12 @@ func (p *QueryParser) parseLiteral() Expression {
 			LiteralVariableName: token.Lexeme,
 		}
 
+		// {row.field}
+		if p.PeekToken().Type == Dot {
+			_ = p.EatToken()
+			expr.LiteralVariableField = p.EatTokenOfType(Identifier).Lexeme
+		}
+
 		token = p.EatTokenOfType(RightBrace)
 
 	} else if token.Type == String {
@@ -502,27 +1272,479 @@ func (p *QueryParser) parseLiteral() Expression {
 	return expr
 }
 
-func (p *QueryParser) parseGrouping() Expression {
-	token := p.PeekToken()
-
-	if token.Type == LeftParen {
-		token = p.EatToken()
+// next token is the function name
+func (p *QueryParser) parseFunctionCall() Expression {
+	token := p.EatTokenOfType(Identifier)
 
-		expr := p.parseExpression()
+	expr := Expression{
+		Type:         ExpressionTypeFunction,
+		FunctionName: token.LexemeLowered,
+	}
 
-		token = p.EatTokenOfType(RightParen)
+	_ = p.EatTokenOfType(LeftParen)
 
-		return expr
+	token = p.PeekToken()
+	if token.Type == Star {
+		_ = p.EatToken()
+		expr.FunctionStar = true
+	} else if token.IsKeyword(KeywordDistinct) {
+		_ = p.EatToken()
+		expr.FunctionDistinct = true
+	}
+
+	for token.Type != RightParen && !expr.FunctionStar {
+		if len(expr.FunctionArgs) > 0 {
+			_ = p.EatTokenOfType(Comma)
+		}
+
+		arg := p.parseExpression()
+		expr.FunctionArgs = append(expr.FunctionArgs, arg)
+
+		token = p.PeekToken()
+	}
+
+	_ = p.EatTokenOfType(RightParen)
+
+	if p.PeekToken().IsKeyword(KeywordOver) {
+		_ = p.EatToken()
+
+		var spec WindowSpec
+		if p.PeekToken().Type == LeftParen {
+			spec = p.parseWindowSpec()
+		} else {
+			spec.Name = p.EatTokenOfType(Identifier).Lexeme
+		}
+		expr.Over = &spec
+	}
+
+	return expr
+}
+
+// next token is `(`
+func (p *QueryParser) parseWindowSpec() WindowSpec {
+	var spec WindowSpec
+
+	_ = p.EatTokenOfType(LeftParen)
+
+	token := p.PeekToken()
+	if token.Type == Identifier && !token.IsKeyword(KeywordPartition, KeywordOrder, KeywordRows, KeywordRange, KeywordGroups) {
+		spec.Name = p.EatToken().Lexeme
+	}
+
+	if p.PeekToken().IsKeyword(KeywordPartition) {
+		_ = p.EatToken()
+		_ = p.EatIdentifier(KeywordBy)
+
+		for {
+			spec.PartitionBy = append(spec.PartitionBy, p.parseConcat())
+			if p.PeekToken().Type != Comma {
+				break
+			}
+			_ = p.EatToken()
+		}
+	}
+
+	if p.PeekToken().IsKeyword(KeywordOrder) {
+		spec.OrderBy = p.parseOrderBy()
+
+		// sort params are declared by the query's own ORDER BY
+		for _, item := range spec.OrderBy {
+			if item.SortParamName != "" || item.DirectionParamName != "" {
+				p.AddError(fmt.Errorf("dynamic ORDER BY can't be used in a window"))
+			}
+		}
+	}
+
+	if p.PeekToken().IsKeyword(KeywordRows, KeywordRange, KeywordGroups) {
+		frame := p.parseWindowFrame()
+		spec.Frame = &frame
+	}
+
+	_ = p.EatTokenOfType(RightParen)
+
+	return spec
+}
+
+// next token is `rows`, `range` or `groups`
+func (p *QueryParser) parseWindowFrame() WindowFrame {
+	var frame WindowFrame
+
+	token := p.EatToken()
+	switch token.LexemeLowered {
+	case KeywordRows:
+		frame.Mode = WindowFrameModeRows
+	case KeywordRange:
+		frame.Mode = WindowFrameModeRange
+	case KeywordGroups:
+		frame.Mode = WindowFrameModeGroups
+	}
+
+	if !p.PeekToken().IsKeyword(KeywordBetween) {
+		frame.Start = p.parseFrameBound()
+		if frame.Start.Type == FrameBoundTypeFollowing || frame.Start.Type == FrameBoundTypeUnboundedFollowing {
+			p.AddError(fmt.Errorf("a frame without BETWEEN can't start after the current row"))
+		}
+		return frame
+	}
+
+	_ = p.EatToken()
+	frame.Start = p.parseFrameBound()
+	_ = p.EatIdentifier(KeywordAnd)
+	end := p.parseFrameBound()
+	frame.End = &end
+
+	if frame.Start.Type == FrameBoundTypeUnboundedFollowing || end.Type == FrameBoundTypeUnboundedPreceding || frame.Start.Type > end.Type {
+		p.AddError(fmt.Errorf("frame can't start after it ends"))
+	}
+
+	return frame
+}
+
+func (p *QueryParser) parseFrameBound() FrameBound {
+	token := p.PeekToken()
+	if token.IsKeyword(KeywordUnbounded) {
+		_ = p.EatToken()
+		token = p.EatToken()
+		switch {
+		case token.IsKeyword(KeywordPreceding):
+			return FrameBound{Type: FrameBoundTypeUnboundedPreceding}
+		case token.IsKeyword(KeywordFollowing):
+			return FrameBound{Type: FrameBoundTypeUnboundedFollowing}
+		default:
+			p.AddError(fmt.Errorf("expected PRECEDING or FOLLOWING after UNBOUNDED"))
+			return FrameBound{}
+		}
+	}
+	if token.IsKeyword(KeywordCurrent) {
+		_ = p.EatToken()
+		_ = p.EatIdentifier(KeywordRow)
+		return FrameBound{Type: FrameBoundTypeCurrentRow}
+	}
+
+	// eg 3 PRECEDING or {offset} FOLLOWING
+	offset := p.parseConcat()
+	token = p.EatToken()
+	switch {
+	case token.IsKeyword(KeywordPreceding):
+		return FrameBound{Type: FrameBoundTypePreceding, Offset: &offset}
+	case token.IsKeyword(KeywordFollowing):
+		return FrameBound{Type: FrameBoundTypeFollowing, Offset: &offset}
+	default:
+		p.AddError(fmt.Errorf("expected PRECEDING or FOLLOWING after frame offset"))
+		return FrameBound{}
+	}
+}
+
+// next token is `case`
+func (p *QueryParser) parseCase() Expression {
+	_ = p.EatIdentifier(KeywordCase)
+
+	expr := Expression{
+		Type: ExpressionTypeCase,
+	}
+
+	token := p.PeekToken()
+	if !token.IsKeyword(KeywordWhen) {
+		operand := p.parseConcat()
+		expr.CaseOperand = &operand
+	}
+
+	token = p.PeekToken()
+	for token.IsKeyword(KeywordWhen) {
+		_ = p.EatToken()
+		when := p.parseExpression()
+
+		_ = p.EatIdentifier(KeywordThen)
+		then := p.parseExpression()
+
+		expr.CaseWhens = append(expr.CaseWhens, CaseWhen{
+			When: &when,
+			Then: &then,
+		})
+
+		token = p.PeekToken()
+	}
+
+	if len(expr.CaseWhens) == 0 {
+		p.AddError(fmt.Errorf("expected 'when' in case expression"))
+	}
+
+	if token.IsKeyword(KeywordElse) {
+		_ = p.EatToken()
+		elseExpr := p.parseExpression()
+		expr.CaseElse = &elseExpr
+	}
+
+	_ = p.EatIdentifier(KeywordEnd)
+
+	return expr
+}
+
+// next token is the type name, eg interval '1 day'
+func (p *QueryParser) parseTypedLiteral() Expression {
+	typeToken := p.EatTokenOfType(Identifier)
+	token := p.EatTokenOfType(String)
+
+	return Expression{
+		Type:              ExpressionTypeLiteral,
+		LiteralType:       LiteralTypeString,
+		LiteralString:     token.Literal.String(),
+		LiteralStringType: typeToken.LexemeLowered,
+	}
+}
+
+// parses a type name for a cast, eg uuid, varchar(255), double precision or int[]
+func (p *QueryParser) parseTypeName() string {
+	token := p.EatTokenOfType(Identifier)
+	name := token.LexemeLowered
+
+	// multi-word type names
+	next := p.PeekToken()
+	switch {
+	case name == "double" && next.LexemeLowered == "precision",
+		name == "character" && next.LexemeLowered == "varying":
+		_ = p.EatToken()
+		name += " " + next.LexemeLowered
+	case (name == "timestamp" || name == "time") && next.IsKeyword("with", "without"):
+		_ = p.EatToken()
+		_ = p.EatIdentifier("time")
+		_ = p.EatIdentifier("zone")
+		name += fmt.Sprintf(" %s time zone", next.LexemeLowered)
+	}
+
+	// type modifiers, eg numeric(10, 2)
+	if p.PeekToken().Type == LeftParen {
+		_ = p.EatToken()
+		var modifiers []string
+		for p.PeekToken().Type != RightParen {
+			if len(modifiers) > 0 {
+				_ = p.EatTokenOfType(Comma)
+			}
+			modifiers = append(modifiers, p.EatTokenOfType(Number).Lexeme)
+		}
+		_ = p.EatTokenOfType(RightParen)
+		name += fmt.Sprintf("(%s)", strings.Join(modifiers, ", "))
+	}
+
+	for p.PeekToken().Type == LeftBracket {
+		_ = p.EatToken()
+		_ = p.EatTokenOfType(RightBracket)
+		name += "[]"
+	}
+
+	return name
+}
+
+// next token is `cast`
+func (p *QueryParser) parseCastFunction() Expression {
+	_ = p.EatIdentifier(KeywordCast)
+	_ = p.EatTokenOfType(LeftParen)
+
+	operand := p.parseExpression()
+
+	_ = p.EatIdentifier(KeywordAs)
+	typeName := p.parseTypeName()
+
+	_ = p.EatTokenOfType(RightParen)
+
+	return Expression{
+		Type:           ExpressionTypeCast,
+		Left:           &operand,
+		CastTypeName:   typeName,
+		CastIsFunction: true,
+	}
+}
+
+// next token is the opening paren, followed by SELECT
+func (p *QueryParser) parseSubquery() SelectStmt {
+	_ = p.EatTokenOfType(LeftParen)
+	_ = p.EatIdentifier(KeywordSelect)
+
+	stmt := p.parseSelect()
+	if stmt.Paginate != nil {
+		p.AddError(fmt.Errorf("paginate can only be used in the main select"))
+	}
+	// sort params are declared by the query, and only its own ORDER BY is checked for them
+	for _, item := range stmt.OrderBy {
+		if item.SortParamName != "" || item.DirectionParamName != "" {
+			p.AddError(fmt.Errorf("dynamic ORDER BY can't be used in a subquery"))
+		}
+	}
+
+	_ = p.EatTokenOfType(RightParen)
+	return stmt
+}
+
+func (p *QueryParser) parseGrouping() Expression {
+	token := p.PeekToken()
+
+	if token.Type == LeftParen && p.PeekTokenAfter(1).IsKeyword(KeywordSelect) && !p.IsParsingTemplate {
+		subquery := p.parseSubquery()
+		return Expression{
+			Type:     ExpressionTypeSubquery,
+			Subquery: &subquery,
+		}
+	}
+
+	if token.Type == Identifier && !p.IsParsingTemplate {
+		next := p.PeekTokenAfter(1)
+
+		if token.IsKeyword(KeywordCase) {
+			return p.parseCase()
+		}
+		if token.IsKeyword(KeywordExists) && next.Type == LeftParen {
+			_ = p.EatToken()
+			subquery := p.parseSubquery()
+			return Expression{
+				Type:           ExpressionTypeSubquery,
+				Subquery:       &subquery,
+				SubqueryExists: true,
+			}
+		}
+		if token.IsKeyword(KeywordCast) && next.Type == LeftParen {
+			return p.parseCastFunction()
+		}
+		if next.Type == LeftParen {
+			return p.parseFunctionCall()
+		}
+		// an identifier can't otherwise be followed by a single quoted string
+		if next.Type == String && next.SingleQuoted {
+			return p.parseTypedLiteral()
+		}
+	}
+
+	// len(ids), which templates can compare to choose between filtering by
+	// the list or not
+	if token.IsKeyword(KeywordLen) && p.PeekTokenAfter(1).Type == LeftParen && p.IsParsingTemplate {
+		_ = p.EatToken()
+		_ = p.EatTokenOfType(LeftParen)
+		list := p.parseLiteral()
+		_ = p.EatTokenOfType(RightParen)
+
+		return Expression{
+			Type: ExpressionTypeListLength,
+			Left: &list,
+		}
+	}
+
+	if token.Type == LeftParen {
+		token = p.EatToken()
+
+		expr := p.parseExpression()
+
+		token = p.EatTokenOfType(RightParen)
+
+		return expr
 	}
 
 	return p.parseLiteral()
 }
 
+// parses postfix casts, eg {id}::uuid, which bind tighter than any operator
+func (p *QueryParser) parseCast() Expression {
+	expr := p.parseGrouping()
+
+	for p.PeekToken().Type == ColonColon && !p.IsParsingTemplate {
+		_ = p.EatToken()
+
+		operand := expr
+		expr = Expression{
+			Type:         ExpressionTypeCast,
+			Left:         &operand,
+			CastTypeName: p.parseTypeName(),
+		}
+	}
+
+	return expr
+}
+
+// parses a unary minus, or passes through to cast
+func (p *QueryParser) parseUnary() Expression {
+	token := p.PeekToken()
+	if token.Type != Minus {
+		return p.parseCast()
+	}
+
+	_ = p.EatToken()
+	operand := p.parseUnary()
+
+	return Expression{
+		Type: ExpressionTypeUnary,
+		Op:   OpTypeNegate,
+		Left: &operand,
+	}
+}
+
+// parses a left-associative chain of binary ops. opForToken returns
+// OpTypeNone if the token doesn't continue the chain.
+func (p *QueryParser) parseBinaryChain(next func() Expression, opForToken func(Token) OpType) Expression {
+	left := next()
+	expr := &left
+
+	opType := opForToken(p.PeekToken())
+
+	for opType != OpTypeNone {
+		_ = p.EatToken()
+
+		right := next()
+
+		expr = &Expression{
+			Type:  ExpressionTypeBinary,
+			Op:    opType,
+			Left:  expr,
+			Right: &right,
+		}
+
+		opType = opForToken(p.PeekToken())
+	}
+
+	return *expr
+}
+
+func (p *QueryParser) parseMultiplicative() Expression {
+	return p.parseBinaryChain(p.parseUnary, func(t Token) OpType {
+		switch t.Type {
+		case Star:
+			return OpTypeMultiply
+		case Slash:
+			return OpTypeDivide
+		case Percent:
+			return OpTypeModulo
+		}
+		return OpTypeNone
+	})
+}
+
+func (p *QueryParser) parseAdditive() Expression {
+	return p.parseBinaryChain(p.parseMultiplicative, func(t Token) OpType {
+		switch t.Type {
+		case Plus:
+			return OpTypeAdd
+		case Minus:
+			return OpTypeSubtract
+		}
+		return OpTypeNone
+	})
+}
+
+// || binds looser than arithmetic, but tighter than comparisons
+func (p *QueryParser) parseConcat() Expression {
+	return p.parseBinaryChain(p.parseAdditive, func(t Token) OpType {
+		if t.Type == PipePipe {
+			return OpTypeConcat
+		}
+		return OpTypeNone
+	})
+}
+
 func (p *QueryParser) parseComparison() Expression {
-	left := p.parseGrouping()
+	left := p.parseConcat()
 	token := p.PeekToken()
+	token2 := p.PeekTokenAfter(1)
 
 	opType := OpTypeEquals
+	// number of tokens that make up the operator, eg 3 for NOT SIMILAR TO
+	opTokenCount := 1
 
 	if token.Type == Equal {
 		opType = OpTypeEquals
@@ -536,28 +1758,112 @@ func (p *QueryParser) parseComparison() Expression {
 		opType = OpTypeLessOrEqual
 	} else if token.Type == GreaterEqual {
 		opType = OpTypeGreaterOrEqual
-	} else if token.Type == Identifier && token.LexemeLowered == KeywordNot {
+	} else if token.IsKeyword(KeywordNot) {
 		// todo: something better, probably want to know all keyword combinations and how they map to ops
-		token = p.PeekToken()
-		if token.Type == Identifier && token.LexemeLowered == KeywordLike {
+		opTokenCount = 2
+		if token2.IsKeyword(KeywordLike) {
 			opType = OpTypeNotLike
+		} else if token2.IsKeyword(KeywordILike) {
+			opType = OpTypeNotILike
+		} else if token2.IsKeyword(KeywordIn) {
+			opType = OpTypeNotIn
+		} else if token2.IsKeyword(KeywordBetween) {
+			opType = OpTypeNotBetween
+		} else if token2.IsKeyword(KeywordSimilar) {
+			opType = OpTypeNotSimilarTo
+			opTokenCount = 3
 		} else {
-			panic("not supported")
+			p.AddError(fmt.Errorf("unsupported operator: NOT %s", token2.Lexeme))
 		}
-	} else if token.Type == Identifier && token.LexemeLowered == KeywordLike {
+	} else if token.IsKeyword(KeywordLike) {
 		opType = OpTypeLike
-	} else if token.Type == Identifier && token.LexemeLowered == KeywordIs {
+	} else if token.IsKeyword(KeywordILike) {
+		opType = OpTypeILike
+	} else if token.IsKeyword(KeywordIn) {
+		opType = OpTypeIn
+	} else if token.IsKeyword(KeywordBetween) {
+		opType = OpTypeBetween
+	} else if token.IsKeyword(KeywordSimilar) {
+		opType = OpTypeSimilarTo
+		opTokenCount = 2
+	} else if token.IsKeyword(KeywordIs) {
 		opType = OpTypeIs
-		token = p.PeekToken()
-		if token.Type == Identifier && token.LexemeLowered == KeywordNot {
+		next := token2
+		if next.IsKeyword(KeywordNot) {
 			opType = OpTypeIsNot
+			opTokenCount = 2
+			next = p.PeekTokenAfter(2)
+		}
+		if next.IsKeyword(KeywordDistinct) {
+			if opType == OpTypeIs {
+				opType = OpTypeIsDistinctFrom
+			} else {
+				opType = OpTypeIsNotDistinctFrom
+			}
+			opTokenCount += 2
 		}
 	} else {
 		return left
 	}
 
-	token = p.EatToken()
-	right := p.parseGrouping()
+	for i := 0; i < opTokenCount; i++ {
+		token = p.EatToken()
+	}
+
+	if opType == OpTypeSimilarTo || opType == OpTypeNotSimilarTo {
+		if !token.IsKeyword(KeywordTo) {
+			p.AddError(fmt.Errorf("expected 'to' after 'similar'"))
+		}
+	}
+	if opType == OpTypeIsDistinctFrom || opType == OpTypeIsNotDistinctFrom {
+		if !token.IsKeyword(KeywordFrom) {
+			p.AddError(fmt.Errorf("expected 'from' after 'distinct'"))
+		}
+	}
+
+	if opType == OpTypeBetween || opType == OpTypeNotBetween {
+		// bounds are parsed as values rather than expressions,
+		// since the AND separating them would otherwise be parsed as a logical AND
+		lower := p.parseConcat()
+		_ = p.EatIdentifier(KeywordAnd)
+		upper := p.parseConcat()
+
+		return Expression{
+			Type:         ExpressionTypeBetween,
+			Op:           opType,
+			Left:         &left,
+			Right:        &lower,
+			BetweenUpper: &upper,
+		}
+	}
+
+	// ids IS EMPTY in a template is len(ids) = 0, and IS NOT EMPTY is len(ids) > 0
+	if (opType == OpTypeIs || opType == OpTypeIsNot) && p.PeekToken().IsKeyword(KeywordEmpty) && p.IsParsingTemplate {
+		_ = p.EatToken()
+
+		lengthOp := OpTypeEquals
+		if opType == OpTypeIsNot {
+			lengthOp = OpTypeGreater
+		}
+		return Expression{
+			Type:  ExpressionTypeBinary,
+			Op:    lengthOp,
+			Left:  &Expression{Type: ExpressionTypeListLength, Left: &left},
+			Right: &Expression{Type: ExpressionTypeLiteral, LiteralType: LiteralTypeNumber, LiteralNumber: 0},
+		}
+	}
+
+	var right Expression
+	if (opType == OpTypeIs || opType == OpTypeIsNot) && p.PeekToken().IsKeyword(KeywordUnknown) {
+		_ = p.EatToken()
+		right = Expression{
+			Type:             ExpressionTypeLiteral,
+			LiteralType:      LiteralTypeUnknown,
+			IsClauseRequired: true,
+		}
+	} else {
+		right = p.parseConcat()
+	}
 
 	return Expression{
 		Type:  ExpressionTypeBinary,
@@ -567,6 +1873,31 @@ func (p *QueryParser) parseComparison() Expression {
 	}
 }
 
+// {foreach query in queries: AND}
+// returns the iterator name, the list name, and the token items are joined with
+func (p *QueryParser) parseForEachHeader() (string, string, Token) {
+	// eat {
+	_ = p.EatToken()
+	// eat foreach
+	_ = p.EatToken()
+
+	iteratorName := p.EatTokenOfType(Identifier).Lexeme
+
+	token := p.EatTokenOfType(Identifier)
+	if token.LexemeLowered != KeywordIn {
+		panic("expected in")
+	}
+
+	listName := p.EatTokenOfType(Identifier).Lexeme
+
+	_ = p.EatTokenOfType(Colon)
+	separator := p.EatToken()
+
+	_ = p.EatTokenOfType(RightBrace)
+
+	return iteratorName, listName, separator
+}
+
 func (p *QueryParser) parseDynamicClause() Expression {
 	token := p.PeekToken()
 	if token.Type != LeftBrace {
@@ -588,37 +1919,16 @@ func (p *QueryParser) parseDynamicClause() Expression {
 		// parse opening
 		// 	{foreach query in queries: AND}
 		{
-			// eat {
-			_ = p.EatToken()
-			// eat foreach
-			_ = p.EatToken()
-
-			token = p.EatTokenOfType(Identifier)
-
-			expr.ForLoopIteratorName = token.Lexeme
-
-			token = p.EatTokenOfType(Identifier)
-			if token.LexemeLowered != KeywordIn {
-				panic("expected in")
-			}
-
-			token = p.EatTokenOfType(Identifier)
-
-			expr.ForLoopVarName = token.Lexeme
-
-			token = p.EatTokenOfType(Colon)
-			token = p.EatTokenOfType(Identifier)
+			expr.ForLoopIteratorName, expr.ForLoopVarName, token = p.parseForEachHeader()
 
 			lowered := token.LexemeLowered
-			if lowered == KeywordAnd {
+			if token.Type == Identifier && lowered == KeywordAnd {
 				expr.ForLoopJoinByOr = false
-			} else if lowered == KeywordOr {
+			} else if token.Type == Identifier && lowered == KeywordOr {
 				expr.ForLoopJoinByOr = true
 			} else {
 				panic("must join for loop with AND or OR")
 			}
-
-			token = p.EatTokenOfType(RightBrace)
 		}
 
 		// parse body of loop into the Left of the for loop expression.
@@ -783,10 +2093,27 @@ func (p *QueryParser) parseDynamicClause() Expression {
 	return expr
 }
 
+// NOT binds tighter than AND, but looser than comparisons
+func (p *QueryParser) parseNot() Expression {
+	token := p.PeekToken()
+	if !token.IsKeyword(KeywordNot) {
+		return p.parseDynamicClause()
+	}
+
+	_ = p.EatToken()
+	operand := p.parseNot()
+
+	return Expression{
+		Type: ExpressionTypeUnary,
+		Op:   OpTypeNot,
+		Left: &operand,
+	}
+}
+
 // note: very similar code to parseOr
 // pratt-style parser probably cleaner
 func (p *QueryParser) parseAnd() Expression {
-	left := p.parseDynamicClause()
+	left := p.parseNot()
 	expr := &left
 
 	token := p.PeekToken()
@@ -794,7 +2121,7 @@ func (p *QueryParser) parseAnd() Expression {
 	for token.Type == Identifier && token.LexemeLowered == KeywordAnd {
 		token = p.EatToken()
 
-		right := p.parseDynamicClause()
+		right := p.parseNot()
 
 		expr = &Expression{
 			Type:  ExpressionTypeBinary,
@@ -874,179 +2201,657 @@ func (p *QueryParser) parseAliasForTable() string {
 	if token.Type == Identifier && token.LexemeLowered == KeywordAs {
 		p.EatToken()
 
-		token = p.EatToken()
-		if IsReservedKeyword(token.LexemeLowered) {
-			p.AddError(fmt.Errorf("cannot used reserved word as table alias"))
+		token = p.EatToken()
+		if IsReservedKeyword(token.LexemeLowered) {
+			p.AddError(fmt.Errorf("cannot used reserved word as table alias"))
+		}
+
+		aliasName := token.Lexeme
+		return aliasName
+	}
+
+	// with or without "as", only non-reserved identifiers allowed here
+	token = p.PeekToken()
+	if token.Type == Identifier && !IsReservedKeyword(token.LexemeLowered) {
+		p.EatToken()
+		aliasName := token.Lexeme
+		return aliasName
+	}
+
+	return ""
+}
+
+func (p *QueryParser) parseFieldName() Field {
+	token := p.PeekToken()
+	field := Field{}
+
+	if token.Type == Star {
+		_ = p.EatToken()
+		field.All = true
+		return field
+	}
+
+	fieldOrTableName := p.parseMaybeQuotedName()
+
+	token = p.PeekToken()
+	if token.Type == Dot {
+		// first name is the table name
+		// now parse possible star/field name again
+
+		p.EatToken()
+
+		field.TableName = fieldOrTableName
+
+		token = p.PeekToken()
+		if token.Type == Star {
+			p.EatToken()
+			field.All = true
+			// don't return - syntactically valid to do authors.* as foo
+		} else {
+			fieldName := p.parseMaybeQuotedName()
+			field.Name = fieldName
+		}
+
+	} else {
+		field.Name = fieldOrTableName
+	}
+
+	return field
+}
+
+// select fields can be any value expression. plain field names are kept
+// as a Field, so they're checked and written the same way as other fields.
+func (p *QueryParser) parseSelectField() Field {
+	var field Field
+
+	token := p.PeekToken()
+	if token.Type == Star {
+		field = p.parseFieldName()
+	} else {
+		expr := p.parseConcat()
+		if expr.Type == ExpressionTypeLiteral && expr.LiteralType == LiteralTypeFieldName {
+			field = expr.LiteralField
+		} else {
+			field.Expr = &expr
+		}
+	}
+
+	field.Alias = p.parseAliasForColumn()
+	return field
+}
+
+func (p *QueryParser) parseJoin() []Join {
+	joins := []Join{}
+
+	for p.isJoin() || p.isConditionalJoin() {
+		if !p.isConditionalJoin() {
+			joins = append(joins, p.parseJoinClause())
+			continue
+		}
+
+		// {if cond} JOIN ... {end}
+		_ = p.EatToken()
+		_ = p.EatToken()
+
+		p.IsParsingTemplate = true
+		condition := p.parseExpression()
+		p.IsParsingTemplate = false
+		_ = p.EatTokenOfType(RightBrace)
+
+		for p.isJoin() {
+			join := p.parseJoinClause()
+			join.Condition = &condition
+			joins = append(joins, join)
+		}
+
+		_ = p.EatTokenOfType(LeftBrace)
+		_ = p.EatIdentifier("end")
+		_ = p.EatTokenOfType(RightBrace)
+	}
+
+	return joins
+}
+
+func (p *QueryParser) parseJoinClause() Join {
+	parseOuterJoin := func(t2, t3 Token) {
+		_ = p.EatToken()
+		if t2.IsKeyword(KeywordOuter) {
+			_ = p.EatToken()
+
+			t := p.EatToken()
+			if !t.IsKeyword(KeywordJoin) {
+				p.AddError(fmt.Errorf("expected 'join' after 'outer"))
+			}
+		} else if t2.IsKeyword(KeywordJoin) {
+			_ = p.EatToken()
+		} else {
+			p.AddError(fmt.Errorf("expected 'outer' or 'join'"))
+		}
+	}
+
+	// parse join type
+	joinType := JoinTypeInner
+
+	natural := false
+	if p.PeekToken().IsKeyword(KeywordNatural) {
+		_ = p.EatToken()
+		natural = true
+	}
+
+	token := p.PeekToken()
+	token2 := p.PeekTokenAfter(1)
+	token3 := p.PeekTokenAfter(2)
+
+	if token.Type != Identifier {
+		p.AddError(fmt.Errorf("expected identifier to start join"))
+	}
+
+	switch token.LexemeLowered {
+	case KeywordJoin:
+		_ = p.EatToken()
+		joinType = JoinTypeInner
+	case KeywordInner:
+		if !token2.IsKeyword(KeywordJoin) {
+			p.AddError(fmt.Errorf("expected 'join' after 'inner'"))
+		}
+		_ = p.EatToken()
+		_ = p.EatToken()
+		joinType = JoinTypeInner
+	case KeywordCross:
+		if !token2.IsKeyword(KeywordJoin) {
+			p.AddError(fmt.Errorf("expected 'join' after 'cross'"))
+		}
+		_ = p.EatToken()
+		_ = p.EatToken()
+		joinType = JoinTypeCross
+	case KeywordLeft:
+		joinType = JoinTypeLeft
+		parseOuterJoin(token2, token3)
+	case KeywordRight:
+		joinType = JoinTypeRight
+		parseOuterJoin(token2, token3)
+	case KeywordFull:
+		joinType = JoinTypeFull
+		parseOuterJoin(token2, token3)
+	}
+
+	join := Join{
+		JoinType: joinType,
+		Natural:  natural,
+	}
+
+	if natural && joinType == JoinTypeCross {
+		p.AddError(fmt.Errorf("a cross join can't be natural"))
+	}
+
+	if p.PeekToken().IsKeyword(KeywordLateral) {
+		_ = p.EatToken()
+		join.Lateral = true
+		if p.PeekToken().Type != LeftParen {
+			p.AddError(fmt.Errorf("expected a subquery after 'lateral'"))
+		}
+	}
+
+	// parse table or subquery with alias
+	if p.PeekToken().Type == LeftParen {
+		subquery := p.parseSubquery()
+		join.Subquery = &subquery
+	} else {
+		token = p.EatTokenOfType(Identifier)
+		join.Table = token.Lexeme
+	}
+	join.TableAlias = p.parseAliasForTable()
+
+	// cross and natural joins have no condition
+	if joinType != JoinTypeCross && !natural {
+		if p.PeekToken().IsKeyword(KeywordUsing) {
+			_ = p.EatToken()
+			_ = p.EatTokenOfType(LeftParen)
+			for {
+				join.Using = append(join.Using, p.EatTokenOfType(Identifier).Lexeme)
+				if p.PeekToken().Type != Comma {
+					break
+				}
+				_ = p.EatToken()
+			}
+			_ = p.EatTokenOfType(RightParen)
+		} else {
+			p.EatIdentifier(KeywordOn)
+			join.On = p.parseExpression()
+		}
+	}
+
+	return join
+}
+
+func (p *QueryParser) isJoin() bool {
+	return p.isJoinAfter(0)
+}
+
+// whether a join starts `i` tokens after the current token
+func (p *QueryParser) isJoinAfter(i int) bool {
+	token := p.PeekTokenAfter(i)
+	token2 := p.PeekTokenAfter(i + 1)
+	token3 := p.PeekTokenAfter(i + 2)
+
+	// simplified check, will actually try to properly parse if join seems to be here:
+	if token.IsKeyword(KeywordJoin, KeywordNatural) || token2.IsKeyword(KeywordJoin) || token3.IsKeyword(KeywordJoin) {
+		return true
+	}
+
+	// possibly do this more precise check
+	// if token.IsKeyword(KeywordInner, KeywordLeft, KeywordRight, KeywordCross) &&
+	// 	(token2.IsKeyword(KeywordJoin) || (token2.IsKeyword(KeywordOuter) && token3.IsKeyword(KeywordJoin))) {
+	// 	return true
+	// }
+
+	return false
+}
+
+// whether the next tokens are `{if ...}` followed by a join. the condition is
+// skipped by looking ahead, so it has to fit in the scanner's buffer.
+func (p *QueryParser) isConditionalJoin() bool {
+	if p.PeekToken().Type != LeftBrace || !p.PeekTokenAfter(1).IsKeyword("if") {
+		return false
+	}
+	for i := 2; i+3 < RingBufferSize; i++ {
+		token := p.PeekTokenAfter(i)
+		if token.Type == EOF {
+			return false
+		}
+		if token.Type == RightBrace {
+			return p.isJoinAfter(i + 1)
+		}
+	}
+	return false
+}
+
+// WITH has already been consumed
+func (p *QueryParser) parseWith() ([]CommonTableExpr, bool) {
+	isRecursive := false
+	if p.PeekToken().IsKeyword(KeywordRecursive) {
+		_ = p.EatToken()
+		isRecursive = true
+	}
+
+	var ctes []CommonTableExpr
+	for {
+		var cte CommonTableExpr
+		cte.Name = p.EatTokenOfType(Identifier).Lexeme
+
+		if p.PeekToken().Type == LeftParen {
+			_ = p.EatToken()
+			for {
+				cte.Columns = append(cte.Columns, p.EatTokenOfType(Identifier).Lexeme)
+				if p.PeekToken().Type != Comma {
+					break
+				}
+				_ = p.EatToken()
+			}
+			_ = p.EatTokenOfType(RightParen)
+		}
+
+		_ = p.EatIdentifier(KeywordAs)
+		_ = p.EatTokenOfType(LeftParen)
+		_ = p.EatIdentifier(KeywordSelect)
+		cte.Select = p.parseSelect()
+		if cte.Select.Paginate != nil {
+			p.AddError(fmt.Errorf("paginate can only be used in the main select"))
+		}
+		_ = p.EatTokenOfType(RightParen)
+
+		ctes = append(ctes, cte)
+
+		if p.PeekToken().Type != Comma {
+			break
+		}
+		_ = p.EatToken()
+	}
+
+	return ctes, isRecursive
+}
+
+// next token is UNION, INTERSECT or EXCEPT
+func (p *QueryParser) parseSetOp() SetOp {
+	var op SetOp
+
+	token := p.EatToken()
+	switch token.LexemeLowered {
+	case KeywordUnion:
+		op.Type = SetOpTypeUnion
+	case KeywordIntersect:
+		op.Type = SetOpTypeIntersect
+	case KeywordExcept:
+		op.Type = SetOpTypeExcept
+	}
+
+	if p.PeekToken().IsKeyword(KeywordAll) {
+		_ = p.EatToken()
+		op.All = true
+	}
+
+	_ = p.EatIdentifier(KeywordSelect)
+	p.parseSelectCore(&op.Select)
+
+	return op
+}
+
+// SELECT has already been consumed
+func (p *QueryParser) parseSelect() SelectStmt {
+	var stmt SelectStmt
+	p.parseSelectCore(&stmt)
+
+	for {
+		if p.PeekToken().IsKeyword(KeywordUnion, KeywordIntersect, KeywordExcept) {
+			stmt.SetOps = append(stmt.SetOps, p.parseSetOp())
+			continue
+		}
+
+		// {if cond} UNION SELECT ... {end}
+		if p.PeekToken().Type == LeftBrace && p.PeekTokenAfter(1).IsKeyword("if") {
+			_ = p.EatToken()
+			_ = p.EatToken()
+
+			p.IsParsingTemplate = true
+			condition := p.parseExpression()
+			p.IsParsingTemplate = false
+			_ = p.EatTokenOfType(RightBrace)
+
+			for p.PeekToken().IsKeyword(KeywordUnion, KeywordIntersect, KeywordExcept) {
+				op := p.parseSetOp()
+				op.Condition = &condition
+				stmt.SetOps = append(stmt.SetOps, op)
+			}
+
+			_ = p.EatTokenOfType(LeftBrace)
+			_ = p.EatIdentifier("end")
+			_ = p.EatTokenOfType(RightBrace)
+			continue
+		}
+
+		break
+	}
+
+	token := p.PeekToken()
+	if token.Type == LeftBrace && p.PeekTokenAfter(1).IsKeyword("paginate") {
+		paginate := p.parsePaginate()
+		stmt.Paginate = &paginate
+	}
+
+	token = p.PeekToken()
+	if token.IsKeyword(KeywordOrder) {
+		stmt.OrderBy = p.parseOrderBy()
+	}
+
+	// postgres accepts limit and offset in either order
+	token = p.PeekToken()
+	for token.IsKeyword(KeywordLimit, KeywordOffset) {
+		expr := p.parseLimitOrOffset()
+		if token.IsKeyword(KeywordLimit) {
+			stmt.Limit = &expr
+		} else {
+			stmt.Offset = &expr
 		}
-
-		aliasName := token.Lexeme
-		return aliasName
+		token = p.PeekToken()
 	}
 
-	// with or without "as", only non-reserved identifiers allowed here
 	token = p.PeekToken()
-	if token.Type == Identifier && !IsReservedKeyword(token.LexemeLowered) {
-		p.EatToken()
-		aliasName := token.Lexeme
-		return aliasName
+	for token.IsKeyword(KeywordFor) {
+		stmt.Locking = append(stmt.Locking, p.parseLockingClause())
+		token = p.PeekToken()
 	}
 
-	return ""
+	if stmt.Paginate != nil {
+		if len(stmt.OrderBy) > 0 || stmt.Limit != nil {
+			p.AddError(fmt.Errorf("paginate can't be used with ORDER BY or LIMIT, since it adds its own"))
+		}
+		if len(stmt.SetOps) > 0 {
+			p.AddError(fmt.Errorf("paginate can't be used with UNION, INTERSECT or EXCEPT"))
+		}
+		stmt.expandPaginate()
+	}
+
+	return stmt
 }
 
-func (p *QueryParser) parseFieldName() Field {
-	token := p.PeekToken()
-	field := Field{}
+// next token is `for`
+func (p *QueryParser) parseLockingClause() LockingClause {
+	var clause LockingClause
 
-	if token.Type == Star {
-		_ = p.EatToken()
-		field.All = true
-		return field
-	}
+	_ = p.EatIdentifier(KeywordFor)
 
-	fieldOrTableName := p.parseMaybeQuotedName()
+	token := p.EatTokenOfType(Identifier)
+	switch token.LexemeLowered {
+	case KeywordUpdate:
+		clause.Strength = LockStrengthUpdate
+	case KeywordShare:
+		clause.Strength = LockStrengthShare
+	case KeywordNo:
+		_ = p.EatIdentifier(KeywordKey)
+		_ = p.EatIdentifier(KeywordUpdate)
+		clause.Strength = LockStrengthNoKeyUpdate
+	case KeywordKey:
+		_ = p.EatIdentifier(KeywordShare)
+		clause.Strength = LockStrengthKeyShare
+	default:
+		p.AddError(fmt.Errorf("expected UPDATE, NO KEY UPDATE, SHARE or KEY SHARE after FOR"))
+	}
 
-	token = p.PeekToken()
-	if token.Type == Dot {
-		// first name is the table name
-		// now parse possible star/field name again
+	if p.PeekToken().IsKeyword(KeywordOf) {
+		_ = p.EatToken()
+		for {
+			clause.Of = append(clause.Of, p.EatTokenOfType(Identifier).Lexeme)
+			if p.PeekToken().Type != Comma {
+				break
+			}
+			_ = p.EatToken()
+		}
+	}
 
-		p.EatToken()
+	// {if skipLocked} SKIP LOCKED {end}
+	if p.PeekToken().Type == LeftBrace && p.PeekTokenAfter(1).IsKeyword("if") {
+		_ = p.EatToken()
+		_ = p.EatToken()
 
-		field.TableName = fieldOrTableName
+		p.IsParsingTemplate = true
+		condition := p.parseExpression()
+		p.IsParsingTemplate = false
+		_ = p.EatTokenOfType(RightBrace)
 
-		token = p.PeekToken()
-		if token.Type == Star {
-			p.EatToken()
-			field.All = true
-			// don't return - syntactically valid to do authors.* as foo
-		} else {
-			fieldName := p.parseMaybeQuotedName()
-			field.Name = fieldName
+		clause.WaitCondition = &condition
+		clause.Wait = p.parseLockWait()
+		if clause.Wait == LockWaitNone {
+			p.AddError(fmt.Errorf("expected SKIP LOCKED or NOWAIT in {if}"))
 		}
 
+		_ = p.EatTokenOfType(LeftBrace)
+		_ = p.EatIdentifier("end")
+		_ = p.EatTokenOfType(RightBrace)
 	} else {
-		field.Name = fieldOrTableName
+		clause.Wait = p.parseLockWait()
 	}
 
-	return field
+	return clause
 }
 
-func (p *QueryParser) parseFieldNameWithAlias() Field {
-	field := p.parseFieldName()
-	aliasName := p.parseAliasForColumn()
-	field.Alias = aliasName
-	return field
+func (p *QueryParser) parseLockWait() LockWait {
+	token := p.PeekToken()
+	if token.IsKeyword(KeywordNowait) {
+		_ = p.EatToken()
+		return LockWaitNowait
+	}
+	if token.IsKeyword(KeywordSkip) {
+		_ = p.EatToken()
+		_ = p.EatIdentifier(KeywordLocked)
+		return LockWaitSkipLocked
+	}
+	return LockWaitNone
 }
 
-func (p *QueryParser) parseJoin() []Join {
+// next token is `into`
+func (p *QueryParser) parseInsert() InsertStmt {
+	var stmt InsertStmt
 
-	joins := []Join{}
+	_ = p.EatIdentifier(KeywordInto)
+	stmt.Table = p.EatTokenOfType(Identifier).Lexeme
 
-	parseOuterJoin := func(t2, t3 Token) {
-		_ = p.EatToken()
-		if t2.IsKeyword(KeywordOuter) {
-			_ = p.EatToken()
+	if p.PeekToken().Type == LeftParen {
+		stmt.Columns = p.parseColumnList()
+	}
 
-			t := p.EatToken()
-			if !t.IsKeyword(KeywordJoin) {
-				p.AddError(fmt.Errorf("expected 'join' after 'outer"))
+	_ = p.EatIdentifier(KeywordValues)
+
+	// {foreach row in rows: ,} (...) {end}
+	if p.PeekToken().Type == LeftBrace && p.PeekTokenAfter(1).IsKeyword("foreach") {
+		var loop ValuesLoop
+		var separator Token
+		loop.IteratorName, loop.ListName, separator = p.parseForEachHeader()
+		if separator.Type != Comma {
+			p.AddError(fmt.Errorf("expected rows in {foreach} to be joined with a comma"))
+		}
+		stmt.ValuesLoop = &loop
+
+		stmt.Values = append(stmt.Values, p.parseValuesRow())
+
+		_ = p.EatTokenOfType(LeftBrace)
+		_ = p.EatIdentifier("end")
+		_ = p.EatTokenOfType(RightBrace)
+	} else {
+		for {
+			stmt.Values = append(stmt.Values, p.parseValuesRow())
+
+			if p.PeekToken().Type != Comma {
+				break
 			}
-		} else if t2.IsKeyword(KeywordJoin) {
 			_ = p.EatToken()
-		} else {
-			p.AddError(fmt.Errorf("expected 'outer' or 'join'"))
 		}
 	}
 
-	for p.isJoin() {
+	if p.PeekToken().IsKeyword(KeywordOn) {
+		onConflict := p.parseOnConflict()
+		stmt.OnConflict = &onConflict
+	}
+
+	if p.PeekToken().IsKeyword(KeywordReturning) {
+		_ = p.EatToken()
+		for {
+			stmt.Returning = append(stmt.Returning, p.parseSelectField())
+			if p.PeekToken().Type != Comma {
+				break
+			}
+			_ = p.EatToken()
+		}
+	}
 
-		// parse join type
-		joinType := JoinTypeInner
+	return stmt
+}
 
-		token := p.PeekToken()
-		token2 := p.PeekTokenAfter(1)
-		token3 := p.PeekTokenAfter(2)
+// eg ({authorID}, 'Dune')
+func (p *QueryParser) parseValuesRow() []Expression {
+	var row []Expression
 
-		if token.Type != Identifier {
-			p.AddError(fmt.Errorf("expected identifier to start join"))
+	_ = p.EatTokenOfType(LeftParen)
+	for {
+		row = append(row, p.parseConcat())
+		if p.PeekToken().Type != Comma {
+			break
 		}
+		_ = p.EatToken()
+	}
+	_ = p.EatTokenOfType(RightParen)
 
-		switch token.LexemeLowered {
-		case KeywordJoin:
-			_ = p.EatToken()
-			joinType = JoinTypeInner
-		case KeywordInner:
-			if !token2.IsKeyword(KeywordJoin) {
-				p.AddError(fmt.Errorf("expected 'join' after 'inner'"))
-			}
-			_ = p.EatToken()
-			_ = p.EatToken()
-			joinType = JoinTypeInner
-		case KeywordCross:
-			_ = p.EatToken()
-			joinType = JoinTypeCross
-		case KeywordLeft:
-			joinType = JoinTypeLeft
-			parseOuterJoin(token2, token3)
-		case KeywordRight:
-			joinType = JoinTypeLeft
-			parseOuterJoin(token2, token3)
-		case KeywordFull:
-			joinType = JoinTypeFull
-			parseOuterJoin(token2, token3)
-		}
-
-		// parse table with alias
-		token = p.EatTokenOfType(Identifier)
-		table := token.Lexeme
-		alias := p.parseAliasForTable()
+	return row
+}
 
-		// parse ON expression.
-		// not required with cross, natural, or using - unsupported for now
+// eg (id, title)
+func (p *QueryParser) parseColumnList() []string {
+	var columns []string
 
-		p.EatIdentifier(KeywordOn)
-		expr := p.parseExpression()
+	_ = p.EatTokenOfType(LeftParen)
+	for {
+		columns = append(columns, p.parseMaybeQuotedName())
+		if p.PeekToken().Type != Comma {
+			break
+		}
+		_ = p.EatToken()
+	}
+	_ = p.EatTokenOfType(RightParen)
 
-		joins = append(joins, Join{
-			Table:      table,
-			TableAlias: alias,
-			JoinType:   joinType,
-			On:         expr,
-		})
+	return columns
+}
+
+// next token is `on`
+func (p *QueryParser) parseOnConflict() OnConflict {
+	var onConflict OnConflict
 
+	_ = p.EatIdentifier(KeywordOn)
+	_ = p.EatIdentifier(KeywordConflict)
+
+	if p.PeekToken().Type == LeftParen {
+		onConflict.Target = p.parseColumnList()
 	}
 
-	return joins
-}
+	_ = p.EatIdentifier(KeywordDo)
+	token := p.EatTokenOfType(Identifier)
+	switch token.LexemeLowered {
+	case KeywordNothing:
+		return onConflict
+	case KeywordUpdate:
+		onConflict.DoUpdate = true
+	default:
+		p.AddError(fmt.Errorf("expected NOTHING or UPDATE after ON CONFLICT DO"))
+	}
 
-func (p *QueryParser) isJoin() bool {
-	token := p.PeekToken()
-	token2 := p.PeekTokenAfter(1)
-	token3 := p.PeekTokenAfter(2)
+	_ = p.EatIdentifier(KeywordSet)
+	for {
+		var set SetClause
+		set.Column = p.parseMaybeQuotedName()
+		_ = p.EatTokenOfType(Equal)
+		set.Value = p.parseConcat()
+		onConflict.Set = append(onConflict.Set, set)
 
-	// simplified check, will actually try to properly parse if join seems to be here:
-	if token.IsKeyword(KeywordJoin) || token2.IsKeyword(KeywordJoin) || token3.IsKeyword(KeywordJoin) {
-		return true
+		if p.PeekToken().Type != Comma {
+			break
+		}
+		_ = p.EatToken()
 	}
 
-	// possibly do this more precise check
-	// if token.IsKeyword(KeywordInner, KeywordLeft, KeywordRight, KeywordCross) &&
-	// 	(token2.IsKeyword(KeywordJoin) || (token2.IsKeyword(KeywordOuter) && token3.IsKeyword(KeywordJoin))) {
-	// 	return true
-	// }
+	if p.PeekToken().IsKeyword(KeywordWhere) {
+		_ = p.EatToken()
+		onConflict.Where = p.parseExpression()
+	}
 
-	return false
+	return onConflict
 }
 
-func (p *QueryParser) parseSelect() SelectStmt {
-	var stmt SelectStmt
-
+// parses a single select, from the select list through HAVING
+func (p *QueryParser) parseSelectCore(stmt *SelectStmt) {
 	token := p.PeekToken()
+	if token.IsKeyword(KeywordDistinct) {
+		token = p.EatToken()
+		stmt.Distinct = true
+
+		if p.PeekToken().IsKeyword(KeywordOn) {
+			_ = p.EatToken()
+			stmt.DistinctOnLine = token.Line
+
+			_ = p.EatTokenOfType(LeftParen)
+			for {
+				stmt.DistinctOn = append(stmt.DistinctOn, p.parseConcat())
+				if p.PeekToken().Type != Comma {
+					break
+				}
+				_ = p.EatToken()
+			}
+			_ = p.EatTokenOfType(RightParen)
+		}
+
+		token = p.PeekToken()
+	}
 
 	// parse select fields
 	for !(token.Type == Identifier && token.LexemeLowered == KeywordFrom) {
-		field := p.parseFieldNameWithAlias()
+		field := p.parseSelectField()
 		stmt.Fields = append(stmt.Fields, field)
 
 		token = p.PeekToken()
@@ -1064,9 +2869,14 @@ func (p *QueryParser) parseSelect() SelectStmt {
 
 	// parse from
 	{
-		// table name
-		token = p.EatTokenOfType(Identifier)
-		stmt.From = token.Lexeme
+		// table name, or a subquery that the checker requires an alias for
+		if p.PeekToken().Type == LeftParen {
+			subquery := p.parseSubquery()
+			stmt.FromSubquery = &subquery
+		} else {
+			token = p.EatTokenOfType(Identifier)
+			stmt.From = token.Lexeme
+		}
 		stmt.FromAlias = p.parseAliasForTable()
 	}
 
@@ -1087,18 +2897,82 @@ func (p *QueryParser) parseSelect() SelectStmt {
 	}
 
 	token = p.PeekToken()
+	if token.IsKeyword(KeywordGroup) {
+		stmt.GroupBy = p.parseGroupBy()
+	}
 
-	if token.Type == Identifier {
-		if token.LexemeLowered == KeywordLimit {
-			n := p.parseLimit()
-			stmt.Limit = &n
-		} else if token.LexemeLowered == KeywordOrder {
-			sortFields := p.parseOrderBy()
-			stmt.OrderByFields = sortFields
+	token = p.PeekToken()
+	if token.IsKeyword(KeywordHaving) {
+		_ = p.EatToken()
+		stmt.Having = p.parseExpression()
+	}
+
+	token = p.PeekToken()
+	if token.IsKeyword(KeywordWindow) {
+		_ = p.EatToken()
+
+		for {
+			window := NamedWindow{Name: p.EatTokenOfType(Identifier).Lexeme}
+			_ = p.EatIdentifier(KeywordAs)
+			window.Spec = p.parseWindowSpec()
+			stmt.Windows = append(stmt.Windows, window)
+
+			if p.PeekToken().Type != Comma {
+				break
+			}
+			_ = p.EatToken()
 		}
 	}
+}
 
-	return stmt
+// eg id: string?, ids: [int] or filter: AuthorFilter!
+func (p *QueryParser) parseParam() Param {
+	param := Param{}
+	param.Name = p.EatTokenOfType(Identifier).Lexeme
+
+	_ = p.EatTokenOfType(Colon)
+
+	var token Token
+	if p.PeekToken().Type == LeftBracket {
+		param.IsList = true
+
+		_ = p.EatToken()
+
+		// only write to `token` for the identifier since it's checked after
+		// consuming the right bracket
+		token = p.EatTokenOfType(Identifier)
+
+		_ = p.EatTokenOfType(RightBracket)
+	} else {
+		token = p.EatTokenOfType(Identifier)
+	}
+
+	switch token.Lexeme {
+	case "string":
+		param.Type = ParamTypeString
+	case "int":
+		param.Type = ParamTypeNumber
+	case "bool":
+		param.Type = ParamTypeBool
+	default:
+		// may be a mapped or declared type - checker will validate
+		param.Type = ParamTypeCustom
+		param.TypeName = token.Lexeme
+	}
+
+	token = p.PeekToken()
+
+	if token.Type == Bang {
+		_ = p.EatToken()
+		param.Required = true
+	} else if token.Type == QuestionMark {
+		_ = p.EatToken()
+		param.Required = false
+	} else {
+		param.Required = true
+	}
+
+	return param
 }
 
 func (p *QueryParser) parseQuery(isFragment bool) {
@@ -1121,53 +2995,12 @@ func (p *QueryParser) parseQuery(isFragment bool) {
 
 		// (id: string?, foo: number, bar: number!)
 		for token.Type != RightParen {
-			param := Param{}
+			param := p.parseParam()
 			param.IsQueryScoped = true
-			token = p.EatTokenOfType(Identifier)
-			param.Name = token.Lexeme
 			if !isFragment {
 				param.GlobalName = "input." + param.Name
 			}
 
-			token = p.EatTokenOfType(Colon)
-
-			token = p.PeekToken()
-
-			if token.Type == LeftBracket {
-				param.IsList = true
-
-				_ = p.EatToken()
-
-				// only write to `token` for the identifier since it's checked after
-				// consuming the right bracket
-				token = p.EatTokenOfType(Identifier)
-
-				_ = p.EatTokenOfType(RightBracket)
-			} else {
-				token = p.EatTokenOfType(Identifier)
-			}
-
-			switch token.Lexeme {
-			case "string":
-				param.Type = ParamTypeString
-			case "int":
-				param.Type = ParamTypeNumber
-			default:
-				p.AddError(fmt.Errorf("unrecognized type: %s", token.Lexeme))
-			}
-
-			token = p.PeekToken()
-
-			if token.Type == Bang {
-				token = p.EatToken()
-				param.Required = true
-			} else if token.Type == QuestionMark {
-				token = p.EatToken()
-				param.Required = false
-			} else {
-				param.Required = true
-			}
-
 			params = append(params, param)
 
 			token = p.PeekToken()
@@ -1193,11 +3026,50 @@ func (p *QueryParser) parseQuery(isFragment bool) {
 	} else {
 		token = p.EatTokenOfType(Identifier)
 
+		var with []CommonTableExpr
+		withRecursive := false
+		if token.LexemeLowered == KeywordWith {
+			with, withRecursive = p.parseWith()
+			token = p.EatTokenOfType(Identifier)
+		}
+
 		if token.LexemeLowered == KeywordSelect {
 			selectStmt := p.parseSelect()
+			selectStmt.With = with
+			selectStmt.WithRecursive = withRecursive
 
 			query.StatementType = StatementTypeSelect
 			query.Select = selectStmt
+			for _, cte := range with {
+				query.Params = append(query.Params, orderByParams(query.Name, cte.Select.OrderBy)...)
+			}
+			query.Params = append(query.Params, orderByParams(query.Name, selectStmt.OrderBy)...)
+			if selectStmt.Paginate != nil {
+				query.Params = append(query.Params, Param{
+					Name:          selectStmt.Paginate.CursorParamName,
+					Type:          ParamTypeCursor,
+					GoType:        GoType{Name: query.Name + "Cursor"},
+					IsQueryScoped: true,
+					GlobalName:    "input." + selectStmt.Paginate.CursorParamName,
+				})
+			}
+
+		} else if token.LexemeLowered == KeywordInsert {
+			if len(with) > 0 {
+				p.AddError(fmt.Errorf("WITH is only supported before SELECT"))
+			}
+
+			query.StatementType = StatementTypeInsert
+			query.Insert = p.parseInsert()
+
+			// the rows' type is generated from the columns they're inserted into
+			if loop := query.Insert.ValuesLoop; loop != nil {
+				for i := range query.Params {
+					if query.Params[i].Name == loop.ListName && query.Params[i].Type == ParamTypeCustom {
+						query.Params[i].Type = ParamTypeStruct
+					}
+				}
+			}
 
 		} else {
 			panic("not supported")
@@ -1214,6 +3086,49 @@ func (p *QueryParser) parseQuery(isFragment bool) {
 	p.Result.Queries = append(p.Result.Queries, query)
 }
 
+// next token is the name of the type.
+// eg type AuthorFilter { name: string?, minID: int? }
+func (p *QueryParser) parseTypeDef() {
+	var typeDef TypeDef
+	typeDef.Name = p.EatTokenOfType(Identifier).Lexeme
+
+	_ = p.EatTokenOfType(LeftBrace)
+
+	for p.PeekToken().Type != RightBrace {
+		typeDef.Fields = append(typeDef.Fields, p.parseParam())
+
+		if p.PeekToken().Type == Comma {
+			_ = p.EatToken()
+		}
+	}
+
+	_ = p.EatTokenOfType(RightBrace)
+
+	p.Result.Types = append(p.Result.Types, typeDef)
+}
+
+// next token is the name of the copy.
+// rows are loaded with COPY FROM, so there's no query body
+func (p *QueryParser) parseCopy() {
+	var query Query
+	query.StatementType = StatementTypeCopy
+
+	query.Name = p.EatTokenOfType(Identifier).Lexeme
+
+	_ = p.EatIdentifier(KeywordInto)
+	query.Copy.Table = p.EatTokenOfType(Identifier).Lexeme
+
+	if p.PeekToken().Type == LeftParen {
+		query.Copy.Columns = p.parseColumnList()
+	}
+
+	if p.Scanner.HasNextToken() && p.PeekToken().Type == Semicolon {
+		_ = p.EatToken()
+	}
+
+	p.Result.Queries = append(p.Result.Queries, query)
+}
+
 func (p *QueryParser) Parse() {
 
 	for p.Scanner.HasNextToken() {
@@ -1232,6 +3147,14 @@ func (p *QueryParser) Parse() {
 		}
 
 		queryType := token.Lexeme
+		if queryType == "copy" {
+			p.parseCopy()
+			continue
+		}
+		if queryType == "type" {
+			p.parseTypeDef()
+			continue
+		}
 		if queryType != "query" && queryType != "fragment" {
 			panic("")
 		}
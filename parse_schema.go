@@ -11,7 +11,20 @@ type TableFieldType int
 const (
 	TableFieldTypeNone TableFieldType = iota
 	TableFieldTypeBigSerial
+	TableFieldTypeSerial
+	TableFieldTypeBigInt
+	TableFieldTypeInteger
+	TableFieldTypeSmallInt
+	TableFieldTypeReal
+	TableFieldTypeDouble
+	TableFieldTypeNumeric
+	TableFieldTypeBoolean
 	TableFieldTypeText
+	TableFieldTypeUUID
+	TableFieldTypeTimestamp
+	TableFieldTypeDate
+	TableFieldTypeJSON
+	TableFieldTypeBytea
 )
 
 func (t TableFieldType) String() string {
@@ -20,16 +33,72 @@ func (t TableFieldType) String() string {
 		return "(unknown)"
 	case TableFieldTypeBigSerial:
 		return "BIG SERIAL"
+	case TableFieldTypeSerial:
+		return "SERIAL"
+	case TableFieldTypeBigInt:
+		return "BIGINT"
+	case TableFieldTypeInteger:
+		return "INTEGER"
+	case TableFieldTypeSmallInt:
+		return "SMALLINT"
+	case TableFieldTypeReal:
+		return "REAL"
+	case TableFieldTypeDouble:
+		return "DOUBLE PRECISION"
+	case TableFieldTypeNumeric:
+		return "NUMERIC"
+	case TableFieldTypeBoolean:
+		return "BOOLEAN"
 	case TableFieldTypeText:
 		return "TEXT"
+	case TableFieldTypeUUID:
+		return "UUID"
+	case TableFieldTypeTimestamp:
+		return "TIMESTAMP"
+	case TableFieldTypeDate:
+		return "DATE"
+	case TableFieldTypeJSON:
+		return "JSON"
+	case TableFieldTypeBytea:
+		return "BYTEA"
 	}
 	panic(fmt.Sprintf("table field type %d not handled", t))
 }
 
+// returns the go type used for result columns when no type mapping is configured
+func (t TableFieldType) GoType() GoType {
+	switch t {
+	case TableFieldTypeBigSerial, TableFieldTypeBigInt:
+		return GoType{Name: "int64"}
+	case TableFieldTypeSerial, TableFieldTypeInteger:
+		return GoType{Name: "int32"}
+	case TableFieldTypeSmallInt:
+		return GoType{Name: "int16"}
+	case TableFieldTypeReal:
+		return GoType{Name: "float32"}
+	case TableFieldTypeDouble:
+		return GoType{Name: "float64"}
+	case TableFieldTypeNumeric, TableFieldTypeText, TableFieldTypeUUID:
+		return GoType{Name: "string"}
+	case TableFieldTypeBoolean:
+		return GoType{Name: "bool"}
+	case TableFieldTypeTimestamp, TableFieldTypeDate:
+		return GoType{ImportPath: "time", Name: "Time"}
+	case TableFieldTypeJSON, TableFieldTypeBytea:
+		return GoType{Name: "[]byte"}
+	default:
+		return GoType{Name: "interface{}"}
+	}
+}
+
 type TableField struct {
-	Name       string
-	Type       TableFieldType
+	Name string
+	Type TableFieldType
+	// the type as written in the schema, lowercased. used to look up type mappings,
+	// since multiple names can map to the same TableFieldType
+	TypeName   string
 	PrimaryKey bool
+	Unique     bool
 	NotNull    bool
 }
 
@@ -37,6 +106,9 @@ type Table struct {
 	Schema string // optional
 	Name   string
 	Fields []TableField
+
+	// the columns of the primary key and each unique constraint
+	Keys [][]string
 }
 
 type Schema struct {
@@ -105,13 +177,44 @@ func (p *SchemaParser) EatTokenOfType(tokenType TokenType) Token {
 }
 
 func (p *SchemaParser) TableFieldTypeFromString(tableType string) TableFieldType {
+	return TableFieldTypeFromName(tableType)
+}
+
+// also used by the checker for types named in queries
+func TableFieldTypeFromName(tableType string) TableFieldType {
 	s := strings.ToLower(tableType)
 
 	switch s {
-	case "bigserial":
+	case "bigserial", "serial8":
 		return TableFieldTypeBigSerial
-	case "text":
+	case "serial", "serial4":
+		return TableFieldTypeSerial
+	case "bigint", "int8":
+		return TableFieldTypeBigInt
+	case "integer", "int", "int4":
+		return TableFieldTypeInteger
+	case "smallint", "int2":
+		return TableFieldTypeSmallInt
+	case "real", "float4":
+		return TableFieldTypeReal
+	case "double", "double precision", "float8":
+		return TableFieldTypeDouble
+	case "numeric", "decimal":
+		return TableFieldTypeNumeric
+	case "boolean", "bool":
+		return TableFieldTypeBoolean
+	case "text", "varchar", "char", "character", "character varying":
 		return TableFieldTypeText
+	case "uuid":
+		return TableFieldTypeUUID
+	case "timestamp", "timestamptz", "timestamp with time zone", "timestamp without time zone":
+		return TableFieldTypeTimestamp
+	case "date":
+		return TableFieldTypeDate
+	case "json", "jsonb":
+		return TableFieldTypeJSON
+	case "bytea":
+		return TableFieldTypeBytea
 	default:
 		// allow parsing schemas even if we don't recognize all types
 		return TableFieldTypeNone
@@ -144,6 +247,7 @@ func (p *SchemaParser) parseTableField() TableField {
 	token := p.EatTokenOfType(Identifier)
 
 	field.Type = p.TableFieldTypeFromString(token.Lexeme)
+	field.TypeName = token.LexemeLowered
 
 	// parse options
 
@@ -174,6 +278,8 @@ func (p *SchemaParser) parseTableField() TableField {
 			} else {
 				// not supported
 			}
+		case KeywordUnique:
+			field.Unique = true
 		case KeywordNull:
 			field.NotNull = false
 		case KeywordNot:
@@ -199,6 +305,70 @@ func (p *SchemaParser) parseTableField() TableField {
 
 }
 
+func (p *SchemaParser) isTableConstraint() bool {
+	token := p.PeekToken()
+	if token.Type != Identifier {
+		return false
+	}
+
+	switch token.LexemeLowered {
+	case KeywordConstraint, KeywordPrimary, KeywordUnique, KeywordCheck, KeywordForeign, KeywordExclude:
+		return true
+	}
+	return false
+}
+
+// eg:
+// CONSTRAINT books_title_key UNIQUE (author_id, title)
+//
+// returns the columns of a primary key or unique constraint. other
+// constraints are skipped and return nil.
+func (p *SchemaParser) parseTableConstraint() ([]string, bool) {
+	token := p.PeekToken()
+	if token.LexemeLowered == KeywordConstraint {
+		_ = p.EatToken()
+		_ = p.parseMaybeQuotedName()
+	}
+
+	var columns []string
+	token = p.EatTokenOfType(Identifier)
+	isPrimaryKey := token.LexemeLowered == KeywordPrimary
+	if isPrimaryKey || token.LexemeLowered == KeywordUnique {
+		if isPrimaryKey {
+			_ = p.EatTokenOfType(Identifier)
+		}
+
+		_ = p.EatTokenOfType(LeftParen)
+		for {
+			columns = append(columns, p.parseMaybeQuotedName())
+			if p.PeekToken().Type != Comma {
+				break
+			}
+			_ = p.EatToken()
+		}
+		_ = p.EatTokenOfType(RightParen)
+	}
+
+	// skip the rest, eg a CHECK expression or index options
+	unclosedParenCount := 0
+	token = p.PeekToken()
+	for unclosedParenCount > 0 || (token.Type != Comma && token.Type != RightParen) {
+		token = p.EatToken()
+		if token.Type == LeftParen {
+			unclosedParenCount++
+		} else if token.Type == RightParen {
+			unclosedParenCount--
+		}
+		token = p.PeekToken()
+	}
+
+	if token.Type == Comma {
+		_ = p.EatToken()
+	}
+
+	return columns, isPrimaryKey
+}
+
 func (p *SchemaParser) parseTableSchemaAndName() (string, string) {
 	var schemaName, tableName string
 
@@ -227,12 +397,37 @@ func (p *SchemaParser) parseTable() {
 
 	token = p.PeekToken()
 
+	var primaryKey []string
 	for token.Type != RightParen {
+		if p.isTableConstraint() {
+			columns, isPrimaryKey := p.parseTableConstraint()
+			if columns != nil {
+				table.Keys = append(table.Keys, columns)
+			}
+			if isPrimaryKey {
+				primaryKey = columns
+			}
+			token = p.PeekToken()
+			continue
+		}
+
 		field := p.parseTableField()
 		table.Fields = append(table.Fields, field)
+		if field.PrimaryKey || field.Unique {
+			table.Keys = append(table.Keys, []string{field.Name})
+		}
 		token = p.PeekToken()
 	}
 
+	// PRIMARY KEY (a, b) makes each column NOT NULL, same as on the column
+	for i := range table.Fields {
+		for _, name := range primaryKey {
+			if table.Fields[i].Name == name {
+				table.Fields[i].PrimaryKey = true
+			}
+		}
+	}
+
 	_ = p.EatTokenOfType(RightParen)
 	_ = p.EatTokenOfType(Semicolon)
 
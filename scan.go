@@ -24,6 +24,7 @@ const (
 	Slash
 	Star
 	Colon
+	Percent
 
 	// one or two character tokens
 	QuestionMark
@@ -35,6 +36,8 @@ const (
 	GreaterEqual
 	Less
 	LessEqual
+	PipePipe   // ||
+	ColonColon // ::
 
 	Identifier
 	String
@@ -73,6 +76,8 @@ func (t TokenType) String() string {
 		return "Star"
 	case Colon:
 		return "Colon"
+	case Percent:
+		return "Percent"
 	case QuestionMark:
 		return "QuestionMark"
 	case Bang:
@@ -91,6 +96,10 @@ func (t TokenType) String() string {
 		return "Less"
 	case LessEqual:
 		return "LessEqual"
+	case PipePipe:
+		return "PipePipe"
+	case ColonColon:
+		return "ColonColon"
 	case Identifier:
 		return "Identifier"
 	case String:
@@ -110,40 +119,106 @@ func (t TokenType) String() string {
 type Keyword = string
 
 const (
-	KeywordCreate Keyword = "create"
-	KeywordTable  Keyword = "table"
-
-	KeywordSelect Keyword = "select"
-	KeywordFrom   Keyword = "from"
-	KeywordWhere  Keyword = "where"
-	KeywordIn     Keyword = "in"
-	KeywordLimit  Keyword = "limit"
-	KeywordOrder  Keyword = "order"
-	KeywordBy     Keyword = "by"
-
-	KeywordAnd     Keyword = "and"
-	KeywordOr      Keyword = "or"
-	KeywordFor     Keyword = "for"
-	KeywordIf      Keyword = "if"
-	KeywordElse    Keyword = "else"
-	KeywordNull    Keyword = "null"
-	KeywordTrue    Keyword = "true"
-	KeywordFalse   Keyword = "false"
-	KeywordNot     Keyword = "not"
-	KeywordLike    Keyword = "like"
-	KeywordIs      Keyword = "is"
-	KeywordPrimary Keyword = "primary"
-	KeywordKey     Keyword = "key"
-	KeywordAs      Keyword = "as"
-
-	KeywordJoin  Keyword = "join"
-	KeywordOn    Keyword = "on"
-	KeywordInner Keyword = "inner"
-	KeywordLeft  Keyword = "left"
-	KeywordRight Keyword = "right"
-	KeywordOuter Keyword = "outer"
-	KeywordCross Keyword = "cross"
-	KeywordFull  Keyword = "full"
+	KeywordCreate     Keyword = "create"
+	KeywordTable      Keyword = "table"
+	KeywordConstraint Keyword = "constraint"
+	KeywordUnique     Keyword = "unique"
+	KeywordCheck      Keyword = "check"
+	KeywordForeign    Keyword = "foreign"
+	KeywordExclude    Keyword = "exclude"
+
+	KeywordSelect    Keyword = "select"
+	KeywordFrom      Keyword = "from"
+	KeywordWhere     Keyword = "where"
+	KeywordIn        Keyword = "in"
+	KeywordLimit     Keyword = "limit"
+	KeywordOffset    Keyword = "offset"
+	KeywordOrder     Keyword = "order"
+	KeywordBy        Keyword = "by"
+	KeywordGroup     Keyword = "group"
+	KeywordAsc       Keyword = "asc"
+	KeywordDesc      Keyword = "desc"
+	KeywordNulls     Keyword = "nulls"
+	KeywordFirst     Keyword = "first"
+	KeywordLast      Keyword = "last"
+	KeywordHaving    Keyword = "having"
+	KeywordWith      Keyword = "with"
+	KeywordRecursive Keyword = "recursive"
+	KeywordUnion     Keyword = "union"
+	KeywordIntersect Keyword = "intersect"
+	KeywordExcept    Keyword = "except"
+	KeywordAll       Keyword = "all"
+	KeywordWindow    Keyword = "window"
+	KeywordOver      Keyword = "over"
+	KeywordPartition Keyword = "partition"
+	KeywordRows      Keyword = "rows"
+	KeywordRange     Keyword = "range"
+	KeywordGroups    Keyword = "groups"
+	KeywordUnbounded Keyword = "unbounded"
+	KeywordPreceding Keyword = "preceding"
+	KeywordFollowing Keyword = "following"
+	KeywordCurrent   Keyword = "current"
+	KeywordRow       Keyword = "row"
+
+	KeywordAnd      Keyword = "and"
+	KeywordOr       Keyword = "or"
+	KeywordFor      Keyword = "for"
+	KeywordIf       Keyword = "if"
+	KeywordElse     Keyword = "else"
+	KeywordNull     Keyword = "null"
+	KeywordTrue     Keyword = "true"
+	KeywordFalse    Keyword = "false"
+	KeywordNot      Keyword = "not"
+	KeywordLike     Keyword = "like"
+	KeywordILike    Keyword = "ilike"
+	KeywordSimilar  Keyword = "similar"
+	KeywordTo       Keyword = "to"
+	KeywordBetween  Keyword = "between"
+	KeywordIs       Keyword = "is"
+	KeywordUnknown  Keyword = "unknown"
+	KeywordDistinct Keyword = "distinct"
+	KeywordExists   Keyword = "exists"
+	KeywordCase     Keyword = "case"
+	KeywordWhen     Keyword = "when"
+	KeywordThen     Keyword = "then"
+	KeywordEnd      Keyword = "end"
+	KeywordCast     Keyword = "cast"
+	KeywordPrimary  Keyword = "primary"
+	KeywordKey      Keyword = "key"
+	KeywordAs       Keyword = "as"
+
+	KeywordJoin    Keyword = "join"
+	KeywordOn      Keyword = "on"
+	KeywordInner   Keyword = "inner"
+	KeywordLeft    Keyword = "left"
+	KeywordRight   Keyword = "right"
+	KeywordOuter   Keyword = "outer"
+	KeywordCross   Keyword = "cross"
+	KeywordFull    Keyword = "full"
+	KeywordNatural Keyword = "natural"
+	KeywordUsing   Keyword = "using"
+	KeywordLateral Keyword = "lateral"
+
+	KeywordUpdate Keyword = "update"
+	KeywordShare  Keyword = "share"
+	KeywordNo     Keyword = "no"
+	KeywordOf     Keyword = "of"
+	KeywordNowait Keyword = "nowait"
+	KeywordSkip   Keyword = "skip"
+	KeywordLocked Keyword = "locked"
+
+	KeywordInsert    Keyword = "insert"
+	KeywordInto      Keyword = "into"
+	KeywordValues    Keyword = "values"
+	KeywordConflict  Keyword = "conflict"
+	KeywordDo        Keyword = "do"
+	KeywordNothing   Keyword = "nothing"
+	KeywordSet       Keyword = "set"
+	KeywordReturning Keyword = "returning"
+
+	// template conditions on list params, eg {if len(ids) > 0} or {if ids IS EMPTY}
+	KeywordLen   Keyword = "len"
+	KeywordEmpty Keyword = "empty"
 )
 
 // todo: flesh out list
@@ -152,7 +227,18 @@ func IsReservedKeyword(k Keyword) bool {
 	case
 		KeywordFrom,
 		KeywordWhere,
+		KeywordGroup,
+		KeywordHaving,
+		KeywordOrder,
 		KeywordLimit,
+		KeywordOffset,
+		KeywordWith,
+		KeywordUnion,
+		KeywordIntersect,
+		KeywordExcept,
+		KeywordWindow,
+		KeywordFor,
+		KeywordReturning,
 		KeywordJoin,
 		KeywordOn,
 		KeywordInner,
@@ -160,7 +246,16 @@ func IsReservedKeyword(k Keyword) bool {
 		KeywordCross,
 		KeywordFull,
 		KeywordLeft,
-		KeywordRight:
+		KeywordRight,
+		KeywordNatural,
+		KeywordUsing,
+		KeywordLateral,
+		KeywordCase,
+		KeywordWhen,
+		KeywordThen,
+		KeywordElse,
+		KeywordEnd,
+		KeywordCast:
 		return true
 	}
 	return false
@@ -232,13 +327,13 @@ func (t Token) IsKeyword(keywords ...string) bool {
 	return false
 }
 
-const RingBufferSize = 5
+const RingBufferSize = 32
 
 type Scanner struct {
 	Source string
 
-	// note: this is probably overkill, but will wait until parser is more complete.
-	// so far only 1-2 token lookahead is needed
+	// most lookahead is 1-2 tokens, but a conditional join looks past its
+	// {if ...} condition to find the join
 	TokenRingBuffer [RingBufferSize]Token
 	BufferSize      int
 	BufferStart     int
@@ -406,8 +501,8 @@ func (s *Scanner) scanToken() error {
 		s.addToken(Semicolon)
 	case "*":
 		s.addToken(Star)
-	case ":":
-		s.addToken(Colon)
+	case "%":
+		s.addToken(Percent)
 
 	// one/two characters
 	case "?":
@@ -439,6 +534,17 @@ func (s *Scanner) scanToken() error {
 			t = GreaterEqual
 		}
 		s.addToken(t)
+	case "|":
+		if !s.match('|') {
+			return fmt.Errorf("line %d: Unexpected character: %s", s.line, c)
+		}
+		s.addToken(PipePipe)
+	case ":":
+		t := Colon
+		if s.match(':') {
+			t = ColonColon
+		}
+		s.addToken(t)
 
 	// division/comment
 	case "/":
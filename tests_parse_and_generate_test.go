@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"go/format"
 	"os"
@@ -14,8 +15,22 @@ func TestGeneration(t *testing.T) {
 		id   BIGSERIAL PRIMARY KEY,
 		first_name text      NOT NULL,
 		last_name text NOT NULL,
-		alias text NOT NULL,
-		bio  text
+		alias text NOT NULL UNIQUE,
+		bio  text,
+		active boolean NOT NULL
+	);
+
+	CREATE TABLE categories (
+		id   BIGSERIAL PRIMARY KEY,
+		parent_id bigint,
+		name text NOT NULL
+	);
+
+	CREATE TABLE books (
+		id   BIGSERIAL PRIMARY KEY,
+		author_id bigint NOT NULL,
+		title text NOT NULL,
+		CONSTRAINT books_author_id_title_key UNIQUE (author_id, title)
 	);
 	`
 
@@ -23,8 +38,11 @@ func TestGeneration(t *testing.T) {
 		name             string
 		queries          string
 		expectErrors     []error
+		expectErrorLines []int // checked when set
 		expectResult     string
 		expectResultFile string
+		types            TypeMap
+		inListStyle      InListStyle
 	}
 
 	testCases := []testCase{
@@ -409,341 +427,2696 @@ func TestGeneration(t *testing.T) {
 			expectErrors:     []error{ErrUnknownTable},
 			expectResultFile: "",
 		},
-	}
+		{
+			name: "select with mapped types",
+			queries: `
+				query GetAuthorMappedTypes(id: AuthorID) {
+					SELECT id, bio FROM authors
+					WHERE id = {id}
+				}
+			`,
+			types: TypeMap{Mappings: []TypeMapping{
+				{Table: "authors", Column: "id", GoType: GoType{ImportPath: "github.com/acme/ids", Name: "AuthorID"}},
+				{ColumnType: "text", GoType: GoType{ImportPath: "github.com/acme/text", Name: "Text"}},
+			}},
+			expectErrors: nil,
+			expectResult: `package main
 
-	for _, test := range testCases {
-		t.Run(test.name, func(t *testing.T) {
+import (
+	"fmt"
+	"github.com/acme/ids"
+	"github.com/acme/text"
+	"strings"
+)
 
-			schemaParser := NewSchemaParser(schema)
-			schemaParser.Parse()
+type GetAuthorMappedTypesInput struct {
+	id ids.AuthorID
+}
 
-			queryParser := NewQueryParser(test.queries)
-			queryParser.Parse()
+type GetAuthorMappedTypesRow struct {
+	id  ids.AuthorID
+	bio *text.Text
+}
 
-			checkErrors := CheckQueries(schemaParser.Result, queryParser.Result)
-			if len(test.expectErrors) != len(checkErrors) {
-				t.Log(test.expectErrors, checkErrors)
-				t.Fatalf("expected %d errors, got %d", len(test.expectErrors), len(checkErrors))
-			} else {
-				for i := range checkErrors {
-					if !errors.Is(checkErrors[i].Err, test.expectErrors[i]) {
-						t.Fatalf("expected error: %s, got: %s", test.expectErrors[i], checkErrors[i].Err)
-					}
-				}
-			}
+func QueryGetAuthorMappedTypes(input GetAuthorMappedTypesInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
 
-			if len(checkErrors) == 0 {
-				generated, err := Generate(schemaParser.Result, queryParser.Result, "main")
-				if err != nil {
-					// allow continuing in case it's an error while formatting
-					t.Errorf("got error: %s", err)
-				}
+	argIndex := 1
 
-				expectedResult := test.expectResult
+	sb.WriteString("SELECT id, bio FROM authors")
 
-				if test.expectResultFile != "" {
-					file, err := os.ReadFile(test.expectResultFile)
-					if err != nil {
-						t.Fatalf("error reading file: %s", err)
-					}
-					expectedResult = string(file)
-				}
+	lit1 := "id"
+	lit2 := fmt.Sprintf("$%d", argIndex)
+	args = append(args, input.id)
+	argIndex++
+	expr1 := fmt.Sprintf("%s = %s", lit1, lit2)
+	sb.WriteString(fmt.Sprintf(" WHERE %s", expr1))
 
-				expectedResultFormatted, err := format.Source([]byte(expectedResult))
-				if err != nil {
-					t.Fatalf("error formatting: %s", err)
-				}
-				expectedResult = string(expectedResultFormatted)
+	sb.WriteString(";")
 
-				if generated != expectedResult {
-					_ = os.WriteFile("testfailure/expected.go", []byte(expectedResult), 0644)
-					_ = os.WriteFile("testfailure/got.go", []byte(generated), 0644)
-					t.Errorf("generated output mismatch")
-					t.Log("expected:\n\n", expectedResult)
-					t.Log("got:\n\n", generated)
+	return sb.String(), args
+}
+`,
+		},
+		{
+			name: "select with mapped types - errors with unknown param type",
+			queries: `
+				query GetAuthorMappedTypes(id: AuthorID) {
+					SELECT id FROM authors
+					WHERE id = {id}
 				}
-			}
-		})
-	}
+			`,
+			expectErrors:     []error{ErrUnknownType},
+			expectResultFile: "",
+		},
+		{
+			name: "select with mapped types - errors with ambiguous param type",
+			queries: `
+				query GetAuthorMappedTypes(id: AuthorID) {
+					SELECT id FROM authors
+					WHERE id = {id}
+				}
+			`,
+			types: TypeMap{Mappings: []TypeMapping{
+				{Table: "authors", Column: "id", GoType: GoType{ImportPath: "github.com/acme/ids", Name: "AuthorID"}},
+				{Table: "books", Column: "author_id", GoType: GoType{ImportPath: "github.com/acme/ids", Name: "AuthorID"}},
+				{Table: "categories", Column: "id", GoType: GoType{ImportPath: "github.com/acme/legacy", Name: "AuthorID"}},
+			}},
+			expectErrors:     []error{ErrAmbiguousType},
+			expectResultFile: "",
+		},
+		{
+			name: "select with in list",
+			queries: `
+				query GetAuthorInList(ids: [int], excludeNames: [string]) {
+					SELECT id FROM authors
+					WHERE id IN {ids} AND first_name NOT IN {excludeNames}
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_in_list.go",
+		},
+		{
+			name: "select with in list - any style",
+			queries: `
+				query GetAuthorInListAny(ids: [int]) {
+					SELECT id FROM authors
+					WHERE id IN {ids}
+				}
+			`,
+			inListStyle:  InListStyleAny,
+			expectErrors: nil,
+			expectResult: `package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorInListAnyInput struct {
+	ids []int
 }
 
-func ptr[T any](in T) *T {
-	return &in
+type GetAuthorInListAnyRow struct {
+	id int64
 }
 
-func assertQuery(t *testing.T, expectQuery string, expectArgs []interface{}, gotQuery string, gotArgs []interface{}) {
-	if expectQuery != gotQuery {
-		t.Fatalf("\nexpected qry: %s\ngot:          %s", expectQuery, gotQuery)
-	}
+func QueryGetAuthorInListAny(input GetAuthorInListAnyInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
 
-	if len(expectArgs) != len(gotArgs) {
-		t.Fatalf("expected %d args, got %d", len(expectArgs), len(gotArgs))
-	} else {
-		for i := range expectArgs {
-			if expectArgs[i] != gotArgs[i] {
-				t.Fatalf("\nexpected arg: %s\ngot:          %s", expectArgs[i], gotArgs[i])
-			}
-		}
+	argIndex := 1
+
+	sb.WriteString("SELECT id FROM authors")
+
+	lit1 := "id"
+	expr1 := "FALSE"
+	if len(input.ids) > 0 {
+		expr1 = fmt.Sprintf("%s = ANY($%d)", lit1, argIndex)
+		args = append(args, input.ids)
+		argIndex++
 	}
+	sb.WriteString(fmt.Sprintf(" WHERE %s", expr1))
+
+	sb.WriteString(";")
+
+	return sb.String(), args
 }
+`,
+		},
+		{
+			name: "select with in list - errors with non-list param",
+			queries: `
+				query GetAuthorInList(id: int) {
+					SELECT id FROM authors
+					WHERE id IN {id}
+				}
+			`,
+			expectErrors:     []error{ErrInvalidListParam},
+			expectResultFile: "",
+		},
+		{
+			name: "select with in list - errors with list param in comparison",
+			queries: `
+				query GetAuthorInList(ids: [int]) {
+					SELECT id FROM authors
+					WHERE id = {ids}
+				}
+			`,
+			expectErrors:     []error{ErrInvalidListParam},
+			expectResultFile: "",
+		},
+		{
+			name: "select with predicates",
+			queries: `
+				query GetAuthorPredicates(minID: int?, maxID: int?, nameLike: string?, bioPattern: string?, otherBio: string?) {
+					SELECT id FROM authors
+					WHERE
+						id BETWEEN {minID} AND {maxID}
+						AND first_name ILIKE {nameLike}
+						AND bio SIMILAR TO {bioPattern}
+						AND bio IS DISTINCT FROM {otherBio}
+						AND active IS NOT FALSE
+						AND NOT (alias NOT ILIKE {nameLike} OR alias NOT SIMILAR TO 'x%')
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_predicates.go",
+		},
+		{
+			name: "select with predicates - errors with invalid IS operand",
+			queries: `
+				query GetAuthorPredicates() {
+					SELECT id FROM authors
+					WHERE id IS 5
+				}
+			`,
+			expectErrors:     []error{ErrInvalidOperand},
+			expectResultFile: "",
+		},
+		{
+			name: "select with arithmetic",
+			queries: `
+				query GetAuthorArithmetic(minID: int?, offset: int, name: string?) {
+					SELECT id FROM authors
+					WHERE
+						id * 2 + 1 > {minID}
+						AND (id + {offset}) * 3 % 7 = -id
+						AND first_name || ' ' || last_name = {name}
+						AND id - (id - {minID}) = 1
+						AND {if offset * 2 > 10}
+							id > {offset}
+						{end}
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_arithmetic.go",
+		},
+		{
+			name: "select with functions",
+			queries: `
+				query GetAuthorFunctions(name: string?, fallback: string?) {
+					SELECT
+						id,
+						lower(first_name),
+						coalesce(bio, {fallback}) AS bio,
+						CASE WHEN id > 5 THEN 'big' ELSE 'small' END AS size,
+						CASE active WHEN TRUE THEN 1 ELSE 0 END AS active_flag
+					FROM authors
+					WHERE
+						lower(first_name) = lower({name})
+						AND length(coalesce(bio, '')) >= 0
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_functions.go",
+		},
+		{
+			name: "select with functions - errors with missing alias",
+			queries: `
+				query GetAuthorFunctions() {
+					SELECT id + 1 FROM authors
+				}
+			`,
+			expectErrors:     []error{ErrMissingAlias},
+			expectResultFile: "",
+		},
+		{
+			name: "select with functions - errors with wrong arg count",
+			queries: `
+				query GetAuthorFunctions() {
+					SELECT id FROM authors
+					WHERE lower(first_name, last_name) = 'a'
+				}
+			`,
+			expectErrors:     []error{ErrInvalidFunctionArgs},
+			expectResultFile: "",
+		},
+		{
+			name: "select with casts",
+			queries: `
+				query GetAuthorCasts(minID: string?, name: string) {
+					SELECT
+						id::text AS id_text,
+						CAST(bio AS varchar(255)),
+						(id + 1)::double precision AS next_id
+					FROM authors
+					WHERE
+						id > {minID}::bigint
+						AND first_name = CAST({name} AS text)
+						AND '5'::int + id > 6
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_casts.go",
+		},
+		{
+			name: "select with casts - errors with arithmetic on text",
+			queries: `
+				query GetAuthorCasts() {
+					SELECT id FROM authors
+					WHERE first_name + 1 > 5
+				}
+			`,
+			expectErrors:     []error{ErrInvalidOperand},
+			expectResultFile: "",
+		},
+		{
+			name: "select with group by",
+			queries: `
+				query GetAuthorGroupBy(minCount: int?) {
+					SELECT
+						first_name,
+						count(*),
+						count(DISTINCT last_name) AS last_names,
+						sum(id) AS id_sum,
+						max(bio) AS max_bio,
+						lower(last_name) AS last_name_lower
+					FROM authors
+					WHERE active
+					GROUP BY first_name, lower(last_name)
+					HAVING count(*) > {minCount}
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_group_by.go",
+		},
+		{
+			name: "select with group by - aggregates without group by are nullable",
+			queries: `
+				query GetAuthorAggregates {
+					SELECT count(*), sum(id) AS total FROM authors
+				}
+			`,
+			expectErrors: nil,
+			expectResult: `package main
 
-// test the generated output with various inputs to
-// ensure they produce expected queries
-func TestGeneratedSelects(t *testing.T) {
-	t.Run("simple select", func(t *testing.T) {
-		query, args := QueryGetAuthorSimpleSelect()
-		assertQuery(t,
-			"SELECT id FROM authors WHERE id = 5;",
-			[]interface{}{},
-			query,
-			args,
-		)
-	})
+import (
+	"fmt"
+	"strings"
+)
 
-	t.Run("simple select - table and column alias", func(t *testing.T) {
-		query, args := QueryGetAuthorSimpleSelectAlias()
-		assertQuery(t,
-			"SELECT id my_id FROM authors my_authors WHERE my_authors.id = 5 OR id = 5;",
-			[]interface{}{},
-			query,
-			args,
-		)
-	})
+type GetAuthorAggregatesRow struct {
+	count int64
+	total *string
+}
 
-	t.Run("select with variable", func(t *testing.T) {
-		query, args := QueryGetAuthorWithVariable(GetAuthorWithVariableInput{id: "32"})
-		assertQuery(t,
-			"SELECT id FROM authors WHERE id = $1 LIMIT 1;",
-			[]interface{}{"32"},
-			query,
-			args,
-		)
-	})
-	t.Run("select with variable - no input", func(t *testing.T) {
-		// todo: consider if this should error instead
-		query, args := QueryGetAuthorWithVariable(GetAuthorWithVariableInput{})
-		assertQuery(t,
-			"SELECT id FROM authors WHERE id = $1 LIMIT 1;",
-			[]interface{}{""},
-			query,
-			args,
-		)
-	})
+func QueryGetAuthorAggregates() (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
 
-	t.Run("select optional where - nil parameter", func(t *testing.T) {
-		query, args := QueryGetAuthorOptionalWhere(GetAuthorOptionalWhereInput{})
-		assertQuery(t,
-			"SELECT id FROM authors LIMIT 1;",
-			[]interface{}{},
-			query,
-			args,
-		)
-	})
+	lit1 := "count(*)"
+	lit2 := "id"
+	lit3 := fmt.Sprintf("sum(%s)", lit2)
+	sb.WriteString(fmt.Sprintf("SELECT %s, %s total FROM authors", lit1, lit3))
 
-	t.Run("select optional where - non-nil parameter", func(t *testing.T) {
-		query, args := QueryGetAuthorOptionalWhere(GetAuthorOptionalWhereInput{id: ptr("23")})
-		assertQuery(t,
-			"SELECT id FROM authors WHERE id = $1 LIMIT 1;",
-			[]interface{}{"23"},
-			query,
-			args,
-		)
-	})
+	sb.WriteString(";")
 
-	t.Run("select optional where or - nil parameters", func(t *testing.T) {
-		query, args := QueryGetAuthorOptionalWhereOr(GetAuthorOptionalWhereOrInput{})
-		assertQuery(t,
-			"SELECT id FROM authors LIMIT 1;",
-			[]interface{}{},
-			query,
+	return sb.String(), args
+}
+`,
+		},
+		{
+			name: "select with group by - grouping by primary key groups its table",
+			queries: `
+				query GetAuthorGroupBy {
+					SELECT id, first_name, count(*) FROM authors
+					GROUP BY id
+				}
+			`,
+			expectErrors: nil,
+			expectResult: `package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorGroupByRow struct {
+	id         int64
+	first_name string
+	count      int64
+}
+
+func QueryGetAuthorGroupBy() (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	lit1 := "count(*)"
+	sb.WriteString(fmt.Sprintf("SELECT id, first_name, %s FROM authors", lit1))
+
+	lit2 := "id"
+	sb.WriteString(fmt.Sprintf(" GROUP BY %s", lit2))
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
+`,
+		},
+		{
+			name: "select with group by - errors with ungrouped field",
+			queries: `
+				query GetAuthorGroupBy {
+					SELECT first_name, last_name, count(*) FROM authors
+					GROUP BY first_name
+				}
+			`,
+			expectErrors:     []error{ErrUngroupedField},
+			expectResultFile: "",
+		},
+		{
+			name: "select with group by - errors with ungrouped field in having",
+			queries: `
+				query GetAuthorGroupBy {
+					SELECT first_name FROM authors
+					GROUP BY first_name
+					HAVING last_name = 'a'
+				}
+			`,
+			expectErrors:     []error{ErrUngroupedField},
+			expectResultFile: "",
+		},
+		{
+			name: "select with group by - errors with aggregate in where",
+			queries: `
+				query GetAuthorGroupBy {
+					SELECT count(*) FROM authors
+					WHERE count(*) > 1
+				}
+			`,
+			expectErrors:     []error{ErrInvalidAggregate},
+			expectResultFile: "",
+		},
+		{
+			name: "select with group by - errors with nested aggregate",
+			queries: `
+				query GetAuthorGroupBy {
+					SELECT max(count(*)) FROM authors
+				}
+			`,
+			expectErrors:     []error{ErrInvalidAggregate},
+			expectResultFile: "",
+		},
+		{
+			name: "select with order by",
+			queries: `
+				query GetAuthorOrderBy(name: string?) {
+					SELECT id, first_name AS author_name FROM authors
+					WHERE first_name = {name}
+					ORDER BY {sort in (first_name, last_name, bio)} {dir} NULLS LAST, author_name, id DESC
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_order_by.go",
+		},
+		{
+			name: "select with order by - errors with unknown sort column",
+			queries: `
+				query GetAuthorOrderBy {
+					SELECT id FROM authors
+					ORDER BY {sort in (first_name, created_at)}
+				}
+			`,
+			expectErrors:     []error{ErrUnknownField},
+			expectResultFile: "",
+		},
+		{
+			name: "select with order by - errors with param declared twice",
+			queries: `
+				query GetAuthorOrderBy(sort: string) {
+					SELECT id FROM authors
+					WHERE first_name = {sort}
+					ORDER BY {sort in (first_name, last_name)}
+				}
+			`,
+			expectErrors:     []error{ErrDuplicateParam},
+			expectResultFile: "",
+		},
+		{
+			name: "select with order by - errors with ungrouped sort column",
+			queries: `
+				query GetAuthorOrderBy {
+					SELECT first_name, count(*) FROM authors
+					GROUP BY first_name
+					ORDER BY {sort in (count, last_name)}
+				}
+			`,
+			expectErrors:     []error{ErrUngroupedField},
+			expectResultFile: "",
+		},
+		{
+			name: "select with limit and offset",
+			queries: `
+				query GetAuthorLimit(limit: int?, offset: int) {
+					SELECT id FROM authors
+					ORDER BY id
+					LIMIT {limit} OFFSET {offset}
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_limit.go",
+		},
+		{
+			name: "select with limit and offset - static values",
+			queries: `
+				query GetAuthorLimitStatic {
+					SELECT id FROM authors
+					OFFSET 5 LIMIT 10
+				}
+			`,
+			expectErrors: nil,
+			expectResult: `package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorLimitStaticRow struct {
+	id int64
+}
+
+func QueryGetAuthorLimitStatic() (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	sb.WriteString("SELECT id FROM authors")
+
+	sb.WriteString(" LIMIT 10")
+	sb.WriteString(" OFFSET 5")
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
+`,
+		},
+		{
+			name: "select with limit and offset - errors with negative limit",
+			queries: `
+				query GetAuthorLimit {
+					SELECT id FROM authors
+					LIMIT -1
+				}
+			`,
+			expectErrors:     []error{ErrInvalidOperand},
+			expectResultFile: "",
+		},
+		{
+			name: "select with limit and offset - errors with non-int param",
+			queries: `
+				query GetAuthorLimit(limit: string, offsets: [int]) {
+					SELECT id FROM authors
+					LIMIT {limit} OFFSET {offsets}
+				}
+			`,
+			expectErrors:     []error{ErrInvalidOperand, ErrInvalidOperand},
+			expectResultFile: "",
+		},
+		{
+			name: "select with paginate",
+			queries: `
+				query GetAuthorPaginate(name: string?, pageSize: int) {
+					SELECT id, first_name FROM authors
+					WHERE first_name = {name}
+					{paginate after cursor by first_name, id limit pageSize}
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_paginate.go",
+		},
+		{
+			name: "select with paginate - errors with nullable column",
+			queries: `
+				query GetAuthorPaginate {
+					SELECT id, bio FROM authors
+					{paginate after cursor by bio, id limit 10}
+				}
+			`,
+			expectErrors:     []error{ErrInvalidPaginate},
+			expectResultFile: "",
+		},
+		{
+			name: "select with paginate - errors with column that isn't selected",
+			queries: `
+				query GetAuthorPaginate {
+					SELECT id FROM authors
+					{paginate after cursor by first_name DESC, id DESC}
+				}
+			`,
+			expectErrors:     []error{ErrInvalidPaginate},
+			expectResultFile: "",
+		}, {
+			name: "select with order by - only sort params",
+			queries: `
+				query GetBookAuthorsSorted {
+					SELECT a.first_name, b.title FROM authors a JOIN books b ON b.author_id = a.id
+					ORDER BY {sort in (a.first_name, b.title)} {dir}
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_order_by_sort_only.go",
+		}, {
+			name: "select with if - only bool params",
+			queries: `
+				query GetAuthorsByFlag(inactive: bool) {
+					SELECT a.id FROM authors a
+					WHERE {if NOT inactive} a.active = TRUE {end}
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_if_flag_only.go",
+		}, {
+			name: "select distinct",
+			queries: `
+				query GetAuthorDistinct {
+					SELECT DISTINCT first_name, last_name FROM authors
+					ORDER BY {sort in (first_name, last_name)}
+				}
+			`,
+			expectErrors: nil,
+			expectResult: `package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorDistinctSort int
+
+const (
+	GetAuthorDistinctSortFirstName GetAuthorDistinctSort = iota
+	GetAuthorDistinctSortLastName
+)
+
+type GetAuthorDistinctInput struct {
+	sort GetAuthorDistinctSort
+}
+
+type GetAuthorDistinctRow struct {
+	first_name string
+	last_name  string
+}
+
+func QueryGetAuthorDistinct(input GetAuthorDistinctInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	sb.WriteString("SELECT DISTINCT first_name, last_name FROM authors")
+
+	var lit1 string
+	switch input.sort {
+	case GetAuthorDistinctSortLastName:
+		lit1 = "last_name"
+	default:
+		lit1 = "first_name"
+	}
+	sb.WriteString(fmt.Sprintf(" ORDER BY %s", lit1))
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
+`,
+		},
+		{
+			name: "select distinct on",
+			queries: `
+				query GetAuthorDistinctOn(name: string?) {
+					SELECT DISTINCT ON (first_name, lower(last_name)) id, first_name, last_name FROM authors a
+					WHERE first_name = {name}
+					ORDER BY lower(last_name), a.first_name, id DESC
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_distinct.go",
+		},
+		{
+			name: "select distinct - errors when DISTINCT ON isn't leftmost in ORDER BY",
+			queries: `
+				query GetAuthorDistinctOn {
+					SELECT DISTINCT ON (first_name) id, first_name FROM authors
+					ORDER BY id, first_name
+				}
+			`,
+			expectErrors:     []error{ErrInvalidDistinct},
+			expectErrorLines: []int{3},
+			expectResultFile: "",
+		},
+		{
+			name: "select distinct - errors when ordering by a field that isn't selected",
+			queries: `
+				query GetAuthorDistinct {
+					SELECT DISTINCT first_name FROM authors
+					ORDER BY id, {sort in (first_name, last_name)}
+				}
+			`,
+			expectErrors:     []error{ErrInvalidDistinct, ErrInvalidDistinct},
+			expectResultFile: "",
+		},
+		{
+			name: "select with cte",
+			queries: `
+				query GetAuthorRecent(name: string?, lastName: string?, minID: int) {
+					WITH named AS (
+						SELECT id, first_name AS name FROM authors
+						WHERE first_name = {name}
+					), recent AS (
+						SELECT n.id, n.name FROM named n
+						JOIN authors a ON a.id = n.id
+						WHERE a.last_name = {lastName}
+						ORDER BY n.id DESC
+						LIMIT 10
+					)
+					SELECT id, name FROM recent
+					WHERE id > {minID}
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_cte.go",
+		},
+		{
+			name: "select with recursive cte",
+			queries: `
+				query GetCategoryTree(rootID: int) {
+					WITH RECURSIVE tree(id, parent_id, label) AS (
+						SELECT id, parent_id, name FROM categories
+						WHERE id = {rootID}
+						UNION ALL
+						SELECT c.id, c.parent_id, c.name FROM categories c
+						JOIN tree t ON c.parent_id = t.id
+					)
+					SELECT id, label FROM tree
+				}
+			`,
+			expectErrors: nil,
+			expectResult: `package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetCategoryTreeInput struct {
+	rootID int
+}
+
+type GetCategoryTreeRow struct {
+	id    int64
+	label string
+}
+
+func QueryGetCategoryTree(input GetCategoryTreeInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	sb.WriteString("WITH RECURSIVE tree(id, parent_id, label) AS (")
+
+	sb.WriteString("SELECT id, parent_id, name FROM categories")
+
+	lit1 := "id"
+	lit2 := fmt.Sprintf("$%d", argIndex)
+	args = append(args, input.rootID)
+	argIndex++
+	expr1 := fmt.Sprintf("%s = %s", lit1, lit2)
+	sb.WriteString(fmt.Sprintf(" WHERE %s", expr1))
+
+	sb.WriteString(" UNION ALL ")
+
+	sb.WriteString("SELECT c.id, c.parent_id, c.name FROM categories c")
+
+	sb.WriteString(" INNER JOIN tree t ON ")
+	lit3 := "c.parent_id"
+	lit4 := "t.id"
+	expr2 := fmt.Sprintf("%s = %s", lit3, lit4)
+	sb.WriteString(fmt.Sprintf("%s", expr2))
+
+	sb.WriteString(") ")
+
+	sb.WriteString("SELECT id, label FROM tree")
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
+`,
+		},
+		{
+			name: "select with cte - errors when cte refers to itself without recursive",
+			queries: `
+				query GetCategoryTree {
+					WITH tree AS (
+						SELECT id FROM categories
+						UNION ALL
+						SELECT c.id FROM categories c JOIN tree t ON c.parent_id = t.id
+					)
+					SELECT id FROM tree
+				}
+			`,
+			expectErrors:     []error{ErrUnknownTable},
+			expectResultFile: "",
+		},
+		{
+			name: "select with cte - errors with unknown cte column",
+			queries: `
+				query GetAuthorRecent {
+					WITH recent AS (
+						SELECT id FROM authors
+					)
+					SELECT id, first_name FROM recent
+				}
+			`,
+			expectErrors:     []error{ErrUnknownField},
+			expectResultFile: "",
+		},
+		{
+			name: "select with cte - errors with mismatched column counts",
+			queries: `
+				query GetCategoryTree {
+					WITH RECURSIVE tree(id) AS (
+						SELECT id, name FROM categories
+						UNION ALL
+						SELECT c.id, c.name FROM categories c JOIN tree t ON c.parent_id = t.id
+					)
+					SELECT id FROM tree
+				}
+			`,
+			expectErrors:     []error{ErrColumnCountMismatch},
+			expectResultFile: "",
+		},
+		{
+			name: "select with subqueries",
+			queries: `
+				query GetCategorySubqueries(childName: string?, minID: int) {
+					SELECT p.id,
+						(SELECT count(*) FROM categories c WHERE c.parent_id = p.id AND c.name = {childName}) AS child_count
+					FROM categories p
+					WHERE EXISTS (SELECT id FROM categories c WHERE c.parent_id = p.id)
+						AND p.parent_id IN (SELECT id FROM categories WHERE id > {minID})
+						AND p.id NOT IN (SELECT id FROM (SELECT id, name FROM categories WHERE name = {childName}) named)
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_subqueries.go",
+		},
+		{
+			name: "select with subqueries - errors when scalar subquery selects more than one column",
+			queries: `
+				query GetCategorySubqueries {
+					SELECT id, (SELECT id, name FROM categories c WHERE c.parent_id = p.id LIMIT 1) AS child
+					FROM categories p
+				}
+			`,
+			expectErrors:     []error{ErrInvalidSubquery},
+			expectResultFile: "",
+		},
+		{
+			name: "select with subqueries - errors when subquery in FROM has no alias",
+			queries: `
+				query GetCategorySubqueries {
+					SELECT id FROM (SELECT id FROM categories)
+				}
+			`,
+			expectErrors:     []error{ErrMissingAlias},
+			expectResultFile: "",
+		},
+		{
+			name: "select with subqueries - errors when subquery in FROM references outer table",
+			queries: `
+				query GetCategorySubqueries {
+					SELECT c.id FROM categories c
+					WHERE c.id IN (SELECT id FROM (SELECT id FROM categories WHERE parent_id = c.id) children)
+				}
+			`,
+			expectErrors:     []error{ErrUnknownTable},
+			expectResultFile: "",
+		},
+		{
+			name: "select with union",
+			queries: `
+				query GetAuthorAndCategoryNames(name: string?, includeCategories: string?, limit: int) {
+					SELECT id, first_name AS label FROM authors
+					WHERE first_name = {name}
+					{if includeCategories IS NOT NULL}
+						UNION ALL
+						SELECT id, name FROM categories
+						WHERE name = {includeCategories}
+					{end}
+					EXCEPT
+					SELECT id, last_name FROM authors
+					ORDER BY label DESC, id
+					LIMIT {limit}
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_union.go",
+		},
+		{
+			name: "select with union - intersect with widened types",
+			queries: `
+				query GetCategoryParents {
+					SELECT parent_id FROM categories
+					INTERSECT
+					SELECT count(*) FROM authors
+				}
+			`,
+			expectErrors: nil,
+			expectResult: `package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetCategoryParentsRow struct {
+	parent_id *int64
+}
+
+func QueryGetCategoryParents() (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	sb.WriteString("SELECT parent_id FROM categories")
+
+	sb.WriteString(" INTERSECT ")
+
+	lit1 := "count(*)"
+	sb.WriteString(fmt.Sprintf("SELECT %s FROM authors", lit1))
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
+`,
+		},
+		{
+			name: "select with union - errors with incompatible types",
+			queries: `
+				query GetAuthorAndCategoryNames {
+					SELECT id, first_name FROM authors
+					UNION
+					SELECT name, id FROM categories
+				}
+			`,
+			expectErrors:     []error{ErrInvalidSetOp, ErrInvalidSetOp},
+			expectResultFile: "",
+		},
+		{
+			name: "select with union - errors with invalid {if} conditions",
+			queries: `
+				query GetAuthorAndCategoryNames(includeCategories: string?, ids: [int]) {
+					SELECT id FROM authors
+					{if includeCategories}
+						UNION ALL
+						SELECT id FROM categories
+					{end}
+					{if ids IS NOT NULL}
+						UNION ALL
+						SELECT id FROM books
+					{end}
+				}
+			`,
+			expectErrors:     []error{ErrInvalidOperand, ErrInvalidListParam},
+			expectResultFile: "",
+		},
+		{
+			name: "select with union - errors when ORDER BY isn't a result column",
+			queries: `
+				query GetAuthorAndCategoryNames {
+					SELECT id FROM authors
+					UNION
+					SELECT id FROM categories
+					ORDER BY lower(name)
+				}
+			`,
+			expectErrors:     []error{ErrInvalidSetOp},
+			expectResultFile: "",
+		},
+		{
+			name: "select with window",
+			queries: `
+				query GetCategoryRanks(frameSize: int) {
+					SELECT id, name,
+						row_number() OVER (PARTITION BY parent_id ORDER BY name) AS position,
+						count(*) OVER siblings AS sibling_count,
+						sum(id) OVER (siblings ORDER BY id ROWS BETWEEN {frameSize} PRECEDING AND CURRENT ROW) AS running_total,
+						lag(name) OVER (ORDER BY id) AS previous_name
+					FROM categories
+					WINDOW siblings AS (PARTITION BY parent_id)
+					ORDER BY rank() OVER (ORDER BY name DESC), id
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_window.go",
+		},
+		{
+			name: "select with window - frames and nullability",
+			queries: `
+				query GetCategoryFirstNames {
+					SELECT id,
+						first_value(name) OVER w AS first_name,
+						last_value(name) OVER (w ROWS BETWEEN 1 FOLLOWING AND UNBOUNDED FOLLOWING) AS last_name
+					FROM categories
+					WINDOW w AS (PARTITION BY parent_id ORDER BY id)
+				}
+			`,
+			expectErrors: nil,
+			expectResult: `package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetCategoryFirstNamesRow struct {
+	id         int64
+	first_name string
+	last_name  *string
+}
+
+func QueryGetCategoryFirstNames() (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	lit1 := "name"
+	lit2 := fmt.Sprintf("first_value(%s) OVER w", lit1)
+	lit3 := "name"
+	lit4 := "1"
+	lit5 := fmt.Sprintf("last_value(%s) OVER (w ROWS BETWEEN %s FOLLOWING AND UNBOUNDED FOLLOWING)", lit3, lit4)
+	sb.WriteString(fmt.Sprintf("SELECT id, %s first_name, %s last_name FROM categories", lit2, lit5))
+
+	lit6 := "parent_id"
+	lit7 := "id"
+	sb.WriteString(fmt.Sprintf(" WINDOW w AS (PARTITION BY %s ORDER BY %s)", lit6, lit7))
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
+`,
+		},
+		{
+			name: "select with window - errors with unknown window or missing OVER",
+			queries: `
+				query GetCategoryRanks {
+					SELECT rank(), count(*) OVER w FROM categories
+				}
+			`,
+			expectErrors:     []error{ErrInvalidWindow, ErrUnknownWindow},
+			expectResultFile: "",
+		},
+		{
+			name: "select with window - errors when used in WHERE",
+			queries: `
+				query GetCategoryRanks {
+					SELECT id FROM categories
+					WHERE row_number() OVER (ORDER BY id) > 1
+				}
+			`,
+			expectErrors:     []error{ErrInvalidWindow},
+			expectResultFile: "",
+		},
+		{
+			name: "select with window - errors when overriding a named window",
+			queries: `
+				query GetCategoryRanks {
+					SELECT rank() OVER (w PARTITION BY name) FROM categories
+					WINDOW w AS (ORDER BY id)
+				}
+			`,
+			expectErrors:     []error{ErrInvalidWindow},
+			expectResultFile: "",
+		},
+		{
+			name: "select with window - errors with optional frame offsets",
+			queries: `
+				query GetCategoryTotals(frameSize: int?) {
+					SELECT sum(id) OVER (ORDER BY id ROWS BETWEEN {frameSize} PRECEDING AND CURRENT ROW) AS total,
+						sum(id) OVER (w ROWS {frameSize} PRECEDING) AS window_total
+					FROM categories
+					WINDOW w AS (ORDER BY id)
+				}
+			`,
+			expectErrors:     []error{ErrInvalidWindow, ErrInvalidWindow},
+			expectResultFile: "",
+		},
+		{
+			name: "select with window - errors with ungrouped partition",
+			queries: `
+				query GetCategoryRanks {
+					SELECT parent_id, rank() OVER (PARTITION BY parent_id ORDER BY name) FROM categories
+					GROUP BY parent_id
+				}
+			`,
+			expectErrors:     []error{ErrUngroupedField},
+			expectResultFile: "",
+		},
+		{
+			name: "select with join - using, lateral and cross",
+			queries: `
+				query GetBookAuthors(title: string?) {
+					SELECT author_id, b.title, first_name, latest.title AS latest_title, c.name
+					FROM books b
+					JOIN (SELECT id AS author_id, first_name FROM authors) a USING (author_id)
+					LEFT JOIN LATERAL (
+						SELECT title FROM books
+						WHERE author_id = b.author_id AND title <> {title}
+						ORDER BY id DESC
+						LIMIT 1
+					) latest ON true
+					CROSS JOIN categories c
+					WHERE c.parent_id IS NULL
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_join_using.go",
+		},
+		{
+			name: "select with join - natural join merges columns in star",
+			queries: `
+				query GetBookAuthors {
+					SELECT * FROM books NATURAL JOIN (SELECT id AS author_id, first_name FROM authors) a
+				}
+			`,
+			expectErrors: nil,
+			expectResult: `package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetBookAuthorsRow struct {
+	author_id  int64
+	id         int64
+	title      string
+	first_name string
+}
+
+func QueryGetBookAuthors() (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	sb.WriteString("SELECT * FROM books")
+
+	var lit1 string
+	{
+		sb := strings.Builder{}
+
+		sb.WriteString("SELECT id author_id, first_name FROM authors")
+
+		lit1 = fmt.Sprintf("(%s)", sb.String())
+	}
+	sb.WriteString(fmt.Sprintf(" NATURAL INNER JOIN %s a", lit1))
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
+`,
+		},
+		{
+			name: "select with join - errors with using column missing on one side",
+			queries: `
+				query GetBookAuthors {
+					SELECT b.id FROM books b JOIN authors a USING (author_id)
+				}
+			`,
+			expectErrors:     []error{ErrUnknownField},
+			expectResultFile: "",
+		},
+		{
+			name: "select with join - errors with ambiguous using column",
+			queries: `
+				query GetBookAuthors {
+					SELECT b.id FROM books b
+					JOIN categories c ON c.id = b.id
+					JOIN authors a USING (id)
+				}
+			`,
+			expectErrors:     []error{ErrAmbiguousField},
+			expectResultFile: "",
+		},
+		{
+			name: "select with join - errors when a subquery without lateral references earlier tables",
+			queries: `
+				query GetBookAuthors {
+					SELECT b.id FROM books b
+					JOIN (SELECT id FROM authors WHERE id = b.author_id) a ON true
+				}
+			`,
+			expectErrors:     []error{ErrUnknownTable},
+			expectResultFile: "",
+		},
+		{
+			name: "select with conditional join",
+			queries: `
+				query SearchBooks(authorName: string?, title: string?) {
+					SELECT b.id, b.title FROM books b
+					{if authorName IS NOT NULL}
+						JOIN authors a ON a.id = b.author_id
+					{end}
+					WHERE b.title = {title}
+						AND a.first_name = {authorName}
+						AND {if authorName IS NOT NULL} a.active = TRUE {end}
+					ORDER BY b.id
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_conditional_join.go",
+		},
+		{
+			name: "select with conditional join - errors when selected unconditionally",
+			queries: `
+				query SearchBooks(authorName: string?) {
+					SELECT b.id, a.first_name FROM books b
+					{if authorName IS NOT NULL}
+						JOIN authors a ON a.id = b.author_id
+					{end}
+				}
+			`,
+			expectErrors:     []error{ErrConditionalJoin},
+			expectResultFile: "",
+		},
+		{
+			name: "select with conditional join - errors when a clause isn't guarded by the condition",
+			queries: `
+				query SearchBooks(authorName: string?, title: string?) {
+					SELECT b.id FROM books b
+					{if authorName IS NOT NULL}
+						JOIN authors a ON a.id = b.author_id
+					{end}
+					WHERE a.active = TRUE
+						AND a.last_name = {title}
+						AND {if title IS NOT NULL} a.first_name = 'Ann' {end}
+				}
+			`,
+			expectErrors:     []error{ErrConditionalJoin, ErrConditionalJoin, ErrConditionalJoin},
+			expectResultFile: "",
+		},
+		{
+			name: "select with nullable expressions of primary keys",
+			queries: `
+				query GetAuthorNullifID {
+					SELECT nullif(id, 5) AS maybe_id FROM authors
+				}
+				query GetAuthorIDRange {
+					SELECT max(id) AS max_id, min(id) AS min_id FROM authors
+				}
+				query GetAuthorPreviousID {
+					SELECT id, lag(id) OVER (ORDER BY id) AS previous_id FROM authors
+				}
+				query GetAuthorFirstBookID {
+					SELECT a.id, (SELECT b.id FROM books b WHERE b.author_id = a.id LIMIT 1) AS first_book_id
+					FROM authors a
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_nullable_expressions.go",
+		},
+		{
+			name: "select with outer joins",
+			queries: `
+				query GetAuthorsWithBooks {
+					SELECT a.id, a.first_name, b.id AS book_id, b.title
+					FROM authors a LEFT JOIN books b ON b.author_id = a.id
+				}
+				query GetBooksWithAuthors {
+					SELECT a.id AS author_id, a.first_name, b.id, b.title
+					FROM authors a RIGHT JOIN books b ON b.author_id = a.id
+				}
+				query GetAuthorsAndBooks {
+					SELECT a.first_name, b.title
+					FROM authors a FULL JOIN books b ON b.author_id = a.id
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_outer_join.go",
+		},
+		{
+			name: "select with self join",
+			queries: `
+				query GetAuthorNamesakes {
+					SELECT a.id, a.first_name, namesake.id AS namesake_id
+					FROM authors a
+					JOIN authors namesake ON namesake.first_name = a.first_name AND namesake.id != a.id
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_self_join.go",
+		},
+		{
+			name: "select with self join - errors with duplicate column names",
+			queries: `
+				query GetAuthorNamesakes {
+					SELECT a.id, namesake.id, a.first_name FROM authors a
+					JOIN authors namesake ON namesake.first_name = a.first_name
+				}
+				query GetAuthorBooks {
+					SELECT * FROM authors a JOIN books b ON b.author_id = a.id
+				}
+			`,
+			expectErrors:     []error{ErrMissingAlias, ErrMissingAlias},
+			expectResultFile: "",
+		},
+		{
+			name: "select with conditional join - errors with invalid {if} conditions",
+			queries: `
+				query SearchBooks(authorName: string?, authorIDs: [int]) {
+					SELECT b.id FROM books b
+					{if authorName}
+						JOIN authors a ON a.id = b.author_id
+					{end}
+					{if authorIDs IS NOT NULL}
+						JOIN authors a2 ON a2.id = b.author_id
+					{end}
+				}
+			`,
+			expectErrors:     []error{ErrInvalidOperand, ErrInvalidListParam},
+			expectResultFile: "",
+		},
+		{
+			name: "select with conditional join - errors when referenced by a correlated subquery",
+			queries: `
+				query SearchBooks(authorName: string?) {
+					SELECT b.id FROM books b
+					{if authorName IS NOT NULL}
+						JOIN authors a ON a.id = b.author_id
+					{end}
+					WHERE EXISTS (SELECT 1 one FROM books other WHERE other.author_id = a.id AND other.id != b.id)
+						AND {if authorName IS NOT NULL} b.id IN (SELECT id FROM books WHERE author_id = a.id) {end}
+				}
+			`,
+			expectErrors:     []error{ErrConditionalJoin},
+			expectResultFile: "",
+		},
+		{
+			name: "select with conditional join - errors when referenced by a lateral join",
+			queries: `
+				query SearchBooks(authorName: string?) {
+					SELECT b.id FROM books b
+					{if authorName IS NOT NULL}
+						JOIN authors a ON a.id = b.author_id
+						JOIN LATERAL (SELECT title FROM books WHERE author_id = a.id LIMIT 1) guarded ON TRUE
+					{end}
+					LEFT JOIN LATERAL (SELECT title FROM books WHERE author_id = a.id LIMIT 1) latest ON TRUE
+				}
+			`,
+			expectErrors:     []error{ErrConditionalJoin},
+			expectResultFile: "",
+		},
+		{
+			name: "select for update",
+			queries: `
+				query LockBooks(authorID: int, skipLocked: bool) {
+					SELECT b.id, b.title FROM books b
+					JOIN authors a ON a.id = b.author_id
+					WHERE b.author_id = {authorID}
+					ORDER BY b.id
+					LIMIT 10
+					FOR NO KEY UPDATE OF b {if skipLocked} SKIP LOCKED {end}
+					FOR KEY SHARE OF a NOWAIT
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_for_update.go",
+		},
+		{
+			name: "select for update - errors",
+			queries: `
+				query LockBooks(skipLocked: string) {
+					SELECT DISTINCT b.id FROM books b
+					FOR UPDATE OF books {if skipLocked} SKIP LOCKED {end}
+				}
+			`,
+			expectErrors:     []error{ErrInvalidLock, ErrUnknownTable, ErrInvalidOperand},
+			expectResultFile: "",
+		},
+		{
+			name: "select for update - errors when locking a conditional join",
+			queries: `
+				query LockBooks(authorName: string?) {
+					SELECT b.id FROM books b
+					{if authorName IS NOT NULL}
+						JOIN authors a ON a.id = b.author_id AND a.first_name = {authorName}
+					{end}
+					FOR UPDATE OF b, a
+				}
+			`,
+			expectErrors:     []error{ErrConditionalJoin},
+			expectResultFile: "",
+		},
+		{
+			name: "select for share - errors with aggregates",
+			queries: `
+				query CountBooks() {
+					SELECT count(*) FROM books FOR SHARE
+				}
+			`,
+			expectErrors:     []error{ErrInvalidLock},
+			expectResultFile: "",
+		},
+		{
+			name: "select for update - errors when locking the nullable side of an outer join",
+			queries: `
+				query LockAuthorBooks() {
+					SELECT a.id, b.title FROM authors a LEFT JOIN books b ON b.author_id = a.id
+					FOR UPDATE
+				}
+				query LockBookAuthors() {
+					SELECT a.id, b.title FROM authors a RIGHT JOIN books b ON b.author_id = a.id
+					FOR SHARE OF a
+				}
+				query LockAuthors() {
+					SELECT a.id, b.title FROM authors a LEFT JOIN books b ON b.author_id = a.id
+					FOR UPDATE OF a
+				}
+			`,
+			expectErrors:     []error{ErrInvalidLock, ErrInvalidLock},
+			expectResultFile: "",
+		},
+		{
+			name: "insert with on conflict do update",
+			queries: `
+				query UpsertBook(authorID: int, title: string) {
+					INSERT INTO books (author_id, title) VALUES ({authorID}, {title})
+					ON CONFLICT (title, author_id) DO UPDATE SET title = EXCLUDED.title || ' (updated)'
+					WHERE books.title != EXCLUDED.title
+					RETURNING id, title
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_insert_on_conflict.go",
+		},
+		{
+			name: "insert with on conflict do nothing",
+			queries: `
+				query CreateAuthor(firstName: string, lastName: string, alias: string, bio: string?) {
+					INSERT INTO authors (first_name, last_name, alias, bio, active)
+					VALUES ({firstName}, {lastName}, {alias}, {bio}, TRUE)
+					ON CONFLICT (alias) DO NOTHING
+					RETURNING id
+				}
+			`,
+			expectErrors: nil,
+			expectResult: `package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type CreateAuthorInput struct {
+	firstName string
+	lastName  string
+	alias     string
+	bio       *string
+}
+
+type CreateAuthorRow struct {
+	id int64
+}
+
+func QueryCreateAuthor(input CreateAuthorInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	lit1 := fmt.Sprintf("$%d", argIndex)
+	args = append(args, input.firstName)
+	argIndex++
+	lit2 := fmt.Sprintf("$%d", argIndex)
+	args = append(args, input.lastName)
+	argIndex++
+	lit3 := fmt.Sprintf("$%d", argIndex)
+	args = append(args, input.alias)
+	argIndex++
+	lit4 := fmt.Sprintf("$%d", argIndex)
+	args = append(args, input.bio)
+	argIndex++
+	lit5 := "TRUE"
+	sb.WriteString(fmt.Sprintf("INSERT INTO authors (first_name, last_name, alias, bio, active) VALUES (%s, %s, %s, %s, %s)", lit1, lit2, lit3, lit4, lit5))
+
+	sb.WriteString(" ON CONFLICT (alias) DO NOTHING")
+
+	sb.WriteString(" RETURNING id")
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
+`,
+		},
+		{
+			name: "insert with on conflict - errors",
+			queries: `
+				query UpsertBook(title: string) {
+					INSERT INTO books (title, missing) VALUES ({title})
+					ON CONFLICT (title) DO UPDATE SET title = EXCLUDED.missing
+				}
+			`,
+			expectErrors:     []error{ErrUnknownField, ErrColumnCountMismatch, ErrInvalidConflictTarget, ErrUnknownField},
+			expectResultFile: "",
+		},
+		{
+			name: "insert with on conflict - do update needs a target",
+			queries: `
+				query UpsertBook(title: string) {
+					INSERT INTO books (author_id, title) VALUES (1, {title})
+					ON CONFLICT DO UPDATE SET title = {title}
+				}
+			`,
+			expectErrors:     []error{ErrInvalidConflictTarget},
+			expectResultFile: "",
+		},
+		{
+			name: "bulk insert",
+			queries: `
+				query CreateAuthors(rows: [AuthorInsert]) {
+					INSERT INTO authors (first_name, last_name, alias, bio, active) VALUES
+					{foreach row in rows: ,}
+						({row.firstName}, {row.lastName}, lower({row.firstName}), {row.bio}, TRUE)
+					{end}
+					ON CONFLICT (alias) DO NOTHING
+					RETURNING id
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_insert_bulk.go",
+		},
+		{
+			name: "bulk insert with fields in expressions",
+			queries: `
+				query CreateAuthorNames(rows: [AuthorName]) {
+					INSERT INTO authors (first_name, last_name, alias) VALUES
+					{foreach row in rows: ,}
+						(initcap({row.name}), upper({row.name}), lower({row.alias}))
+					{end}
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_insert_bulk_expressions.go",
+		},
+		{
+			name: "bulk insert - errors",
+			queries: `
+				query CreateAuthors(rows: [AuthorInsert], names: [string]) {
+					INSERT INTO authors (first_name, last_name) VALUES
+					{foreach row in rows: ,}
+						(lower({row.nickname}), {row})
+					{end}
+					ON CONFLICT (alias) DO UPDATE SET bio = {row.bio}
+				}
+				query CreateAuthorNames(names: [string]) {
+					INSERT INTO authors (first_name) VALUES
+					{foreach name in names: ,}
+						({name})
+					{end}
+				}
+			`,
+			expectErrors:     []error{ErrInvalidOperand, ErrInvalidOperand, ErrInvalidListParam},
+			expectResultFile: "",
+		},
+		{
+			name: "copy",
+			queries: `
+				copy LoadAuthors INTO authors (first_name, last_name, alias, bio, active)
+				copy LoadBooks INTO books (author_id, title)
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_copy.go",
+		},
+		{
+			name: "copy - errors",
+			queries: `
+				copy LoadAuthors INTO authors (first_name, nickname)
+				copy LoadReviews INTO reviews
+			`,
+			expectErrors:     []error{ErrUnknownField, ErrUnknownTable},
+			expectResultFile: "",
+		},
+		{
+			name: "struct params",
+			queries: `
+				type AuthorFilter {
+					name: string?,
+					minID: int?,
+					active: bool
+				}
+
+				query FilterAuthors(filter: AuthorFilter, exclude: AuthorFilter?) {
+					SELECT id FROM authors
+					WHERE first_name = {filter.name}
+						AND active = {filter.active}
+						AND {if filter.minID IS NOT NULL} id >= {filter.minID} {end}
+						AND last_name != {exclude.name}
+						AND {if exclude.name IS NULL} active != {exclude.active} {end}
+					ORDER BY id
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_struct_params.go",
+		},
+		{
+			name: "struct params - errors",
+			queries: `
+				type AuthorFilter {
+					names: [string],
+					since: Timestamp
+				}
+				type AuthorFilter { name: string? }
+				type Empty {}
+
+				query FilterAuthors(filter: AuthorFilter) {
+					SELECT id FROM authors WHERE first_name = {filter.nickname} OR {filter}
+				}
+				query CreateAuthor(filter: AuthorFilter?) {
+					INSERT INTO authors (first_name) VALUES ({filter.since})
+				}
+			`,
+			expectErrors:     []error{ErrInvalidTypeDef, ErrUnknownType, ErrInvalidTypeDef, ErrInvalidTypeDef, ErrUnknownParam, ErrInvalidOperand, ErrInvalidOperand},
+			expectResultFile: "",
+		},
+		{
+			name: "struct params - errors with optional struct fields in field lists",
+			queries: `
+				type AuthorFilter { name: string }
+
+				query FilterAuthors(filter: AuthorFilter?) {
+					SELECT id, coalesce({filter.name}, first_name) AS name,
+						rank() OVER (PARTITION BY coalesce({filter.name}, last_name) ORDER BY id) AS position
+					FROM authors
+					ORDER BY coalesce({filter.name}, last_name)
+				}
+				query CreateAuthor(filter: AuthorFilter?) {
+					INSERT INTO authors (first_name) VALUES ('Ann')
+					RETURNING id, {filter.name} AS name
+				}
+			`,
+			expectErrors:     []error{ErrInvalidOperand, ErrInvalidOperand, ErrInvalidOperand, ErrInvalidOperand},
+			expectResultFile: "",
+		},
+		{
+			name: "select with if statement - flag conditions",
+			queries: `
+				query GetAuthorsWithFlags(includeInactive: bool, onlyWithBio: bool, name: string?) {
+					SELECT id FROM authors
+					WHERE {if NOT includeInactive} active = TRUE {end}
+						AND {if onlyWithBio AND (name IS NULL OR NOT includeInactive)} bio IS NOT NULL {end}
+						AND {if name IS NOT NULL} first_name = {name} {else if includeInactive} last_name IS NOT NULL {end}
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_if_statement_flags.go",
+		},
+		{
+			name: "select with if statement - flag condition errors",
+			queries: `
+				query GetAuthorsWithFlags(includeInactive: bool?, name: string) {
+					SELECT id FROM authors
+					WHERE {if includeInactive} active = TRUE {end}
+						AND {if NOT name} bio IS NOT NULL {end}
+						AND {if name = 'Ann' OR 1} first_name = {name} {end}
+				}
+			`,
+			expectErrors:     []error{ErrInvalidOperand, ErrInvalidOperand, ErrInvalidOperand},
+			expectResultFile: "",
+		},
+		{
+			name: "select with if statement - list conditions",
+			queries: `
+				query GetAuthorsByIDs(ids: [int], excludeIDs: [int], names: [string]) {
+					SELECT id FROM authors
+					WHERE {if len(ids) > 0} id IN {ids} {end}
+						AND {if excludeIDs IS NOT EMPTY} id NOT IN {excludeIDs} {end}
+						AND ({foreach name in names: OR} first_name = {name} {end})
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_if_statement_lists.go",
+		},
+		{
+			name: "select with if statement - list condition errors",
+			queries: `
+				query GetAuthorsByIDs(ids: [int], name: string) {
+					SELECT id FROM authors
+					WHERE {if len(name) > 0} first_name = {name} {end}
+						AND {if name IS EMPTY} bio IS NULL {end}
+						AND {if len(ids)} id IN {ids} {end}
+				}
+			`,
+			expectErrors:     []error{ErrInvalidListParam, ErrInvalidListParam, ErrInvalidOperand},
+			expectResultFile: "",
+		},
+		{
+			name: "select with optional list params",
+			queries: `
+				query GetAuthorsByOptionalLists(ids: [int]?, names: [string]?) {
+					SELECT id FROM authors
+					WHERE id IN {ids}
+						AND ({foreach name in names: AND} first_name != {name} {end})
+						AND {if names IS NULL} bio IS NOT NULL {end}
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_optional_lists.go",
+		},
+		{
+			name: "select with optional list params - errors",
+			queries: `
+				fragment IDsFragment(ids: [int]) {
+					id IN {ids}
+				}
+
+				query GetAuthorsByOptionalLists(ids: [int]?, names: [string]) {
+					SELECT id FROM authors
+					WHERE {include IDsFragment(ids)}
+						AND {if names IS NULL} bio IS NULL {end}
+						AND {ids} IS NULL
+				}
+			`,
+			expectErrors:     []error{ErrFragmentParamMismatch, ErrInvalidListParam, ErrInvalidListParam},
+			expectResultFile: "",
+		},
+		{
+			name: "select with arithmetic - nested negation",
+			queries: `
+				query GetAuthorNegation(offset: int) {
+					SELECT id FROM authors
+					WHERE id = - -{offset} OR id = - - -5 OR -(-id) > 1
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_negation.go",
+		},
+		{
+			name: "select with arithmetic - optional params in if conditions",
+			queries: `
+				query GetAuthorIfArithmetic(minID: int?, offset: int) {
+					SELECT id FROM authors
+					WHERE {if minID + offset > 5 OR -minID < 0} id > {offset} {end}
+				}
+			`,
+			expectErrors:     nil,
+			expectResultFile: "tests_sample_select_if_arithmetic.go",
+		},
+		{
+			name: "bulk insert - errors when queries infer different fields for a type",
+			queries: `
+				query CreateAuthors(rows: [AuthorInsert]) {
+					INSERT INTO authors (first_name, last_name, alias) VALUES
+					{foreach row in rows: ,}
+						({row.firstName}, {row.lastName}, {row.alias})
+					{end}
+				}
+				query CreateMoreAuthors(rows: [AuthorInsert]) {
+					INSERT INTO authors (first_name, last_name, alias) VALUES
+					{foreach row in rows: ,}
+						({row.firstName}, {row.lastName}, {row.alias})
+					{end}
+				}
+				query CreateAuthorBios(rows: [AuthorInsert]) {
+					INSERT INTO authors (first_name, last_name, alias, bio) VALUES
+					{foreach row in rows: ,}
+						({row.firstName}, {row.lastName}, {row.alias}, {row.bio})
+					{end}
+				}
+			`,
+			expectErrors:     []error{ErrInvalidTypeDef},
+			expectResultFile: "",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+
+			schemaParser := NewSchemaParser(schema)
+			schemaParser.Parse()
+
+			queryParser := NewQueryParser(test.queries)
+			queryParser.Parse()
+
+			checkErrors := CheckQueries(schemaParser.Result, queryParser.Result, test.types)
+			if len(test.expectErrors) != len(checkErrors) {
+				t.Log(test.expectErrors, checkErrors)
+				t.Fatalf("expected %d errors, got %d", len(test.expectErrors), len(checkErrors))
+			} else {
+				for i := range checkErrors {
+					if !errors.Is(checkErrors[i].Err, test.expectErrors[i]) {
+						t.Fatalf("expected error: %s, got: %s", test.expectErrors[i], checkErrors[i].Err)
+					}
+					if test.expectErrorLines != nil && checkErrors[i].Line != test.expectErrorLines[i] {
+						t.Fatalf("expected error on line %d, got: %s", test.expectErrorLines[i], checkErrors[i].Error())
+					}
+				}
+			}
+
+			if len(checkErrors) == 0 {
+				generated, err := Generate(schemaParser.Result, queryParser.Result, Config{OutputPackage: "main", Types: test.types, InListStyle: test.inListStyle})
+				if err != nil {
+					// allow continuing in case it's an error while formatting
+					t.Errorf("got error: %s", err)
+				}
+
+				expectedResult := test.expectResult
+
+				if test.expectResultFile != "" {
+					file, err := os.ReadFile(test.expectResultFile)
+					if err != nil {
+						t.Fatalf("error reading file: %s", err)
+					}
+					expectedResult = string(file)
+				}
+
+				expectedResultFormatted, err := format.Source([]byte(expectedResult))
+				if err != nil {
+					t.Fatalf("error formatting: %s", err)
+				}
+				expectedResult = string(expectedResultFormatted)
+
+				if generated != expectedResult {
+					_ = os.WriteFile("testfailure/expected.go", []byte(expectedResult), 0644)
+					_ = os.WriteFile("testfailure/got.go", []byte(generated), 0644)
+					t.Errorf("generated output mismatch")
+					t.Log("expected:\n\n", expectedResult)
+					t.Log("got:\n\n", generated)
+				}
+			}
+		})
+	}
+}
+
+func ptr[T any](in T) *T {
+	return &in
+}
+
+func assertQuery(t *testing.T, expectQuery string, expectArgs []interface{}, gotQuery string, gotArgs []interface{}) {
+	if expectQuery != gotQuery {
+		t.Fatalf("\nexpected qry: %s\ngot:          %s", expectQuery, gotQuery)
+	}
+
+	if len(expectArgs) != len(gotArgs) {
+		t.Fatalf("expected %d args, got %d", len(expectArgs), len(gotArgs))
+	} else {
+		for i := range expectArgs {
+			if expectArgs[i] != gotArgs[i] {
+				t.Fatalf("\nexpected arg: %s\ngot:          %s", expectArgs[i], gotArgs[i])
+			}
+		}
+	}
+}
+
+// test the generated output with various inputs to
+// ensure they produce expected queries
+func TestGeneratedSelects(t *testing.T) {
+	t.Run("simple select", func(t *testing.T) {
+		query, args := QueryGetAuthorSimpleSelect()
+		assertQuery(t,
+			"SELECT id FROM authors WHERE id = 5;",
+			[]interface{}{},
+			query,
+			args,
+		)
+	})
+
+	t.Run("simple select - table and column alias", func(t *testing.T) {
+		query, args := QueryGetAuthorSimpleSelectAlias()
+		assertQuery(t,
+			"SELECT id my_id FROM authors my_authors WHERE my_authors.id = 5 OR id = 5;",
+			[]interface{}{},
+			query,
+			args,
+		)
+	})
+
+	t.Run("select with variable", func(t *testing.T) {
+		query, args := QueryGetAuthorWithVariable(GetAuthorWithVariableInput{id: "32"})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE id = $1 LIMIT 1;",
+			[]interface{}{"32"},
+			query,
+			args,
+		)
+	})
+	t.Run("select with variable - no input", func(t *testing.T) {
+		// todo: consider if this should error instead
+		query, args := QueryGetAuthorWithVariable(GetAuthorWithVariableInput{})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE id = $1 LIMIT 1;",
+			[]interface{}{""},
+			query,
+			args,
+		)
+	})
+
+	t.Run("select optional where - nil parameter", func(t *testing.T) {
+		query, args := QueryGetAuthorOptionalWhere(GetAuthorOptionalWhereInput{})
+		assertQuery(t,
+			"SELECT id FROM authors LIMIT 1;",
+			[]interface{}{},
+			query,
+			args,
+		)
+	})
+
+	t.Run("select optional where - non-nil parameter", func(t *testing.T) {
+		query, args := QueryGetAuthorOptionalWhere(GetAuthorOptionalWhereInput{id: ptr("23")})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE id = $1 LIMIT 1;",
+			[]interface{}{"23"},
+			query,
+			args,
+		)
+	})
+
+	t.Run("select optional where or - nil parameters", func(t *testing.T) {
+		query, args := QueryGetAuthorOptionalWhereOr(GetAuthorOptionalWhereOrInput{})
+		assertQuery(t,
+			"SELECT id FROM authors LIMIT 1;",
+			[]interface{}{},
+			query,
+			args,
+		)
+	})
+	t.Run("select optional where or - non-nil", func(t *testing.T) {
+		query, args := QueryGetAuthorOptionalWhereOr(GetAuthorOptionalWhereOrInput{id: ptr("8"), id2: ptr("9")})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE id = $1 OR id = $2 LIMIT 1;",
+			[]interface{}{"8", "9"},
+			query,
+			args,
+		)
+	})
+	t.Run("select optional where or - id2 is nil", func(t *testing.T) {
+		query, args := QueryGetAuthorOptionalWhereOr(GetAuthorOptionalWhereOrInput{id: ptr("8"), id2: nil})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE id = $1 LIMIT 1;",
+			[]interface{}{"8"},
+			query,
+			args,
+		)
+	})
+	t.Run("select optional where or - id1 is nil", func(t *testing.T) {
+		query, args := QueryGetAuthorOptionalWhereOr(GetAuthorOptionalWhereOrInput{id: nil, id2: ptr("9")})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE id = $1 LIMIT 1;",
+			[]interface{}{"9"},
+			query,
+			args,
+		)
+	})
+
+	t.Run("select with loop", func(t *testing.T) {
+		query, args := QueryGetAuthorForLoop(GetAuthorForLoopInput{bioLike: "bio", queries: []string{"foo", "bar"}, bioOptional: ptr("opt")})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE bio LIKE $1 AND (((((bio LIKE $2 OR first_name LIKE $3) OR last_name LIKE $4) OR alias LIKE $5) AND (((bio LIKE $6 OR first_name LIKE $7) OR last_name LIKE $8) OR alias LIKE $9)) AND bio LIKE $10) LIMIT 1;",
+			[]interface{}{"bio", "foo", "foo", "foo", "foo", "bar", "bar", "bar", "bar", "opt"},
+			query,
+			args,
+		)
+	})
+	t.Run("select with loop - nil arg", func(t *testing.T) {
+		query, args := QueryGetAuthorForLoop(GetAuthorForLoopInput{bioLike: "bio", queries: []string{"foo", "bar"}, bioOptional: nil})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE bio LIKE $1 AND (((((bio LIKE $2 OR first_name LIKE $3) OR last_name LIKE $4) OR alias LIKE $5) AND (((bio LIKE $6 OR first_name LIKE $7) OR last_name LIKE $8) OR alias LIKE $9))) LIMIT 1;",
+			[]interface{}{"bio", "foo", "foo", "foo", "foo", "bar", "bar", "bar", "bar"},
+			query,
+			args,
+		)
+	})
+	t.Run("select with loop - nil arg and one item in list", func(t *testing.T) {
+		query, args := QueryGetAuthorForLoop(GetAuthorForLoopInput{bioLike: "bio", queries: []string{"foo"}, bioOptional: nil})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE bio LIKE $1 AND (((((bio LIKE $2 OR first_name LIKE $3) OR last_name LIKE $4) OR alias LIKE $5))) LIMIT 1;",
+			[]interface{}{"bio", "foo", "foo", "foo", "foo"},
+			query,
+			args,
+		)
+	})
+	t.Run("select with loop - nil arg and empty list", func(t *testing.T) {
+		query, args := QueryGetAuthorForLoop(GetAuthorForLoopInput{bioLike: "bio", queries: []string{}, bioOptional: nil})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE bio LIKE $1 LIMIT 1;",
+			[]interface{}{"bio"},
+			query,
+			args,
+		)
+	})
+
+	t.Run("select with fragment - optional provided", func(t *testing.T) {
+		query, args := QueryGetAuthorWithFragment(GetAuthorWithFragmentInput{bioLike: "bio", bioLikeOptional: ptr("bio2")})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE id = 1 AND (bio LIKE $1 OR bio LIKE $2) LIMIT 1;",
+			[]interface{}{"bio", "bio2"},
+			query,
+			args,
+		)
+	})
+	t.Run("select with fragment - optional not provided", func(t *testing.T) {
+		query, args := QueryGetAuthorWithFragment(GetAuthorWithFragmentInput{bioLike: "bio", bioLikeOptional: nil})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE id = 1 AND (bio LIKE $1) LIMIT 1;",
+			[]interface{}{"bio"},
+			query,
+			args,
+		)
+	})
+
+	// query GetAuthorIfStatement(bioOptional: string?) {
+	// 	SELECT id FROM authors
+	// 		WHERE
+	// 			{if bioOptional == nil}
+	// 			bio IS NULL
+	// 			{else if bioOptional == "specialValue"}
+	// 			{else}
+	// 			bio = {bioLike}
+	// 			{end}
+	// }
+	t.Run("select with if statement equality - is null check", func(t *testing.T) {
+		query, args := QueryGetAuthorIfStatement(GetAuthorIfStatementInput{bioOptional: nil})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE bio IS NULL;",
+			[]interface{}{},
+			query,
+			args,
+		)
+	})
+	t.Run("select with if statement equality - empty statement", func(t *testing.T) {
+		query, args := QueryGetAuthorIfStatement(GetAuthorIfStatementInput{bioOptional: ptr("specialValue")})
+		assertQuery(t,
+			"SELECT id FROM authors;",
+			[]interface{}{},
+			query,
+			args,
+		)
+	})
+	t.Run("select with if statement equality - any other value", func(t *testing.T) {
+		query, args := QueryGetAuthorIfStatement(GetAuthorIfStatementInput{bioOptional: ptr("bio")})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE bio = $1;",
+			[]interface{}{"bio"},
+			query,
+			args,
+		)
+	})
+
+	// query GetAuthorIfStatementMultipleJoined(bioOptional: string?, id: int?) {
+	// 	SELECT id FROM authors
+	// 	WHERE
+	// 		{if id is NULL}
+	// 			id IS NULL
+	// 		{end}
+
+	// 		AND
+
+	// 		{if bioOptional IS NULL}
+	// 			bio IS NULL
+	// 		{else if bioOptional = "specialValue"}
+	// 		{else}
+	// 			bio = {bioOptional}
+	// 		{end}
+	// }
+	t.Run("select with if statement equality - is null check", func(t *testing.T) {
+		query, args := QueryGetAuthorIfStatementMultipleJoined(GetAuthorIfStatementMultipleJoinedInput{bioOptional: nil, id: nil})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE id IS NULL AND bio IS NULL;",
+			[]interface{}{},
+			query,
+			args,
+		)
+	})
+	t.Run("select with if statement equality - empty statement", func(t *testing.T) {
+		query, args := QueryGetAuthorIfStatementMultipleJoined(GetAuthorIfStatementMultipleJoinedInput{bioOptional: ptr("specialValue"), id: nil})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE id IS NULL;",
+			[]interface{}{},
+			query,
+			args,
+		)
+	})
+	t.Run("select with if statement equality - multiple empty statements", func(t *testing.T) {
+		query, args := QueryGetAuthorIfStatementMultipleJoined(GetAuthorIfStatementMultipleJoinedInput{bioOptional: ptr("specialValue"), id: ptr(3)})
+		assertQuery(t,
+			"SELECT id FROM authors;",
+			[]interface{}{},
+			query,
+			args,
+		)
+	})
+	t.Run("select with if statement equality - empty ID check", func(t *testing.T) {
+		query, args := QueryGetAuthorIfStatementMultipleJoined(GetAuthorIfStatementMultipleJoinedInput{bioOptional: ptr("bio"), id: ptr(3)})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE bio = $1;",
+			[]interface{}{"bio"},
+			query,
+			args,
+		)
+	})
+
+	t.Run("select with in list", func(t *testing.T) {
+		query, args := QueryGetAuthorInList(GetAuthorInListInput{ids: []int{1, 2}, excludeNames: []string{"foo"}})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE id IN ($1, $2) AND first_name NOT IN ($3);",
+			[]interface{}{1, 2, "foo"},
+			query,
+			args,
+		)
+	})
+	t.Run("select with in list - empty lists", func(t *testing.T) {
+		query, args := QueryGetAuthorInList(GetAuthorInListInput{})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE FALSE AND TRUE;",
+			[]interface{}{},
+			query,
+			args,
+		)
+	})
+
+	t.Run("select with predicates - nil params", func(t *testing.T) {
+		query, args := QueryGetAuthorPredicates(GetAuthorPredicatesInput{})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE (active IS NOT FALSE) AND NOT ((alias NOT SIMILAR TO 'x%'));",
+			[]interface{}{},
+			query,
+			args,
+		)
+	})
+	t.Run("select with predicates - partial between", func(t *testing.T) {
+		query, args := QueryGetAuthorPredicates(GetAuthorPredicatesInput{minID: ptr(1), nameLike: ptr("a%")})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE ((((first_name ILIKE $1))) AND active IS NOT FALSE) AND NOT ((alias NOT ILIKE $2 OR alias NOT SIMILAR TO 'x%'));",
+			[]interface{}{"a%", "a%"},
+			query,
+			args,
+		)
+	})
+	t.Run("select with predicates - all params", func(t *testing.T) {
+		query, args := QueryGetAuthorPredicates(GetAuthorPredicatesInput{minID: ptr(1), maxID: ptr(9), nameLike: ptr("a%"), bioPattern: ptr("b%"), otherBio: ptr("c")})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE ((((id BETWEEN $1 AND $2 AND first_name ILIKE $3) AND bio SIMILAR TO $4) AND bio IS DISTINCT FROM $5) AND active IS NOT FALSE) AND NOT ((alias NOT ILIKE $6 OR alias NOT SIMILAR TO 'x%'));",
+			[]interface{}{1, 9, "a%", "b%", "c", "a%"},
+			query,
+			args,
+		)
+	})
+
+	t.Run("select with arithmetic - nil params", func(t *testing.T) {
+		query, args := QueryGetAuthorArithmetic(GetAuthorArithmeticInput{offset: 2})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE ((((id + $1) * 3 % 7 = -id)));",
+			[]interface{}{2},
+			query,
+			args,
+		)
+	})
+	t.Run("select with arithmetic - all params", func(t *testing.T) {
+		query, args := QueryGetAuthorArithmetic(GetAuthorArithmeticInput{minID: ptr(4), offset: 6, name: ptr("a b")})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE (((id * 2 + 1 > $1 AND (id + $2) * 3 % 7 = -id) AND first_name || ' ' || last_name = $3) AND id - (id - $4) = 1) AND id > $5;",
+			[]interface{}{4, 6, "a b", 4, 6},
+			query,
+			args,
+		)
+	})
+
+	t.Run("select with arithmetic - nested negation", func(t *testing.T) {
+		query, args := QueryGetAuthorNegation(GetAuthorNegationInput{offset: 2})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE (id = -(-$1) OR id = -(-(-5))) OR -(-id) > 1;",
+			[]interface{}{2},
+			query,
+			args,
+		)
+	})
+
+	t.Run("select with arithmetic - nil param in if condition", func(t *testing.T) {
+		query, args := QueryGetAuthorIfArithmetic(GetAuthorIfArithmeticInput{offset: 10})
+		assertQuery(t,
+			"SELECT id FROM authors;",
+			[]interface{}{},
+			query,
+			args,
+		)
+	})
+
+	t.Run("select with arithmetic - param in if condition", func(t *testing.T) {
+		query, args := QueryGetAuthorIfArithmetic(GetAuthorIfArithmeticInput{minID: ptr(-2), offset: 10})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE id > $1;",
+			[]interface{}{10},
+			query,
+			args,
+		)
+	})
+
+	t.Run("select with functions - nil params", func(t *testing.T) {
+		query, args := QueryGetAuthorFunctions(GetAuthorFunctionsInput{})
+		assertQuery(t,
+			"SELECT id, lower(first_name), coalesce(bio, $1) bio, CASE WHEN id > 5 THEN 'big' ELSE 'small' END size, CASE active WHEN TRUE THEN 1 ELSE 0 END active_flag FROM authors WHERE length(coalesce(bio, '')) >= 0;",
+			[]interface{}{(*string)(nil)},
+			query,
+			args,
+		)
+	})
+	t.Run("select with functions - all params", func(t *testing.T) {
+		fallback := ptr("none")
+		query, args := QueryGetAuthorFunctions(GetAuthorFunctionsInput{name: ptr("Ann"), fallback: fallback})
+		assertQuery(t,
+			"SELECT id, lower(first_name), coalesce(bio, $1) bio, CASE WHEN id > 5 THEN 'big' ELSE 'small' END size, CASE active WHEN TRUE THEN 1 ELSE 0 END active_flag FROM authors WHERE lower(first_name) = lower($2) AND length(coalesce(bio, '')) >= 0;",
+			[]interface{}{fallback, "Ann"},
+			query,
+			args,
+		)
+	})
+
+	t.Run("select with casts - nil params", func(t *testing.T) {
+		query, args := QueryGetAuthorCasts(GetAuthorCastsInput{name: "Ann"})
+		assertQuery(t,
+			"SELECT id::text id_text, CAST(bio AS varchar(255)), (id + 1)::double precision next_id FROM authors WHERE (first_name = CAST($1 AS text)) AND '5'::int + id > 6;",
+			[]interface{}{"Ann"},
+			query,
 			args,
 		)
 	})
-	t.Run("select optional where or - non-nil", func(t *testing.T) {
-		query, args := QueryGetAuthorOptionalWhereOr(GetAuthorOptionalWhereOrInput{id: ptr("8"), id2: ptr("9")})
+	t.Run("select with casts - all params", func(t *testing.T) {
+		query, args := QueryGetAuthorCasts(GetAuthorCastsInput{minID: ptr("3"), name: "Ann"})
 		assertQuery(t,
-			"SELECT id FROM authors WHERE id = $1 OR id = $2 LIMIT 1;",
-			[]interface{}{"8", "9"},
+			"SELECT id::text id_text, CAST(bio AS varchar(255)), (id + 1)::double precision next_id FROM authors WHERE (id > $1::bigint AND first_name = CAST($2 AS text)) AND '5'::int + id > 6;",
+			[]interface{}{"3", "Ann"},
 			query,
 			args,
 		)
 	})
-	t.Run("select optional where or - id2 is nil", func(t *testing.T) {
-		query, args := QueryGetAuthorOptionalWhereOr(GetAuthorOptionalWhereOrInput{id: ptr("8"), id2: nil})
+
+	t.Run("select with group by - nil params", func(t *testing.T) {
+		query, args := QueryGetAuthorGroupBy(GetAuthorGroupByInput{})
 		assertQuery(t,
-			"SELECT id FROM authors WHERE id = $1 LIMIT 1;",
-			[]interface{}{"8"},
+			"SELECT first_name, count(*), count(DISTINCT last_name) last_names, sum(id) id_sum, max(bio) max_bio, lower(last_name) last_name_lower FROM authors WHERE active GROUP BY first_name, lower(last_name);",
+			[]interface{}{},
 			query,
 			args,
 		)
 	})
-	t.Run("select optional where or - id1 is nil", func(t *testing.T) {
-		query, args := QueryGetAuthorOptionalWhereOr(GetAuthorOptionalWhereOrInput{id: nil, id2: ptr("9")})
+	t.Run("select with group by - all params", func(t *testing.T) {
+		query, args := QueryGetAuthorGroupBy(GetAuthorGroupByInput{minCount: ptr(2)})
 		assertQuery(t,
-			"SELECT id FROM authors WHERE id = $1 LIMIT 1;",
-			[]interface{}{"9"},
+			"SELECT first_name, count(*), count(DISTINCT last_name) last_names, sum(id) id_sum, max(bio) max_bio, lower(last_name) last_name_lower FROM authors WHERE active GROUP BY first_name, lower(last_name) HAVING count(*) > $1;",
+			[]interface{}{2},
 			query,
 			args,
 		)
 	})
 
-	t.Run("select with loop", func(t *testing.T) {
-		query, args := QueryGetAuthorForLoop(GetAuthorForLoopInput{bioLike: "bio", queries: []string{"foo", "bar"}, bioOptional: ptr("opt")})
+	t.Run("select with order by - defaults", func(t *testing.T) {
+		query, args := QueryGetAuthorOrderBy(GetAuthorOrderByInput{})
 		assertQuery(t,
-			"SELECT id FROM authors WHERE bio LIKE $1 AND (((((bio LIKE $2 OR first_name LIKE $3) OR last_name LIKE $4) OR alias LIKE $5) AND (((bio LIKE $6 OR first_name LIKE $7) OR last_name LIKE $8) OR alias LIKE $9)) AND bio LIKE $10) LIMIT 1;",
-			[]interface{}{"bio", "foo", "foo", "foo", "foo", "bar", "bar", "bar", "bar", "opt"},
+			"SELECT id, first_name author_name FROM authors ORDER BY first_name ASC NULLS LAST, author_name, id DESC;",
+			[]interface{}{},
 			query,
 			args,
 		)
 	})
-	t.Run("select with loop - nil arg", func(t *testing.T) {
-		query, args := QueryGetAuthorForLoop(GetAuthorForLoopInput{bioLike: "bio", queries: []string{"foo", "bar"}, bioOptional: nil})
+	t.Run("select with order by - sort and direction", func(t *testing.T) {
+		query, args := QueryGetAuthorOrderBy(GetAuthorOrderByInput{name: ptr("Ann"), sort: GetAuthorOrderBySortBio, dir: GetAuthorOrderByDirDesc})
 		assertQuery(t,
-			"SELECT id FROM authors WHERE bio LIKE $1 AND (((((bio LIKE $2 OR first_name LIKE $3) OR last_name LIKE $4) OR alias LIKE $5) AND (((bio LIKE $6 OR first_name LIKE $7) OR last_name LIKE $8) OR alias LIKE $9))) LIMIT 1;",
-			[]interface{}{"bio", "foo", "foo", "foo", "foo", "bar", "bar", "bar", "bar"},
+			"SELECT id, first_name author_name FROM authors WHERE first_name = $1 ORDER BY bio DESC NULLS LAST, author_name, id DESC;",
+			[]interface{}{"Ann"},
 			query,
 			args,
 		)
 	})
-	t.Run("select with loop - nil arg and one item in list", func(t *testing.T) {
-		query, args := QueryGetAuthorForLoop(GetAuthorForLoopInput{bioLike: "bio", queries: []string{"foo"}, bioOptional: nil})
+	t.Run("select with order by - unknown sort falls back to first option", func(t *testing.T) {
+		query, args := QueryGetAuthorOrderBy(GetAuthorOrderByInput{sort: GetAuthorOrderBySort(10)})
 		assertQuery(t,
-			"SELECT id FROM authors WHERE bio LIKE $1 AND (((((bio LIKE $2 OR first_name LIKE $3) OR last_name LIKE $4) OR alias LIKE $5))) LIMIT 1;",
-			[]interface{}{"bio", "foo", "foo", "foo", "foo"},
+			"SELECT id, first_name author_name FROM authors ORDER BY first_name ASC NULLS LAST, author_name, id DESC;",
+			[]interface{}{},
 			query,
 			args,
 		)
 	})
-	t.Run("select with loop - nil arg and empty list", func(t *testing.T) {
-		query, args := QueryGetAuthorForLoop(GetAuthorForLoopInput{bioLike: "bio", queries: []string{}, bioOptional: nil})
+
+	t.Run("select with limit and offset - nil limit", func(t *testing.T) {
+		query, args := QueryGetAuthorLimit(GetAuthorLimitInput{offset: 20})
 		assertQuery(t,
-			"SELECT id FROM authors WHERE bio LIKE $1 LIMIT 1;",
-			[]interface{}{"bio"},
+			"SELECT id FROM authors ORDER BY id OFFSET $1;",
+			[]interface{}{20},
+			query,
+			args,
+		)
+	})
+	t.Run("select with limit and offset - all params", func(t *testing.T) {
+		query, args := QueryGetAuthorLimit(GetAuthorLimitInput{limit: ptr(10), offset: 20})
+		assertQuery(t,
+			"SELECT id FROM authors ORDER BY id LIMIT $1 OFFSET $2;",
+			[]interface{}{10, 20},
 			query,
 			args,
 		)
 	})
 
-	t.Run("select with fragment - optional provided", func(t *testing.T) {
-		query, args := QueryGetAuthorWithFragment(GetAuthorWithFragmentInput{bioLike: "bio", bioLikeOptional: ptr("bio2")})
+	t.Run("select with paginate - first page", func(t *testing.T) {
+		query, args := QueryGetAuthorPaginate(GetAuthorPaginateInput{pageSize: 10})
 		assertQuery(t,
-			"SELECT id FROM authors WHERE id = 1 AND (bio LIKE $1 OR bio LIKE $2) LIMIT 1;",
-			[]interface{}{"bio", "bio2"},
+			"SELECT id, first_name FROM authors ORDER BY first_name, id LIMIT $1;",
+			[]interface{}{10},
 			query,
 			args,
 		)
 	})
-	t.Run("select with fragment - optional not provided", func(t *testing.T) {
-		query, args := QueryGetAuthorWithFragment(GetAuthorWithFragmentInput{bioLike: "bio", bioLikeOptional: nil})
+	t.Run("select with paginate - next page", func(t *testing.T) {
+		encoded := EncodeGetAuthorPaginateCursor(GetAuthorPaginateRow{id: 7, first_name: "Ann"})
+		cursor, err := DecodeGetAuthorPaginateCursor(encoded)
+		if err != nil {
+			t.Fatalf("got error decoding cursor: %s", err)
+		}
+
+		query, args := QueryGetAuthorPaginate(GetAuthorPaginateInput{name: ptr("Ann"), pageSize: 10, cursor: &cursor})
 		assertQuery(t,
-			"SELECT id FROM authors WHERE id = 1 AND (bio LIKE $1) LIMIT 1;",
-			[]interface{}{"bio"},
+			"SELECT id, first_name FROM authors WHERE first_name = $1 AND (first_name, id) > ($2, $3) ORDER BY first_name, id LIMIT $4;",
+			[]interface{}{"Ann", "Ann", int64(7), 10},
 			query,
 			args,
 		)
 	})
+	t.Run("select with paginate - invalid cursor", func(t *testing.T) {
+		for _, encoded := range []string{"not base64!", "bm90IGpzb24", "WyJBbm4iXQ"} {
+			if _, err := DecodeGetAuthorPaginateCursor(encoded); err == nil {
+				t.Fatalf("expected error decoding %q", encoded)
+			}
+		}
+	})
 
-	// query GetAuthorIfStatement(bioOptional: string?) {
-	// 	SELECT id FROM authors
-	// 		WHERE
-	// 			{if bioOptional == nil}
-	// 			bio IS NULL
-	// 			{else if bioOptional == "specialValue"}
-	// 			{else}
-	// 			bio = {bioLike}
-	// 			{end}
-	// }
-	t.Run("select with if statement equality - is null check", func(t *testing.T) {
-		query, args := QueryGetAuthorIfStatement(GetAuthorIfStatementInput{bioOptional: nil})
+	t.Run("select distinct on - nil param", func(t *testing.T) {
+		query, args := QueryGetAuthorDistinctOn(GetAuthorDistinctOnInput{})
 		assertQuery(t,
-			"SELECT id FROM authors WHERE bio IS NULL;",
+			"SELECT DISTINCT ON (first_name, lower(last_name)) id, first_name, last_name FROM authors a ORDER BY lower(last_name), a.first_name, id DESC;",
 			[]interface{}{},
 			query,
 			args,
 		)
 	})
-	t.Run("select with if statement equality - empty statement", func(t *testing.T) {
-		query, args := QueryGetAuthorIfStatement(GetAuthorIfStatementInput{bioOptional: ptr("specialValue")})
+	t.Run("select distinct on - with param", func(t *testing.T) {
+		query, args := QueryGetAuthorDistinctOn(GetAuthorDistinctOnInput{name: ptr("Ann")})
 		assertQuery(t,
-			"SELECT id FROM authors;",
+			"SELECT DISTINCT ON (first_name, lower(last_name)) id, first_name, last_name FROM authors a WHERE first_name = $1 ORDER BY lower(last_name), a.first_name, id DESC;",
+			[]interface{}{"Ann"},
+			query,
+			args,
+		)
+	})
+
+	t.Run("select with cte - nil params", func(t *testing.T) {
+		query, args := QueryGetAuthorRecent(GetAuthorRecentInput{minID: 5})
+		assertQuery(t,
+			"WITH named AS (SELECT id, first_name name FROM authors), recent AS (SELECT n.id, n.name FROM named n INNER JOIN authors a ON a.id = n.id ORDER BY n.id DESC LIMIT 10) SELECT id, name FROM recent WHERE id > $1;",
+			[]interface{}{5},
+			query,
+			args,
+		)
+	})
+	t.Run("select with cte - all params", func(t *testing.T) {
+		query, args := QueryGetAuthorRecent(GetAuthorRecentInput{name: ptr("Ann"), lastName: ptr("Lee"), minID: 5})
+		assertQuery(t,
+			"WITH named AS (SELECT id, first_name name FROM authors WHERE first_name = $1), recent AS (SELECT n.id, n.name FROM named n INNER JOIN authors a ON a.id = n.id WHERE a.last_name = $2 ORDER BY n.id DESC LIMIT 10) SELECT id, name FROM recent WHERE id > $3;",
+			[]interface{}{"Ann", "Lee", 5},
+			query,
+			args,
+		)
+	})
+
+	t.Run("select with subqueries - nil params", func(t *testing.T) {
+		query, args := QueryGetCategorySubqueries(GetCategorySubqueriesInput{minID: 3})
+		assertQuery(t,
+			"SELECT p.id, (SELECT count(*) FROM categories c WHERE c.parent_id = p.id) child_count FROM categories p WHERE (EXISTS (SELECT id FROM categories c WHERE c.parent_id = p.id) AND p.parent_id IN (SELECT id FROM categories WHERE id > $1)) AND p.id NOT IN (SELECT id FROM (SELECT id, name FROM categories) named);",
+			[]interface{}{3},
+			query,
+			args,
+		)
+	})
+	t.Run("select with subqueries - all params", func(t *testing.T) {
+		query, args := QueryGetCategorySubqueries(GetCategorySubqueriesInput{childName: ptr("books"), minID: 3})
+		assertQuery(t,
+			"SELECT p.id, (SELECT count(*) FROM categories c WHERE c.parent_id = p.id AND c.name = $1) child_count FROM categories p WHERE (EXISTS (SELECT id FROM categories c WHERE c.parent_id = p.id) AND p.parent_id IN (SELECT id FROM categories WHERE id > $2)) AND p.id NOT IN (SELECT id FROM (SELECT id, name FROM categories WHERE name = $3) named);",
+			[]interface{}{"books", 3, "books"},
+			query,
+			args,
+		)
+	})
+
+	t.Run("select with union - without conditional branch", func(t *testing.T) {
+		query, args := QueryGetAuthorAndCategoryNames(GetAuthorAndCategoryNamesInput{name: ptr("Ann"), limit: 10})
+		assertQuery(t,
+			"SELECT id, first_name label FROM authors WHERE first_name = $1 EXCEPT SELECT id, last_name FROM authors ORDER BY label DESC, id LIMIT $2;",
+			[]interface{}{"Ann", 10},
+			query,
+			args,
+		)
+	})
+	t.Run("select with union - with conditional branch", func(t *testing.T) {
+		query, args := QueryGetAuthorAndCategoryNames(GetAuthorAndCategoryNamesInput{includeCategories: ptr("books"), limit: 10})
+		assertQuery(t,
+			"SELECT id, first_name label FROM authors UNION ALL SELECT id, name FROM categories WHERE name = $1 EXCEPT SELECT id, last_name FROM authors ORDER BY label DESC, id LIMIT $2;",
+			[]interface{}{"books", 10},
+			query,
+			args,
+		)
+	})
+
+	t.Run("select with window", func(t *testing.T) {
+		query, args := QueryGetCategoryRanks(GetCategoryRanksInput{frameSize: 2})
+		assertQuery(t,
+			"SELECT id, name, row_number() OVER (PARTITION BY parent_id ORDER BY name) position, count(*) OVER siblings sibling_count, sum(id) OVER (siblings ORDER BY id ROWS BETWEEN $1 PRECEDING AND CURRENT ROW) running_total, lag(name) OVER (ORDER BY id) previous_name FROM categories WINDOW siblings AS (PARTITION BY parent_id) ORDER BY rank() OVER (ORDER BY name DESC), id;",
+			[]interface{}{2},
+			query,
+			args,
+		)
+	})
+
+	t.Run("select with join", func(t *testing.T) {
+		query, args := QueryGetAuthorJoin()
+		assertQuery(t,
+			"SELECT a1.id FROM authors a1 LEFT JOIN authors a2 ON a1.id = a2.id;",
 			[]interface{}{},
 			query,
 			args,
 		)
 	})
-	t.Run("select with if statement equality - any other value", func(t *testing.T) {
-		query, args := QueryGetAuthorIfStatement(GetAuthorIfStatementInput{bioOptional: ptr("bio")})
+	t.Run("select with conditional join - without the join", func(t *testing.T) {
+		query, args := QuerySearchBooks(SearchBooksInput{title: ptr("Dune")})
 		assertQuery(t,
-			"SELECT id FROM authors WHERE bio = $1;",
-			[]interface{}{"bio"},
+			"SELECT b.id, b.title FROM books b WHERE (b.title = $1) ORDER BY b.id;",
+			[]interface{}{"Dune"},
+			query,
+			args,
+		)
+	})
+	t.Run("select with conditional join - with the join", func(t *testing.T) {
+		query, args := QuerySearchBooks(SearchBooksInput{authorName: ptr("Frank")})
+		assertQuery(t,
+			"SELECT b.id, b.title FROM books b INNER JOIN authors a ON a.id = b.author_id WHERE (a.first_name = $1) AND a.active = TRUE ORDER BY b.id;",
+			[]interface{}{"Frank"},
 			query,
 			args,
 		)
 	})
 
-	// query GetAuthorIfStatementMultipleJoined(bioOptional: string?, id: int?) {
-	// 	SELECT id FROM authors
-	// 	WHERE
-	// 		{if id is NULL}
-	// 			id IS NULL
-	// 		{end}
+	t.Run("select for update - waits for locked rows", func(t *testing.T) {
+		query, args := QueryLockBooks(LockBooksInput{authorID: 1})
+		assertQuery(t,
+			"SELECT b.id, b.title FROM books b INNER JOIN authors a ON a.id = b.author_id WHERE b.author_id = $1 ORDER BY b.id LIMIT 10 FOR NO KEY UPDATE OF b FOR KEY SHARE OF a NOWAIT;",
+			[]interface{}{1},
+			query,
+			args,
+		)
+	})
+	t.Run("select for update - skips locked rows", func(t *testing.T) {
+		query, args := QueryLockBooks(LockBooksInput{authorID: 1, skipLocked: true})
+		assertQuery(t,
+			"SELECT b.id, b.title FROM books b INNER JOIN authors a ON a.id = b.author_id WHERE b.author_id = $1 ORDER BY b.id LIMIT 10 FOR NO KEY UPDATE OF b SKIP LOCKED FOR KEY SHARE OF a NOWAIT;",
+			[]interface{}{1},
+			query,
+			args,
+		)
+	})
 
-	// 		AND
+	t.Run("select with join - using, lateral and cross", func(t *testing.T) {
+		query, args := QueryGetBookAuthors(GetBookAuthorsInput{title: ptr("Draft")})
+		assertQuery(t,
+			"SELECT author_id, b.title, first_name, latest.title latest_title, c.name FROM books b INNER JOIN (SELECT id author_id, first_name FROM authors) a USING (author_id) LEFT JOIN LATERAL (SELECT title FROM books WHERE author_id = b.author_id AND title != $1 ORDER BY id DESC LIMIT 1) latest ON TRUE CROSS JOIN categories c WHERE c.parent_id IS NULL;",
+			[]interface{}{"Draft"},
+			query,
+			args,
+		)
+	})
 
-	// 		{if bioOptional IS NULL}
-	// 			bio IS NULL
-	// 		{else if bioOptional = "specialValue"}
-	// 		{else}
-	// 			bio = {bioOptional}
-	// 		{end}
-	// }
-	t.Run("select with if statement equality - is null check", func(t *testing.T) {
-		query, args := QueryGetAuthorIfStatementMultipleJoined(GetAuthorIfStatementMultipleJoinedInput{bioOptional: nil, id: nil})
+	t.Run("select with join - lateral left join without a row", func(t *testing.T) {
+		// latest_title comes from the nullable side of a left join, so a book
+		// without another title scans it as nil
+		row := GetBookAuthorsRow{author_id: 1, title: "Draft", first_name: "Ann", latest_title: nil, name: "Fiction"}
+		if row.latest_title != nil {
+			t.Errorf("expected latest_title to be nil, got %v", *row.latest_title)
+		}
+		row.latest_title = ptr("Final")
+		if *row.latest_title != "Final" {
+			t.Errorf("expected latest_title to be Final, got %v", *row.latest_title)
+		}
+	})
+
+	t.Run("select with struct params - empty filter", func(t *testing.T) {
+		query, args := QueryFilterAuthors(FilterAuthorsInput{})
 		assertQuery(t,
-			"SELECT id FROM authors WHERE id IS NULL AND bio IS NULL;",
+			"SELECT id FROM authors WHERE (((active = $1))) ORDER BY id;",
+			[]interface{}{false},
+			query,
+			args,
+		)
+	})
+	t.Run("select with struct params - set fields", func(t *testing.T) {
+		query, args := QueryFilterAuthors(FilterAuthorsInput{
+			filter:  AuthorFilter{name: ptr("Ann"), minID: ptr(3), active: true},
+			exclude: &AuthorFilter{active: true},
+		})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE (((first_name = $1 AND active = $2) AND id >= $3)) AND active != $4 ORDER BY id;",
+			[]interface{}{"Ann", true, 3, true},
+			query,
+			args,
+		)
+	})
+	t.Run("select with struct params - optional struct field", func(t *testing.T) {
+		query, args := QueryFilterAuthors(FilterAuthorsInput{
+			filter:  AuthorFilter{active: true},
+			exclude: &AuthorFilter{name: ptr("Smith")},
+		})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE (((active = $1)) AND last_name != $2) ORDER BY id;",
+			[]interface{}{true, "Smith"},
+			query,
+			args,
+		)
+	})
+
+	t.Run("select with if statement - flag conditions unset", func(t *testing.T) {
+		query, args := QueryGetAuthorsWithFlags(GetAuthorsWithFlagsInput{})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE (active = TRUE);",
 			[]interface{}{},
 			query,
 			args,
 		)
 	})
-	t.Run("select with if statement equality - empty statement", func(t *testing.T) {
-		query, args := QueryGetAuthorIfStatementMultipleJoined(GetAuthorIfStatementMultipleJoinedInput{bioOptional: ptr("specialValue"), id: nil})
+	t.Run("select with if statement - flag conditions set", func(t *testing.T) {
+		query, args := QueryGetAuthorsWithFlags(GetAuthorsWithFlagsInput{includeInactive: true, onlyWithBio: true})
 		assertQuery(t,
-			"SELECT id FROM authors WHERE id IS NULL;",
+			"SELECT id FROM authors WHERE (bio IS NOT NULL) AND last_name IS NOT NULL;",
 			[]interface{}{},
 			query,
 			args,
 		)
 	})
-	t.Run("select with if statement equality - multiple empty statements", func(t *testing.T) {
-		query, args := QueryGetAuthorIfStatementMultipleJoined(GetAuthorIfStatementMultipleJoinedInput{bioOptional: ptr("specialValue"), id: ptr(3)})
+	t.Run("select with if statement - flag conditions with name", func(t *testing.T) {
+		query, args := QueryGetAuthorsWithFlags(GetAuthorsWithFlagsInput{onlyWithBio: true, name: ptr("Ann")})
 		assertQuery(t,
-			"SELECT id FROM authors;",
+			"SELECT id FROM authors WHERE (active = TRUE AND bio IS NOT NULL) AND first_name = $1;",
+			[]interface{}{"Ann"},
+			query,
+			args,
+		)
+	})
+
+	t.Run("select with if statement - empty lists", func(t *testing.T) {
+		query, args := QueryGetAuthorsByIDs(GetAuthorsByIDsInput{})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE (FALSE);",
 			[]interface{}{},
 			query,
 			args,
 		)
 	})
-	t.Run("select with if statement equality - empty ID check", func(t *testing.T) {
-		query, args := QueryGetAuthorIfStatementMultipleJoined(GetAuthorIfStatementMultipleJoinedInput{bioOptional: ptr("bio"), id: ptr(3)})
+	t.Run("select with if statement - non-empty lists", func(t *testing.T) {
+		query, args := QueryGetAuthorsByIDs(GetAuthorsByIDsInput{ids: []int{1, 2}, excludeIDs: []int{3}, names: []string{"Ann", "Bo"}})
 		assertQuery(t,
-			"SELECT id FROM authors WHERE bio = $1;",
-			[]interface{}{"bio"},
+			"SELECT id FROM authors WHERE (id IN ($1, $2) AND id NOT IN ($3)) AND (first_name = $4 OR first_name = $5);",
+			[]interface{}{1, 2, 3, "Ann", "Bo"},
 			query,
 			args,
 		)
 	})
 
-	t.Run("select with join", func(t *testing.T) {
-		query, args := QueryGetAuthorJoin()
+	t.Run("select with optional list params - nil lists", func(t *testing.T) {
+		query, args := QueryGetAuthorsByOptionalLists(GetAuthorsByOptionalListsInput{})
 		assertQuery(t,
-			"SELECT a1.id FROM authors a1 LEFT JOIN authors a2 ON a1.id = a2.id;",
+			"SELECT id FROM authors WHERE bio IS NOT NULL;",
+			[]interface{}{},
+			query,
+			args,
+		)
+	})
+	t.Run("select with optional list params - empty lists", func(t *testing.T) {
+		query, args := QueryGetAuthorsByOptionalLists(GetAuthorsByOptionalListsInput{ids: []int{}, names: []string{}})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE (FALSE AND (FALSE));",
 			[]interface{}{},
 			query,
 			args,
 		)
 	})
+	t.Run("select with optional list params - non-empty lists", func(t *testing.T) {
+		query, args := QueryGetAuthorsByOptionalLists(GetAuthorsByOptionalListsInput{ids: []int{1}, names: []string{"Ann"}})
+		assertQuery(t,
+			"SELECT id FROM authors WHERE (id IN ($1) AND (first_name != $2));",
+			[]interface{}{1, "Ann"},
+			query,
+			args,
+		)
+	})
+}
+
+func TestGeneratedInserts(t *testing.T) {
+	t.Run("insert with on conflict", func(t *testing.T) {
+		query, args := QueryUpsertBook(UpsertBookInput{authorID: 1, title: "Dune"})
+		assertQuery(t,
+			"INSERT INTO books (author_id, title) VALUES ($1, $2) ON CONFLICT (title, author_id) DO UPDATE SET title = excluded.title || ' (updated)' WHERE books.title != excluded.title RETURNING id, title;",
+			[]interface{}{1, "Dune"},
+			query,
+			args,
+		)
+	})
+
+	t.Run("bulk insert", func(t *testing.T) {
+		bio := ptr("Culture")
+		queries, batches := QueryCreateAuthors(CreateAuthorsInput{rows: []AuthorInsert{
+			{firstName: "Ann", lastName: "Leckie"},
+			{firstName: "Iain", lastName: "Banks", bio: bio},
+		}})
+		if len(queries) != 1 || len(batches) != 1 {
+			t.Fatalf("expected 1 statement, got %d", len(queries))
+		}
+		assertQuery(t,
+			"INSERT INTO authors (first_name, last_name, alias, bio, active) VALUES ($1, $2, lower($3), $4, TRUE), ($5, $6, lower($7), $8, TRUE) ON CONFLICT (alias) DO NOTHING RETURNING id;",
+			[]interface{}{"Ann", "Leckie", "Ann", (*string)(nil), "Iain", "Banks", "Iain", bio},
+			queries[0],
+			batches[0],
+		)
+	})
+	t.Run("bulk insert - no rows", func(t *testing.T) {
+		queries, _ := QueryCreateAuthors(CreateAuthorsInput{})
+		if len(queries) != 0 {
+			t.Fatalf("expected no statements, got %d", len(queries))
+		}
+	})
+	t.Run("bulk insert with fields in expressions", func(t *testing.T) {
+		queries, batches := QueryCreateAuthorNames(CreateAuthorNamesInput{rows: []AuthorName{
+			{name: "ann", alias: "AL"},
+		}})
+		assertQuery(t,
+			"INSERT INTO authors (first_name, last_name, alias) VALUES (initcap($1), upper($2), lower($3));",
+			[]interface{}{"ann", "ann", "AL"},
+			queries[0],
+			batches[0],
+		)
+	})
+	t.Run("bulk insert - split at the bind param limit", func(t *testing.T) {
+		rows := make([]AuthorInsert, 20000)
+		queries, batches := QueryCreateAuthors(CreateAuthorsInput{rows: rows})
+		if len(queries) != 2 {
+			t.Fatalf("expected 2 statements, got %d", len(queries))
+		}
+		// 4 params per row
+		if len(batches[0]) != 16383*4 || len(batches[1]) != (20000-16383)*4 {
+			t.Fatalf("unexpected batch sizes %d and %d", len(batches[0]), len(batches[1]))
+		}
+		prefix := "INSERT INTO authors (first_name, last_name, alias, bio, active) VALUES ($1, $2, lower($3), $4, TRUE), "
+		if queries[1][:len(prefix)] != prefix {
+			t.Fatalf("expected params to be numbered from 1 in each statement, got %s", queries[1][:len(prefix)])
+		}
+	})
+}
+
+// collects the rows of a copy, the way a test would fake a pgx conn
+type fakeCopyFromer struct {
+	table   string
+	columns []string
+	rows    [][]interface{}
+}
+
+func (f *fakeCopyFromer) CopyFrom(ctx context.Context, table string, columns []string, source CopyFromSource) (int64, error) {
+	f.table = table
+	f.columns = columns
+	for source.Next() {
+		values, err := source.Values()
+		if err != nil {
+			return 0, err
+		}
+		f.rows = append(f.rows, values)
+	}
+	return int64(len(f.rows)), source.Err()
+}
+
+func TestGeneratedCopy(t *testing.T) {
+	bio := ptr("Culture")
+	db := &fakeCopyFromer{}
+	count, err := CopyLoadAuthors(context.Background(), db, []LoadAuthorsRow{
+		{first_name: "Ann", last_name: "Leckie", alias: "ann", active: true},
+		{first_name: "Iain", last_name: "Banks", alias: "iain", bio: bio},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 || len(db.rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", count)
+	}
+	if db.table != "authors" || len(db.columns) != 5 || db.columns[3] != "bio" {
+		t.Fatalf("unexpected table %s and columns %v", db.table, db.columns)
+	}
+
+	expectRows := [][]interface{}{
+		{"Ann", "Leckie", "ann", (*string)(nil), true},
+		{"Iain", "Banks", "iain", bio, false},
+	}
+	for i := range expectRows {
+		for j := range expectRows[i] {
+			if expectRows[i][j] != db.rows[i][j] {
+				t.Fatalf("row %d: expected %v, got %v", i, expectRows[i], db.rows[i])
+			}
+		}
+	}
 
+	// copies in the same file share the interfaces
+	db = &fakeCopyFromer{}
+	count, err = CopyLoadBooks(context.Background(), db, []LoadBooksRow{
+		{author_id: 1, title: "Ancillary Justice"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || db.table != "books" || db.rows[0][0] != int64(1) || db.rows[0][1] != "Ancillary Justice" {
+		t.Fatalf("unexpected copy of %d rows into %s: %v", count, db.table, db.rows)
+	}
 }
 
 func TestGeneratedSelectMoreComplexWhere(t *testing.T) {
@@ -813,3 +3186,23 @@ func TestGeneratedSelectMoreComplexWhere(t *testing.T) {
 		})
 	}
 }
+
+func TestGoTypePackageName(t *testing.T) {
+	testCases := []struct {
+		goType string
+		expect string
+	}{
+		{goType: "int64", expect: "int64"},
+		{goType: "time.Time", expect: "time.Time"},
+		{goType: "github.com/acme/ids.AuthorID", expect: "ids.AuthorID"},
+		{goType: "gopkg.in/yaml.v3.Node", expect: "yaml.Node"},
+		{goType: "github.com/jackc/pgx/v5/pgtype.Text", expect: "pgtype.Text"},
+		{goType: "github.com/acme/uuid/v2.UUID", expect: "uuid.UUID"},
+	}
+
+	for _, test := range testCases {
+		if got := ParseGoType(test.goType).String(); got != test.expect {
+			t.Errorf("expected %s for %s, got %s", test.expect, test.goType, got)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+
+	// the required keys, so each case only adds what it tests
+	const paths = `
+schema_path = "schema.sql"
+query_path = "queries.sql"
+output_path = "output.go"
+output_package = "subpkg"
+`
+
+	schemaParser := NewSchemaParser(`
+		CREATE TABLE authors (
+			id BIGSERIAL PRIMARY KEY,
+			first_name TEXT NOT NULL
+		);
+	`)
+	schemaParser.Parse()
+
+	type testCase struct {
+		name              string
+		config            string
+		expectError       bool
+		expectInListStyle InListStyle
+		expectMappings    []TypeMapping
+		expectCheckErrors []error
+	}
+
+	testCases := []testCase{
+		{
+			name:              "type mappings",
+			config:            paths + "in_list_style = \"any\"\n[types]\nTIMESTAMPTZ = \"time.Time\"\nauthors.id = \"github.com/acme/ids.AuthorID\"\n",
+			expectInListStyle: InListStyleAny,
+			expectMappings: []TypeMapping{
+				{ColumnType: "timestamptz", GoType: GoType{ImportPath: "time", Name: "Time"}},
+				{Table: "authors", Column: "id", GoType: GoType{ImportPath: "github.com/acme/ids", Name: "AuthorID"}},
+			},
+		},
+		{
+			name:   "type mappings - errors with an unknown table or column",
+			config: paths + "[types]\nreviews.id = \"github.com/acme/ids.ReviewID\"\nauthors.nickname = \"github.com/acme/names.Nickname\"\n",
+			expectMappings: []TypeMapping{
+				{Table: "reviews", Column: "id", GoType: GoType{ImportPath: "github.com/acme/ids", Name: "ReviewID"}},
+				{Table: "authors", Column: "nickname", GoType: GoType{ImportPath: "github.com/acme/names", Name: "Nickname"}},
+			},
+			expectCheckErrors: []error{ErrUnknownTable, ErrUnknownField},
+		},
+		{
+			name:        "type mappings - errors with a missing type name",
+			config:      paths + "[types]\nauthors.id = \"github.com/acme/ids.\"\n",
+			expectError: true,
+		},
+		{
+			name:        "type mappings - errors with an invalid type name",
+			config:      paths + "[types]\nuuid = \"github.com/google/uuid.UU-ID\"\n",
+			expectError: true,
+		},
+		{
+			name:        "type mappings - errors with an empty import path element",
+			config:      paths + "[types]\nuuid = \"github.com//uuid.UUID\"\n",
+			expectError: true,
+		},
+		{
+			name:        "type mappings - errors with an unknown section",
+			config:      paths + "[mappings]\nuuid = \"github.com/google/uuid.UUID\"\n",
+			expectError: true,
+		},
+		{
+			name:        "in list style - errors with an unknown value",
+			config:      paths + "in_list_style = \"array\"\n",
+			expectError: true,
+		},
+		{
+			name:        "errors with a missing key",
+			config:      "schema_path = \"schema.sql\"\n",
+			expectError: true,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := parseConfig(test.config)
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if config.InListStyle != test.expectInListStyle {
+				t.Errorf("expected in list style %d, got %d", test.expectInListStyle, config.InListStyle)
+			}
+			if len(config.Types.Mappings) != len(test.expectMappings) {
+				t.Fatalf("expected %d mappings, got %d", len(test.expectMappings), len(config.Types.Mappings))
+			}
+			for i, mapping := range config.Types.Mappings {
+				if mapping != test.expectMappings[i] {
+					t.Errorf("expected mapping %+v, got %+v", test.expectMappings[i], mapping)
+				}
+			}
+
+			checkErrors := CheckQueries(schemaParser.Result, Queries{}, config.Types)
+			if len(checkErrors) != len(test.expectCheckErrors) {
+				t.Log(test.expectCheckErrors, checkErrors)
+				t.Fatalf("expected %d errors, got %d", len(test.expectCheckErrors), len(checkErrors))
+			}
+			for i := range checkErrors {
+				if !errors.Is(checkErrors[i].Err, test.expectCheckErrors[i]) {
+					t.Fatalf("expected error: %s, got: %s", test.expectCheckErrors[i], checkErrors[i].Err)
+				}
+			}
+		})
+	}
+}
@@ -18,7 +18,7 @@ func TestParseSchema(t *testing.T) {
 		{
 			name:             "simple select",
 			schemaFile:       "tests_sample_schema.sql",
-			expectTableCount: 3,
+			expectTableCount: 4,
 			expectErrors:     nil,
 		},
 	}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+)
+
+type LoadAuthorsRow struct {
+	first_name string
+	last_name  string
+	alias      string
+	bio        *string
+	active     bool
+}
+
+type CopyFromSource interface {
+	Next() bool
+	Values() ([]interface{}, error)
+	Err() error
+}
+
+type CopyFromer interface {
+	CopyFrom(ctx context.Context, table string, columns []string, source CopyFromSource) (int64, error)
+}
+
+type loadAuthorsSource struct {
+	rows  []LoadAuthorsRow
+	index int
+}
+
+func (s *loadAuthorsSource) Next() bool {
+	s.index++
+	return s.index <= len(s.rows)
+}
+
+func (s *loadAuthorsSource) Values() ([]interface{}, error) {
+	row := s.rows[s.index-1]
+	return []interface{}{row.first_name, row.last_name, row.alias, row.bio, row.active}, nil
+}
+
+func (s *loadAuthorsSource) Err() error {
+	return nil
+}
+
+func CopyLoadAuthors(ctx context.Context, db CopyFromer, rows []LoadAuthorsRow) (int64, error) {
+	return db.CopyFrom(ctx, "authors", []string{"first_name", "last_name", "alias", "bio", "active"}, &loadAuthorsSource{rows: rows})
+}
+
+type LoadBooksRow struct {
+	author_id int64
+	title     string
+}
+
+type loadBooksSource struct {
+	rows  []LoadBooksRow
+	index int
+}
+
+func (s *loadBooksSource) Next() bool {
+	s.index++
+	return s.index <= len(s.rows)
+}
+
+func (s *loadBooksSource) Values() ([]interface{}, error) {
+	row := s.rows[s.index-1]
+	return []interface{}{row.author_id, row.title}, nil
+}
+
+func (s *loadBooksSource) Err() error {
+	return nil
+}
+
+func CopyLoadBooks(ctx context.Context, db CopyFromer, rows []LoadBooksRow) (int64, error) {
+	return db.CopyFrom(ctx, "books", []string{"author_id", "title"}, &loadBooksSource{rows: rows})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type AuthorInsert struct {
+	firstName string
+	lastName  string
+	bio       *string
+}
+
+type CreateAuthorsInput struct {
+	rows []AuthorInsert
+}
+
+type CreateAuthorsRow struct {
+	id int64
+}
+
+func QueryCreateAuthors(input CreateAuthorsInput) ([]string, [][]interface{}) {
+	var queries []string
+	var batches [][]interface{}
+
+	rows := input.rows
+	for len(rows) > 0 {
+		sb := strings.Builder{}
+		args := []interface{}{}
+		argIndex := 1
+
+		var suffix string
+		{
+			sb := strings.Builder{}
+
+			sb.WriteString(" ON CONFLICT (alias) DO NOTHING")
+
+			sb.WriteString(" RETURNING id")
+
+			suffix = sb.String()
+		}
+
+		sb.WriteString("INSERT INTO authors (first_name, last_name, alias, bio, active) VALUES ")
+
+		n := 0
+		rowArgs := 0
+		for _, local2_row := range rows {
+			if n > 0 && len(args)+rowArgs > 65535 {
+				break
+			}
+			if n > 0 {
+				sb.WriteString(", ")
+			}
+
+			start := len(args)
+			lit1 := fmt.Sprintf("$%d", argIndex)
+			args = append(args, local2_row.firstName)
+			argIndex++
+			lit2 := fmt.Sprintf("$%d", argIndex)
+			args = append(args, local2_row.lastName)
+			argIndex++
+			lit3 := fmt.Sprintf("$%d", argIndex)
+			args = append(args, local2_row.firstName)
+			argIndex++
+			lit4 := fmt.Sprintf("lower(%s)", lit3)
+			lit5 := fmt.Sprintf("$%d", argIndex)
+			args = append(args, local2_row.bio)
+			argIndex++
+			lit6 := "TRUE"
+			sb.WriteString(fmt.Sprintf("(%s, %s, %s, %s, %s)", lit1, lit2, lit4, lit5, lit6))
+			rowArgs = len(args) - start
+			n++
+		}
+		rows = rows[n:]
+
+		sb.WriteString(suffix)
+		sb.WriteString(";")
+
+		queries = append(queries, sb.String())
+		batches = append(batches, args)
+	}
+
+	return queries, batches
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type AuthorName struct {
+	name  string
+	alias string
+}
+
+type CreateAuthorNamesInput struct {
+	rows []AuthorName
+}
+
+func QueryCreateAuthorNames(input CreateAuthorNamesInput) ([]string, [][]interface{}) {
+	var queries []string
+	var batches [][]interface{}
+
+	rows := input.rows
+	for len(rows) > 0 {
+		sb := strings.Builder{}
+		args := []interface{}{}
+		argIndex := 1
+
+		var suffix string
+		{
+			sb := strings.Builder{}
+
+			suffix = sb.String()
+		}
+
+		sb.WriteString("INSERT INTO authors (first_name, last_name, alias) VALUES ")
+
+		n := 0
+		rowArgs := 0
+		for _, local3_row := range rows {
+			if n > 0 && len(args)+rowArgs > 65535 {
+				break
+			}
+			if n > 0 {
+				sb.WriteString(", ")
+			}
+
+			start := len(args)
+			lit1 := fmt.Sprintf("$%d", argIndex)
+			args = append(args, local3_row.name)
+			argIndex++
+			lit2 := fmt.Sprintf("initcap(%s)", lit1)
+			lit3 := fmt.Sprintf("$%d", argIndex)
+			args = append(args, local3_row.name)
+			argIndex++
+			lit4 := fmt.Sprintf("upper(%s)", lit3)
+			lit5 := fmt.Sprintf("$%d", argIndex)
+			args = append(args, local3_row.alias)
+			argIndex++
+			lit6 := fmt.Sprintf("lower(%s)", lit5)
+			sb.WriteString(fmt.Sprintf("(%s, %s, %s)", lit2, lit4, lit6))
+			rowArgs = len(args) - start
+			n++
+		}
+		rows = rows[n:]
+
+		sb.WriteString(suffix)
+		sb.WriteString(";")
+
+		queries = append(queries, sb.String())
+		batches = append(batches, args)
+	}
+
+	return queries, batches
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type UpsertBookInput struct {
+	authorID int
+	title    string
+}
+
+type UpsertBookRow struct {
+	id    int64
+	title string
+}
+
+func QueryUpsertBook(input UpsertBookInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	lit1 := fmt.Sprintf("$%d", argIndex)
+	args = append(args, input.authorID)
+	argIndex++
+	lit2 := fmt.Sprintf("$%d", argIndex)
+	args = append(args, input.title)
+	argIndex++
+	sb.WriteString(fmt.Sprintf("INSERT INTO books (author_id, title) VALUES (%s, %s)", lit1, lit2))
+
+	lit3 := "excluded.title"
+	lit4 := "' (updated)'"
+	lit5 := fmt.Sprintf("%s || %s", lit3, lit4)
+	sb.WriteString(fmt.Sprintf(" ON CONFLICT (title, author_id) DO UPDATE SET title = %s", lit5))
+
+	lit6 := "books.title"
+	lit7 := "excluded.title"
+	expr1 := fmt.Sprintf("%s != %s", lit6, lit7)
+	sb.WriteString(fmt.Sprintf(" WHERE %s", expr1))
+
+	sb.WriteString(" RETURNING id, title")
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
@@ -18,4 +18,13 @@ CREATE TABLE "with_schema"."another_table" (
   name text      NOT NULL,
   unknownType some unknown types and keywords,
   "bio"  text
-);
\ No newline at end of file
+);
+
+CREATE TABLE books (
+  id        BIGSERIAL PRIMARY KEY,
+  author_id bigint NOT NULL REFERENCES authors (id),
+  isbn      text UNIQUE,
+  title     text NOT NULL,
+  CONSTRAINT books_author_id_title_key UNIQUE (author_id, title),
+  CHECK (length(title) > 0)
+);
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorArithmeticInput struct {
+	minID  *int
+	offset int
+	name   *string
+}
+
+type GetAuthorArithmeticRow struct {
+	id int64
+}
+
+func QueryGetAuthorArithmetic(input GetAuthorArithmeticInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	sb.WriteString("SELECT id FROM authors")
+
+	groupClause1 := make([]string, 0, 2)
+
+	groupClause2 := make([]string, 0, 2)
+
+	groupClause3 := make([]string, 0, 2)
+
+	groupClause4 := make([]string, 0, 2)
+
+	if input.minID != nil {
+		lit1 := "id"
+		lit2 := "2"
+		lit3 := fmt.Sprintf("%s * %s", lit1, lit2)
+		lit4 := "1"
+		lit5 := fmt.Sprintf("%s + %s", lit3, lit4)
+		lit6 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.minID)
+		argIndex++
+		expr1 := fmt.Sprintf("%s > %s", lit5, lit6)
+		groupClause4 = append(groupClause4, expr1)
+	}
+
+	lit7 := "id"
+	lit8 := fmt.Sprintf("$%d", argIndex)
+	args = append(args, input.offset)
+	argIndex++
+	lit9 := fmt.Sprintf("%s + %s", lit7, lit8)
+	lit10 := "3"
+	lit11 := fmt.Sprintf("(%s) * %s", lit9, lit10)
+	lit12 := "7"
+	lit13 := fmt.Sprintf("%s %% %s", lit11, lit12)
+	lit14 := "id"
+	lit15 := fmt.Sprintf("-%s", lit14)
+	expr2 := fmt.Sprintf("%s = %s", lit13, lit15)
+	groupClause4 = append(groupClause4, expr2)
+	groupClause4Result := strings.Join(groupClause4, " AND ")
+	if len(groupClause4Result) > 0 {
+		groupClause3 = append(groupClause3, fmt.Sprintf("(%s)", groupClause4Result))
+	}
+
+	if input.name != nil {
+		lit16 := "first_name"
+		lit17 := "' '"
+		lit18 := fmt.Sprintf("%s || %s", lit16, lit17)
+		lit19 := "last_name"
+		lit20 := fmt.Sprintf("%s || %s", lit18, lit19)
+		lit21 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.name)
+		argIndex++
+		expr3 := fmt.Sprintf("%s = %s", lit20, lit21)
+		groupClause3 = append(groupClause3, expr3)
+	}
+
+	groupClause3Result := strings.Join(groupClause3, " AND ")
+	if len(groupClause3Result) > 0 {
+		groupClause2 = append(groupClause2, fmt.Sprintf("(%s)", groupClause3Result))
+	}
+
+	if input.minID != nil {
+		lit22 := "id"
+		lit23 := "id"
+		lit24 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.minID)
+		argIndex++
+		lit25 := fmt.Sprintf("%s - %s", lit23, lit24)
+		lit26 := fmt.Sprintf("%s - (%s)", lit22, lit25)
+		lit27 := "1"
+		expr4 := fmt.Sprintf("%s = %s", lit26, lit27)
+		groupClause2 = append(groupClause2, expr4)
+	}
+
+	groupClause2Result := strings.Join(groupClause2, " AND ")
+	if len(groupClause2Result) > 0 {
+		groupClause1 = append(groupClause1, fmt.Sprintf("(%s)", groupClause2Result))
+	}
+
+	if input.offset*2 > 10 {
+		lit28 := "id"
+		lit29 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, input.offset)
+		argIndex++
+		expr5 := fmt.Sprintf("%s > %s", lit28, lit29)
+		groupClause1 = append(groupClause1, expr5)
+	}
+
+	groupClause1Result := strings.Join(groupClause1, " AND ")
+	if len(groupClause1Result) > 0 {
+		sb.WriteString(fmt.Sprintf(" WHERE %s", groupClause1Result))
+	}
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
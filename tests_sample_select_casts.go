@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorCastsInput struct {
+	minID *string
+	name  string
+}
+
+type GetAuthorCastsRow struct {
+	id_text string
+	bio     *string
+	next_id float64
+}
+
+func QueryGetAuthorCasts(input GetAuthorCastsInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	lit1 := "id"
+	lit2 := fmt.Sprintf("%s::text", lit1)
+	lit3 := "bio"
+	lit4 := fmt.Sprintf("CAST(%s AS varchar(255))", lit3)
+	lit5 := "id"
+	lit6 := "1"
+	lit7 := fmt.Sprintf("%s + %s", lit5, lit6)
+	lit8 := fmt.Sprintf("(%s)::double precision", lit7)
+	sb.WriteString(fmt.Sprintf("SELECT %s id_text, %s, %s next_id FROM authors", lit2, lit4, lit8))
+
+	groupClause1 := make([]string, 0, 2)
+
+	groupClause2 := make([]string, 0, 2)
+
+	if input.minID != nil {
+		lit9 := "id"
+		lit10 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.minID)
+		argIndex++
+		lit11 := fmt.Sprintf("%s::bigint", lit10)
+		expr1 := fmt.Sprintf("%s > %s", lit9, lit11)
+		groupClause2 = append(groupClause2, expr1)
+	}
+
+	lit12 := "first_name"
+	lit13 := fmt.Sprintf("$%d", argIndex)
+	args = append(args, input.name)
+	argIndex++
+	lit14 := fmt.Sprintf("CAST(%s AS text)", lit13)
+	expr2 := fmt.Sprintf("%s = %s", lit12, lit14)
+	groupClause2 = append(groupClause2, expr2)
+	groupClause2Result := strings.Join(groupClause2, " AND ")
+	if len(groupClause2Result) > 0 {
+		groupClause1 = append(groupClause1, fmt.Sprintf("(%s)", groupClause2Result))
+	}
+
+	lit15 := "'5'"
+	lit16 := fmt.Sprintf("%s::int", lit15)
+	lit17 := "id"
+	lit18 := fmt.Sprintf("%s + %s", lit16, lit17)
+	lit19 := "6"
+	expr3 := fmt.Sprintf("%s > %s", lit18, lit19)
+	groupClause1 = append(groupClause1, expr3)
+	groupClause1Result := strings.Join(groupClause1, " AND ")
+	if len(groupClause1Result) > 0 {
+		sb.WriteString(fmt.Sprintf(" WHERE %s", groupClause1Result))
+	}
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type SearchBooksInput struct {
+	authorName *string
+	title      *string
+}
+
+type SearchBooksRow struct {
+	id    int64
+	title string
+}
+
+func QuerySearchBooks(input SearchBooksInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	sb.WriteString("SELECT b.id, b.title FROM books b")
+
+	if input.authorName != nil {
+		sb.WriteString(" INNER JOIN authors a ON ")
+		lit1 := "a.id"
+		lit2 := "b.author_id"
+		expr1 := fmt.Sprintf("%s = %s", lit1, lit2)
+		sb.WriteString(fmt.Sprintf("%s", expr1))
+
+	}
+
+	groupClause1 := make([]string, 0, 2)
+
+	groupClause2 := make([]string, 0, 2)
+
+	if input.title != nil {
+		lit3 := "b.title"
+		lit4 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.title)
+		argIndex++
+		expr2 := fmt.Sprintf("%s = %s", lit3, lit4)
+		groupClause2 = append(groupClause2, expr2)
+	}
+
+	if input.authorName != nil {
+		lit5 := "a.first_name"
+		lit6 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.authorName)
+		argIndex++
+		expr3 := fmt.Sprintf("%s = %s", lit5, lit6)
+		groupClause2 = append(groupClause2, expr3)
+	}
+
+	groupClause2Result := strings.Join(groupClause2, " AND ")
+	if len(groupClause2Result) > 0 {
+		groupClause1 = append(groupClause1, fmt.Sprintf("(%s)", groupClause2Result))
+	}
+
+	if input.authorName != nil {
+		lit7 := "a.active"
+		lit8 := "TRUE"
+		expr4 := fmt.Sprintf("%s = %s", lit7, lit8)
+		groupClause1 = append(groupClause1, expr4)
+	}
+
+	groupClause1Result := strings.Join(groupClause1, " AND ")
+	if len(groupClause1Result) > 0 {
+		sb.WriteString(fmt.Sprintf(" WHERE %s", groupClause1Result))
+	}
+
+	lit9 := "b.id"
+	sb.WriteString(fmt.Sprintf(" ORDER BY %s", lit9))
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorRecentInput struct {
+	name     *string
+	lastName *string
+	minID    int
+}
+
+type GetAuthorRecentRow struct {
+	id   int64
+	name string
+}
+
+func QueryGetAuthorRecent(input GetAuthorRecentInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	sb.WriteString("WITH named AS (")
+
+	sb.WriteString("SELECT id, first_name name FROM authors")
+
+	if input.name != nil {
+		lit1 := "first_name"
+		lit2 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.name)
+		argIndex++
+		expr1 := fmt.Sprintf("%s = %s", lit1, lit2)
+		sb.WriteString(fmt.Sprintf(" WHERE %s", expr1))
+
+	}
+
+	sb.WriteString("), recent AS (")
+
+	sb.WriteString("SELECT n.id, n.name FROM named n")
+
+	sb.WriteString(" INNER JOIN authors a ON ")
+	lit3 := "a.id"
+	lit4 := "n.id"
+	expr2 := fmt.Sprintf("%s = %s", lit3, lit4)
+	sb.WriteString(fmt.Sprintf("%s", expr2))
+
+	if input.lastName != nil {
+		lit5 := "a.last_name"
+		lit6 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.lastName)
+		argIndex++
+		expr3 := fmt.Sprintf("%s = %s", lit5, lit6)
+		sb.WriteString(fmt.Sprintf(" WHERE %s", expr3))
+
+	}
+
+	lit7 := "n.id"
+	sb.WriteString(fmt.Sprintf(" ORDER BY %s DESC", lit7))
+
+	sb.WriteString(" LIMIT 10")
+	sb.WriteString(") ")
+
+	sb.WriteString("SELECT id, name FROM recent")
+
+	lit8 := "id"
+	lit9 := fmt.Sprintf("$%d", argIndex)
+	args = append(args, input.minID)
+	argIndex++
+	expr4 := fmt.Sprintf("%s > %s", lit8, lit9)
+	sb.WriteString(fmt.Sprintf(" WHERE %s", expr4))
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
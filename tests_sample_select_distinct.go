@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorDistinctOnInput struct {
+	name *string
+}
+
+type GetAuthorDistinctOnRow struct {
+	id         int64
+	first_name string
+	last_name  string
+}
+
+func QueryGetAuthorDistinctOn(input GetAuthorDistinctOnInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	lit1 := "first_name"
+	lit2 := "last_name"
+	lit3 := fmt.Sprintf("lower(%s)", lit2)
+	sb.WriteString(fmt.Sprintf("SELECT DISTINCT ON (%s, %s) id, first_name, last_name FROM authors a", lit1, lit3))
+
+	if input.name != nil {
+		lit4 := "first_name"
+		lit5 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.name)
+		argIndex++
+		expr1 := fmt.Sprintf("%s = %s", lit4, lit5)
+		sb.WriteString(fmt.Sprintf(" WHERE %s", expr1))
+
+	}
+
+	lit6 := "last_name"
+	lit7 := fmt.Sprintf("lower(%s)", lit6)
+	lit8 := "a.first_name"
+	lit9 := "id"
+	sb.WriteString(fmt.Sprintf(" ORDER BY %s, %s, %s DESC", lit7, lit8, lit9))
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
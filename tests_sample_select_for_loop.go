@@ -11,6 +11,10 @@ type GetAuthorForLoopInput struct {
 	bioOptional *string
 }
 
+type GetAuthorForLoopRow struct {
+	id int64
+}
+
 func QueryGetAuthorForLoop(input GetAuthorForLoopInput) (string, []interface{}) {
 	sb := strings.Builder{}
 	args := []interface{}{}
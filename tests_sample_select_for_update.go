@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type LockBooksInput struct {
+	authorID   int
+	skipLocked bool
+}
+
+type LockBooksRow struct {
+	id    int64
+	title string
+}
+
+func QueryLockBooks(input LockBooksInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	sb.WriteString("SELECT b.id, b.title FROM books b")
+
+	sb.WriteString(" INNER JOIN authors a ON ")
+	lit1 := "a.id"
+	lit2 := "b.author_id"
+	expr1 := fmt.Sprintf("%s = %s", lit1, lit2)
+	sb.WriteString(fmt.Sprintf("%s", expr1))
+
+	lit3 := "b.author_id"
+	lit4 := fmt.Sprintf("$%d", argIndex)
+	args = append(args, input.authorID)
+	argIndex++
+	expr2 := fmt.Sprintf("%s = %s", lit3, lit4)
+	sb.WriteString(fmt.Sprintf(" WHERE %s", expr2))
+
+	lit5 := "b.id"
+	sb.WriteString(fmt.Sprintf(" ORDER BY %s", lit5))
+
+	sb.WriteString(" LIMIT 10")
+	sb.WriteString(" FOR NO KEY UPDATE OF b")
+	if input.skipLocked {
+		sb.WriteString(" SKIP LOCKED")
+	}
+
+	sb.WriteString(" FOR KEY SHARE OF a NOWAIT")
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
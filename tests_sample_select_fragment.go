@@ -10,6 +10,10 @@ type GetAuthorWithFragmentInput struct {
 	bioLikeOptional *string
 }
 
+type GetAuthorWithFragmentRow struct {
+	id int64
+}
+
 func QueryGetAuthorWithFragment(input GetAuthorWithFragmentInput) (string, []interface{}) {
 	sb := strings.Builder{}
 	args := []interface{}{}
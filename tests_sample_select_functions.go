@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorFunctionsInput struct {
+	name     *string
+	fallback *string
+}
+
+type GetAuthorFunctionsRow struct {
+	id          int64
+	lower       string
+	bio         *string
+	size        string
+	active_flag int32
+}
+
+func QueryGetAuthorFunctions(input GetAuthorFunctionsInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	lit1 := "first_name"
+	lit2 := fmt.Sprintf("lower(%s)", lit1)
+	lit3 := "bio"
+	lit4 := fmt.Sprintf("$%d", argIndex)
+	args = append(args, input.fallback)
+	argIndex++
+	lit5 := fmt.Sprintf("coalesce(%s, %s)", lit3, lit4)
+	lit6 := "id"
+	lit7 := "5"
+	lit8 := fmt.Sprintf("%s > %s", lit6, lit7)
+	lit9 := "'big'"
+	lit10 := "'small'"
+	lit11 := fmt.Sprintf("CASE WHEN %s THEN %s ELSE %s END", lit8, lit9, lit10)
+	lit12 := "active"
+	lit13 := "TRUE"
+	lit14 := "1"
+	lit15 := "0"
+	lit16 := fmt.Sprintf("CASE %s WHEN %s THEN %s ELSE %s END", lit12, lit13, lit14, lit15)
+	sb.WriteString(fmt.Sprintf("SELECT id, %s, %s bio, %s size, %s active_flag FROM authors", lit2, lit5, lit11, lit16))
+
+	groupClause1 := make([]string, 0, 2)
+
+	if input.name != nil {
+		lit17 := "first_name"
+		lit18 := fmt.Sprintf("lower(%s)", lit17)
+		lit19 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.name)
+		argIndex++
+		lit20 := fmt.Sprintf("lower(%s)", lit19)
+		expr1 := fmt.Sprintf("%s = %s", lit18, lit20)
+		groupClause1 = append(groupClause1, expr1)
+	}
+
+	lit21 := "bio"
+	lit22 := "''"
+	lit23 := fmt.Sprintf("coalesce(%s, %s)", lit21, lit22)
+	lit24 := fmt.Sprintf("length(%s)", lit23)
+	lit25 := "0"
+	expr2 := fmt.Sprintf("%s >= %s", lit24, lit25)
+	groupClause1 = append(groupClause1, expr2)
+	groupClause1Result := strings.Join(groupClause1, " AND ")
+	if len(groupClause1Result) > 0 {
+		sb.WriteString(fmt.Sprintf(" WHERE %s", groupClause1Result))
+	}
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
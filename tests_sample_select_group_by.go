@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorGroupByInput struct {
+	minCount *int
+}
+
+type GetAuthorGroupByRow struct {
+	first_name      string
+	count           int64
+	last_names      int64
+	id_sum          string
+	max_bio         *string
+	last_name_lower string
+}
+
+func QueryGetAuthorGroupBy(input GetAuthorGroupByInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	lit1 := "count(*)"
+	lit2 := "last_name"
+	lit3 := fmt.Sprintf("count(DISTINCT %s)", lit2)
+	lit4 := "id"
+	lit5 := fmt.Sprintf("sum(%s)", lit4)
+	lit6 := "bio"
+	lit7 := fmt.Sprintf("max(%s)", lit6)
+	lit8 := "last_name"
+	lit9 := fmt.Sprintf("lower(%s)", lit8)
+	sb.WriteString(fmt.Sprintf("SELECT first_name, %s, %s last_names, %s id_sum, %s max_bio, %s last_name_lower FROM authors", lit1, lit3, lit5, lit7, lit9))
+
+	lit10 := "active"
+	sb.WriteString(fmt.Sprintf(" WHERE %s", lit10))
+
+	lit11 := "first_name"
+	lit12 := "last_name"
+	lit13 := fmt.Sprintf("lower(%s)", lit12)
+	sb.WriteString(fmt.Sprintf(" GROUP BY %s, %s", lit11, lit13))
+
+	if input.minCount != nil {
+		lit14 := "count(*)"
+		lit15 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.minCount)
+		argIndex++
+		expr1 := fmt.Sprintf("%s > %s", lit14, lit15)
+		sb.WriteString(fmt.Sprintf(" HAVING %s", expr1))
+
+	}
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
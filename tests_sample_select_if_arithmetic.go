@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorIfArithmeticInput struct {
+	minID  *int
+	offset int
+}
+
+type GetAuthorIfArithmeticRow struct {
+	id int64
+}
+
+func QueryGetAuthorIfArithmetic(input GetAuthorIfArithmeticInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	sb.WriteString("SELECT id FROM authors")
+
+	if (input.minID != nil && *input.minID+input.offset > 5) || (input.minID != nil && -*input.minID < 0) {
+		lit1 := "id"
+		lit2 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, input.offset)
+		argIndex++
+		expr1 := fmt.Sprintf("%s > %s", lit1, lit2)
+		sb.WriteString(fmt.Sprintf(" WHERE %s", expr1))
+
+	}
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
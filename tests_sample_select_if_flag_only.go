@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorsByFlagInput struct {
+	inactive bool
+}
+
+type GetAuthorsByFlagRow struct {
+	id int64
+}
+
+func QueryGetAuthorsByFlag(input GetAuthorsByFlagInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	sb.WriteString("SELECT a.id FROM authors a")
+
+	if !input.inactive {
+		lit1 := "a.active"
+		lit2 := "TRUE"
+		expr1 := fmt.Sprintf("%s = %s", lit1, lit2)
+		sb.WriteString(fmt.Sprintf(" WHERE %s", expr1))
+
+	}
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
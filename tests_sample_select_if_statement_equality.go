@@ -9,6 +9,10 @@ type GetAuthorIfStatementInput struct {
 	bioOptional *string
 }
 
+type GetAuthorIfStatementRow struct {
+	id int64
+}
+
 func QueryGetAuthorIfStatement(input GetAuthorIfStatementInput) (string, []interface{}) {
 	sb := strings.Builder{}
 	args := []interface{}{}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorsWithFlagsInput struct {
+	includeInactive bool
+	onlyWithBio     bool
+	name            *string
+}
+
+type GetAuthorsWithFlagsRow struct {
+	id int64
+}
+
+func QueryGetAuthorsWithFlags(input GetAuthorsWithFlagsInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	sb.WriteString("SELECT id FROM authors")
+
+	groupClause1 := make([]string, 0, 2)
+
+	groupClause2 := make([]string, 0, 2)
+
+	if !input.includeInactive {
+		lit1 := "active"
+		lit2 := "TRUE"
+		expr1 := fmt.Sprintf("%s = %s", lit1, lit2)
+		groupClause2 = append(groupClause2, expr1)
+	}
+
+	if input.onlyWithBio && (input.name == nil || !input.includeInactive) {
+		lit3 := "bio"
+		lit4 := "NULL"
+		expr2 := fmt.Sprintf("%s IS NOT %s", lit3, lit4)
+		groupClause2 = append(groupClause2, expr2)
+	}
+
+	groupClause2Result := strings.Join(groupClause2, " AND ")
+	if len(groupClause2Result) > 0 {
+		groupClause1 = append(groupClause1, fmt.Sprintf("(%s)", groupClause2Result))
+	}
+
+	if input.name != nil {
+		if input.name != nil {
+			lit5 := "first_name"
+			lit6 := fmt.Sprintf("$%d", argIndex)
+			args = append(args, *input.name)
+			argIndex++
+			expr3 := fmt.Sprintf("%s = %s", lit5, lit6)
+			groupClause1 = append(groupClause1, expr3)
+		}
+
+	} else if input.includeInactive {
+		lit7 := "last_name"
+		lit8 := "NULL"
+		expr4 := fmt.Sprintf("%s IS NOT %s", lit7, lit8)
+		groupClause1 = append(groupClause1, expr4)
+	}
+
+	groupClause1Result := strings.Join(groupClause1, " AND ")
+	if len(groupClause1Result) > 0 {
+		sb.WriteString(fmt.Sprintf(" WHERE %s", groupClause1Result))
+	}
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorsByIDsInput struct {
+	ids        []int
+	excludeIDs []int
+	names      []string
+}
+
+type GetAuthorsByIDsRow struct {
+	id int64
+}
+
+func QueryGetAuthorsByIDs(input GetAuthorsByIDsInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	sb.WriteString("SELECT id FROM authors")
+
+	groupClause1 := make([]string, 0, 2)
+
+	groupClause2 := make([]string, 0, 2)
+
+	if len(input.ids) > 0 {
+		lit1 := "id"
+		expr1 := "FALSE"
+		if len(input.ids) > 0 {
+			lit2 := make([]string, 0, len(input.ids))
+			for _, item := range input.ids {
+				lit2 = append(lit2, fmt.Sprintf("$%d", argIndex))
+				args = append(args, item)
+				argIndex++
+			}
+			expr1 = fmt.Sprintf("%s IN (%s)", lit1, strings.Join(lit2, ", "))
+		}
+		groupClause2 = append(groupClause2, expr1)
+	}
+
+	if len(input.excludeIDs) > 0 {
+		lit3 := "id"
+		expr2 := "TRUE"
+		if len(input.excludeIDs) > 0 {
+			lit4 := make([]string, 0, len(input.excludeIDs))
+			for _, item := range input.excludeIDs {
+				lit4 = append(lit4, fmt.Sprintf("$%d", argIndex))
+				args = append(args, item)
+				argIndex++
+			}
+			expr2 = fmt.Sprintf("%s NOT IN (%s)", lit3, strings.Join(lit4, ", "))
+		}
+		groupClause2 = append(groupClause2, expr2)
+	}
+
+	groupClause2Result := strings.Join(groupClause2, " AND ")
+	if len(groupClause2Result) > 0 {
+		groupClause1 = append(groupClause1, fmt.Sprintf("(%s)", groupClause2Result))
+	}
+
+	groupClause3 := make([]string, 0, len(input.names))
+
+	for _, local6_name := range input.names {
+		lit5 := "first_name"
+		lit6 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, local6_name)
+		argIndex++
+		expr3 := fmt.Sprintf("%s = %s", lit5, lit6)
+		groupClause3 = append(groupClause3, expr3)
+	}
+
+	if len(input.names) == 0 {
+		groupClause3 = append(groupClause3, "FALSE")
+	}
+
+	groupClause3Result := strings.Join(groupClause3, " OR ")
+	if len(groupClause3Result) > 0 {
+		groupClause1 = append(groupClause1, fmt.Sprintf("(%s)", groupClause3Result))
+	}
+
+	groupClause1Result := strings.Join(groupClause1, " AND ")
+	if len(groupClause1Result) > 0 {
+		sb.WriteString(fmt.Sprintf(" WHERE %s", groupClause1Result))
+	}
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
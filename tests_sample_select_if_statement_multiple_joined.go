@@ -10,6 +10,10 @@ type GetAuthorIfStatementMultipleJoinedInput struct {
 	id          *int
 }
 
+type GetAuthorIfStatementMultipleJoinedRow struct {
+	id int64
+}
+
 func QueryGetAuthorIfStatementMultipleJoined(input GetAuthorIfStatementMultipleJoinedInput) (string, []interface{}) {
 	sb := strings.Builder{}
 	args := []interface{}{}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorInListInput struct {
+	ids          []int
+	excludeNames []string
+}
+
+type GetAuthorInListRow struct {
+	id int64
+}
+
+func QueryGetAuthorInList(input GetAuthorInListInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	sb.WriteString("SELECT id FROM authors")
+
+	groupClause1 := make([]string, 0, 2)
+
+	lit1 := "id"
+	expr1 := "FALSE"
+	if len(input.ids) > 0 {
+		lit2 := make([]string, 0, len(input.ids))
+		for _, item := range input.ids {
+			lit2 = append(lit2, fmt.Sprintf("$%d", argIndex))
+			args = append(args, item)
+			argIndex++
+		}
+		expr1 = fmt.Sprintf("%s IN (%s)", lit1, strings.Join(lit2, ", "))
+	}
+	groupClause1 = append(groupClause1, expr1)
+	lit3 := "first_name"
+	expr2 := "TRUE"
+	if len(input.excludeNames) > 0 {
+		lit4 := make([]string, 0, len(input.excludeNames))
+		for _, item := range input.excludeNames {
+			lit4 = append(lit4, fmt.Sprintf("$%d", argIndex))
+			args = append(args, item)
+			argIndex++
+		}
+		expr2 = fmt.Sprintf("%s NOT IN (%s)", lit3, strings.Join(lit4, ", "))
+	}
+	groupClause1 = append(groupClause1, expr2)
+	groupClause1Result := strings.Join(groupClause1, " AND ")
+	if len(groupClause1Result) > 0 {
+		sb.WriteString(fmt.Sprintf(" WHERE %s", groupClause1Result))
+	}
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
@@ -5,6 +5,10 @@ import (
 	"strings"
 )
 
+type GetAuthorJoinRow struct {
+	id int64
+}
+
 func QueryGetAuthorJoin() (string, []interface{}) {
 	sb := strings.Builder{}
 	args := []interface{}{}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetBookAuthorsInput struct {
+	title *string
+}
+
+type GetBookAuthorsRow struct {
+	author_id    int64
+	title        string
+	first_name   string
+	latest_title *string
+	name         string
+}
+
+func QueryGetBookAuthors(input GetBookAuthorsInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	sb.WriteString("SELECT author_id, b.title, first_name, latest.title latest_title, c.name FROM books b")
+
+	var lit1 string
+	{
+		sb := strings.Builder{}
+
+		sb.WriteString("SELECT id author_id, first_name FROM authors")
+
+		lit1 = fmt.Sprintf("(%s)", sb.String())
+	}
+	sb.WriteString(fmt.Sprintf(" INNER JOIN %s a USING (author_id)", lit1))
+
+	var lit2 string
+	{
+		sb := strings.Builder{}
+
+		sb.WriteString("SELECT title FROM books")
+
+		groupClause1 := make([]string, 0, 2)
+
+		lit3 := "author_id"
+		lit4 := "b.author_id"
+		expr1 := fmt.Sprintf("%s = %s", lit3, lit4)
+		groupClause1 = append(groupClause1, expr1)
+		if input.title != nil {
+			lit5 := "title"
+			lit6 := fmt.Sprintf("$%d", argIndex)
+			args = append(args, *input.title)
+			argIndex++
+			expr2 := fmt.Sprintf("%s != %s", lit5, lit6)
+			groupClause1 = append(groupClause1, expr2)
+		}
+
+		groupClause1Result := strings.Join(groupClause1, " AND ")
+		if len(groupClause1Result) > 0 {
+			sb.WriteString(fmt.Sprintf(" WHERE %s", groupClause1Result))
+		}
+
+		lit7 := "id"
+		sb.WriteString(fmt.Sprintf(" ORDER BY %s DESC", lit7))
+
+		sb.WriteString(" LIMIT 1")
+
+		lit2 = fmt.Sprintf("LATERAL (%s)", sb.String())
+	}
+	sb.WriteString(fmt.Sprintf(" LEFT JOIN %s latest ON ", lit2))
+	lit8 := "TRUE"
+	sb.WriteString(fmt.Sprintf("%s", lit8))
+
+	sb.WriteString(" CROSS JOIN categories c")
+
+	lit9 := "c.parent_id"
+	lit10 := "NULL"
+	expr3 := fmt.Sprintf("%s IS %s", lit9, lit10)
+	sb.WriteString(fmt.Sprintf(" WHERE %s", expr3))
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
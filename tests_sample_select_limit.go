@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorLimitInput struct {
+	limit  *int
+	offset int
+}
+
+type GetAuthorLimitRow struct {
+	id int64
+}
+
+func QueryGetAuthorLimit(input GetAuthorLimitInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	sb.WriteString("SELECT id FROM authors")
+
+	lit1 := "id"
+	sb.WriteString(fmt.Sprintf(" ORDER BY %s", lit1))
+
+	if input.limit != nil {
+		lit2 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.limit)
+		argIndex++
+		sb.WriteString(fmt.Sprintf(" LIMIT %s", lit2))
+	}
+
+	lit3 := fmt.Sprintf("$%d", argIndex)
+	args = append(args, input.offset)
+	argIndex++
+	sb.WriteString(fmt.Sprintf(" OFFSET %s", lit3))
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
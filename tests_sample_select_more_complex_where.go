@@ -13,6 +13,10 @@ type GetAuthorMoreComplexWhereInput struct {
 	id5 *string
 }
 
+type GetAuthorMoreComplexWhereRow struct {
+	id int64
+}
+
 func QueryGetAuthorMoreComplexWhere(input GetAuthorMoreComplexWhereInput) (string, []interface{}) {
 	sb := strings.Builder{}
 	args := []interface{}{}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorNegationInput struct {
+	offset int
+}
+
+type GetAuthorNegationRow struct {
+	id int64
+}
+
+func QueryGetAuthorNegation(input GetAuthorNegationInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	sb.WriteString("SELECT id FROM authors")
+
+	groupClause1 := make([]string, 0, 2)
+
+	groupClause2 := make([]string, 0, 2)
+
+	lit1 := "id"
+	lit2 := fmt.Sprintf("$%d", argIndex)
+	args = append(args, input.offset)
+	argIndex++
+	lit3 := fmt.Sprintf("-%s", lit2)
+	lit4 := fmt.Sprintf("-(%s)", lit3)
+	expr1 := fmt.Sprintf("%s = %s", lit1, lit4)
+	groupClause2 = append(groupClause2, expr1)
+	lit5 := "id"
+	lit6 := "5"
+	lit7 := fmt.Sprintf("-%s", lit6)
+	lit8 := fmt.Sprintf("-(%s)", lit7)
+	lit9 := fmt.Sprintf("-(%s)", lit8)
+	expr2 := fmt.Sprintf("%s = %s", lit5, lit9)
+	groupClause2 = append(groupClause2, expr2)
+	groupClause2Result := strings.Join(groupClause2, " OR ")
+	if len(groupClause2Result) > 0 {
+		groupClause1 = append(groupClause1, fmt.Sprintf("(%s)", groupClause2Result))
+	}
+
+	lit10 := "id"
+	lit11 := fmt.Sprintf("-%s", lit10)
+	lit12 := fmt.Sprintf("-(%s)", lit11)
+	lit13 := "1"
+	expr3 := fmt.Sprintf("%s > %s", lit12, lit13)
+	groupClause1 = append(groupClause1, expr3)
+	groupClause1Result := strings.Join(groupClause1, " OR ")
+	if len(groupClause1Result) > 0 {
+		sb.WriteString(fmt.Sprintf(" WHERE %s", groupClause1Result))
+	}
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorNullifIDRow struct {
+	maybe_id *int64
+}
+
+func QueryGetAuthorNullifID() (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	lit1 := "id"
+	lit2 := "5"
+	lit3 := fmt.Sprintf("nullif(%s, %s)", lit1, lit2)
+	sb.WriteString(fmt.Sprintf("SELECT %s maybe_id FROM authors", lit3))
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
+
+type GetAuthorIDRangeRow struct {
+	max_id *int64
+	min_id *int64
+}
+
+func QueryGetAuthorIDRange() (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	lit1 := "id"
+	lit2 := fmt.Sprintf("max(%s)", lit1)
+	lit3 := "id"
+	lit4 := fmt.Sprintf("min(%s)", lit3)
+	sb.WriteString(fmt.Sprintf("SELECT %s max_id, %s min_id FROM authors", lit2, lit4))
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
+
+type GetAuthorPreviousIDRow struct {
+	id          int64
+	previous_id *int64
+}
+
+func QueryGetAuthorPreviousID() (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	lit1 := "id"
+	lit2 := "id"
+	lit3 := fmt.Sprintf("lag(%s) OVER (ORDER BY %s)", lit1, lit2)
+	sb.WriteString(fmt.Sprintf("SELECT id, %s previous_id FROM authors", lit3))
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
+
+type GetAuthorFirstBookIDRow struct {
+	id            int64
+	first_book_id *int64
+}
+
+func QueryGetAuthorFirstBookID() (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	var lit1 string
+	{
+		sb := strings.Builder{}
+
+		sb.WriteString("SELECT b.id FROM books b")
+
+		lit2 := "b.author_id"
+		lit3 := "a.id"
+		expr1 := fmt.Sprintf("%s = %s", lit2, lit3)
+		sb.WriteString(fmt.Sprintf(" WHERE %s", expr1))
+
+		sb.WriteString(" LIMIT 1")
+
+		lit1 = fmt.Sprintf("(%s)", sb.String())
+	}
+	sb.WriteString(fmt.Sprintf("SELECT a.id, %s first_book_id FROM authors a", lit1))
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorsByOptionalListsInput struct {
+	ids   []int
+	names []string
+}
+
+type GetAuthorsByOptionalListsRow struct {
+	id int64
+}
+
+func QueryGetAuthorsByOptionalLists(input GetAuthorsByOptionalListsInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	sb.WriteString("SELECT id FROM authors")
+
+	groupClause1 := make([]string, 0, 2)
+
+	groupClause2 := make([]string, 0, 2)
+
+	if input.ids != nil {
+		lit1 := "id"
+		expr1 := "FALSE"
+		if len(input.ids) > 0 {
+			lit2 := make([]string, 0, len(input.ids))
+			for _, item := range input.ids {
+				lit2 = append(lit2, fmt.Sprintf("$%d", argIndex))
+				args = append(args, item)
+				argIndex++
+			}
+			expr1 = fmt.Sprintf("%s IN (%s)", lit1, strings.Join(lit2, ", "))
+		}
+		groupClause2 = append(groupClause2, expr1)
+	}
+
+	if input.names != nil {
+		groupClause3 := make([]string, 0, len(input.names))
+
+		for _, local7_name := range input.names {
+			lit3 := "first_name"
+			lit4 := fmt.Sprintf("$%d", argIndex)
+			args = append(args, local7_name)
+			argIndex++
+			expr2 := fmt.Sprintf("%s != %s", lit3, lit4)
+			groupClause3 = append(groupClause3, expr2)
+		}
+
+		if len(input.names) == 0 {
+			groupClause3 = append(groupClause3, "FALSE")
+		}
+
+		groupClause3Result := strings.Join(groupClause3, " AND ")
+		if len(groupClause3Result) > 0 {
+			groupClause2 = append(groupClause2, fmt.Sprintf("(%s)", groupClause3Result))
+		}
+
+	}
+
+	groupClause2Result := strings.Join(groupClause2, " AND ")
+	if len(groupClause2Result) > 0 {
+		groupClause1 = append(groupClause1, fmt.Sprintf("(%s)", groupClause2Result))
+	}
+
+	if input.names == nil {
+		lit5 := "bio"
+		lit6 := "NULL"
+		expr3 := fmt.Sprintf("%s IS NOT %s", lit5, lit6)
+		groupClause1 = append(groupClause1, expr3)
+	}
+
+	groupClause1Result := strings.Join(groupClause1, " AND ")
+	if len(groupClause1Result) > 0 {
+		sb.WriteString(fmt.Sprintf(" WHERE %s", groupClause1Result))
+	}
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
@@ -9,6 +9,10 @@ type GetAuthorOptionalWhereInput struct {
 	id *string
 }
 
+type GetAuthorOptionalWhereRow struct {
+	id int64
+}
+
 func QueryGetAuthorOptionalWhere(input GetAuthorOptionalWhereInput) (string, []interface{}) {
 	sb := strings.Builder{}
 	args := []interface{}{}
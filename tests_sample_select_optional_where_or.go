@@ -10,6 +10,10 @@ type GetAuthorOptionalWhereOrInput struct {
 	id2 *string
 }
 
+type GetAuthorOptionalWhereOrRow struct {
+	id int64
+}
+
 func QueryGetAuthorOptionalWhereOr(input GetAuthorOptionalWhereOrInput) (string, []interface{}) {
 	sb := strings.Builder{}
 	args := []interface{}{}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorOrderBySort int
+
+const (
+	GetAuthorOrderBySortFirstName GetAuthorOrderBySort = iota
+	GetAuthorOrderBySortLastName
+	GetAuthorOrderBySortBio
+)
+
+type GetAuthorOrderByDir int
+
+const (
+	GetAuthorOrderByDirAsc GetAuthorOrderByDir = iota
+	GetAuthorOrderByDirDesc
+)
+
+type GetAuthorOrderByInput struct {
+	name *string
+	sort GetAuthorOrderBySort
+	dir  GetAuthorOrderByDir
+}
+
+type GetAuthorOrderByRow struct {
+	id          int64
+	author_name string
+}
+
+func QueryGetAuthorOrderBy(input GetAuthorOrderByInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	sb.WriteString("SELECT id, first_name author_name FROM authors")
+
+	if input.name != nil {
+		lit1 := "first_name"
+		lit2 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.name)
+		argIndex++
+		expr1 := fmt.Sprintf("%s = %s", lit1, lit2)
+		sb.WriteString(fmt.Sprintf(" WHERE %s", expr1))
+
+	}
+
+	var lit3 string
+	switch input.sort {
+	case GetAuthorOrderBySortLastName:
+		lit3 = "last_name"
+	case GetAuthorOrderBySortBio:
+		lit3 = "bio"
+	default:
+		lit3 = "first_name"
+	}
+	lit4 := "ASC"
+	if input.dir == GetAuthorOrderByDirDesc {
+		lit4 = "DESC"
+	}
+	lit5 := "author_name"
+	lit6 := "id"
+	sb.WriteString(fmt.Sprintf(" ORDER BY %s %s NULLS LAST, %s, %s DESC", lit3, lit4, lit5, lit6))
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
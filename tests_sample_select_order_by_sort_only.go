@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetBookAuthorsSortedSort int
+
+const (
+	GetBookAuthorsSortedSortAFirstName GetBookAuthorsSortedSort = iota
+	GetBookAuthorsSortedSortBTitle
+)
+
+type GetBookAuthorsSortedDir int
+
+const (
+	GetBookAuthorsSortedDirAsc GetBookAuthorsSortedDir = iota
+	GetBookAuthorsSortedDirDesc
+)
+
+type GetBookAuthorsSortedInput struct {
+	sort GetBookAuthorsSortedSort
+	dir  GetBookAuthorsSortedDir
+}
+
+type GetBookAuthorsSortedRow struct {
+	first_name string
+	title      string
+}
+
+func QueryGetBookAuthorsSorted(input GetBookAuthorsSortedInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	sb.WriteString("SELECT a.first_name, b.title FROM authors a")
+
+	sb.WriteString(" INNER JOIN books b ON ")
+	lit1 := "b.author_id"
+	lit2 := "a.id"
+	expr1 := fmt.Sprintf("%s = %s", lit1, lit2)
+	sb.WriteString(fmt.Sprintf("%s", expr1))
+
+	var lit3 string
+	switch input.sort {
+	case GetBookAuthorsSortedSortBTitle:
+		lit3 = "b.title"
+	default:
+		lit3 = "a.first_name"
+	}
+	lit4 := "ASC"
+	if input.dir == GetBookAuthorsSortedDirDesc {
+		lit4 = "DESC"
+	}
+	sb.WriteString(fmt.Sprintf(" ORDER BY %s %s", lit3, lit4))
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
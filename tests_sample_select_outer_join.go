@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorsWithBooksRow struct {
+	id         int64
+	first_name string
+	book_id    *int64
+	title      *string
+}
+
+func QueryGetAuthorsWithBooks() (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	sb.WriteString("SELECT a.id, a.first_name, b.id book_id, b.title FROM authors a")
+
+	sb.WriteString(" LEFT JOIN books b ON ")
+	lit1 := "b.author_id"
+	lit2 := "a.id"
+	expr1 := fmt.Sprintf("%s = %s", lit1, lit2)
+	sb.WriteString(fmt.Sprintf("%s", expr1))
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
+
+type GetBooksWithAuthorsRow struct {
+	author_id  *int64
+	first_name *string
+	id         int64
+	title      string
+}
+
+func QueryGetBooksWithAuthors() (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	sb.WriteString("SELECT a.id author_id, a.first_name, b.id, b.title FROM authors a")
+
+	sb.WriteString(" RIGHT JOIN books b ON ")
+	lit1 := "b.author_id"
+	lit2 := "a.id"
+	expr1 := fmt.Sprintf("%s = %s", lit1, lit2)
+	sb.WriteString(fmt.Sprintf("%s", expr1))
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
+
+type GetAuthorsAndBooksRow struct {
+	first_name *string
+	title      *string
+}
+
+func QueryGetAuthorsAndBooks() (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	sb.WriteString("SELECT a.first_name, b.title FROM authors a")
+
+	sb.WriteString(" FULL OUTER JOIN books b ON ")
+	lit1 := "b.author_id"
+	lit2 := "a.id"
+	expr1 := fmt.Sprintf("%s = %s", lit1, lit2)
+	sb.WriteString(fmt.Sprintf("%s", expr1))
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type GetAuthorPaginateInput struct {
+	name     *string
+	pageSize int
+	cursor   *GetAuthorPaginateCursor
+}
+
+type GetAuthorPaginateRow struct {
+	id         int64
+	first_name string
+}
+
+type GetAuthorPaginateCursor struct {
+	first_name string
+	id         int64
+}
+
+// returns the cursor for the page after row
+func EncodeGetAuthorPaginateCursor(row GetAuthorPaginateRow) string {
+	data, _ := json.Marshal([]interface{}{row.first_name, row.id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeGetAuthorPaginateCursor(cursor string) (GetAuthorPaginateCursor, error) {
+	var c GetAuthorPaginateCursor
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return c, fmt.Errorf("invalid cursor: %w", err)
+	}
+	var values []json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return c, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if len(values) != 2 {
+		return c, fmt.Errorf("invalid cursor: expected 2 values, got %d", len(values))
+	}
+	if err := json.Unmarshal(values[0], &c.first_name); err != nil {
+		return c, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if err := json.Unmarshal(values[1], &c.id); err != nil {
+		return c, fmt.Errorf("invalid cursor: %w", err)
+	}
+	return c, nil
+}
+
+func QueryGetAuthorPaginate(input GetAuthorPaginateInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	sb.WriteString("SELECT id, first_name FROM authors")
+
+	groupClause1 := make([]string, 0, 2)
+
+	if input.name != nil {
+		lit1 := "first_name"
+		lit2 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.name)
+		argIndex++
+		expr1 := fmt.Sprintf("%s = %s", lit1, lit2)
+		groupClause1 = append(groupClause1, expr1)
+	}
+
+	if input.cursor != nil {
+		lit3 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, input.cursor.first_name)
+		argIndex++
+		lit4 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, input.cursor.id)
+		argIndex++
+		expr2 := fmt.Sprintf("(first_name, id) > (%s, %s)", lit3, lit4)
+		groupClause1 = append(groupClause1, expr2)
+	}
+
+	groupClause1Result := strings.Join(groupClause1, " AND ")
+	if len(groupClause1Result) > 0 {
+		sb.WriteString(fmt.Sprintf(" WHERE %s", groupClause1Result))
+	}
+
+	lit5 := "first_name"
+	lit6 := "id"
+	sb.WriteString(fmt.Sprintf(" ORDER BY %s, %s", lit5, lit6))
+
+	lit7 := fmt.Sprintf("$%d", argIndex)
+	args = append(args, input.pageSize)
+	argIndex++
+	sb.WriteString(fmt.Sprintf(" LIMIT %s", lit7))
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorPredicatesInput struct {
+	minID      *int
+	maxID      *int
+	nameLike   *string
+	bioPattern *string
+	otherBio   *string
+}
+
+type GetAuthorPredicatesRow struct {
+	id int64
+}
+
+func QueryGetAuthorPredicates(input GetAuthorPredicatesInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	sb.WriteString("SELECT id FROM authors")
+
+	groupClause1 := make([]string, 0, 2)
+
+	groupClause2 := make([]string, 0, 2)
+
+	groupClause3 := make([]string, 0, 2)
+
+	groupClause4 := make([]string, 0, 2)
+
+	groupClause5 := make([]string, 0, 2)
+
+	if input.minID != nil && input.maxID != nil {
+		lit1 := "id"
+		lit2 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.minID)
+		argIndex++
+		lit3 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.maxID)
+		argIndex++
+		expr1 := fmt.Sprintf("%s BETWEEN %s AND %s", lit1, lit2, lit3)
+		groupClause5 = append(groupClause5, expr1)
+	}
+
+	if input.nameLike != nil {
+		lit4 := "first_name"
+		lit5 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.nameLike)
+		argIndex++
+		expr2 := fmt.Sprintf("%s ILIKE %s", lit4, lit5)
+		groupClause5 = append(groupClause5, expr2)
+	}
+
+	groupClause5Result := strings.Join(groupClause5, " AND ")
+	if len(groupClause5Result) > 0 {
+		groupClause4 = append(groupClause4, fmt.Sprintf("(%s)", groupClause5Result))
+	}
+
+	if input.bioPattern != nil {
+		lit6 := "bio"
+		lit7 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.bioPattern)
+		argIndex++
+		expr3 := fmt.Sprintf("%s SIMILAR TO %s", lit6, lit7)
+		groupClause4 = append(groupClause4, expr3)
+	}
+
+	groupClause4Result := strings.Join(groupClause4, " AND ")
+	if len(groupClause4Result) > 0 {
+		groupClause3 = append(groupClause3, fmt.Sprintf("(%s)", groupClause4Result))
+	}
+
+	if input.otherBio != nil {
+		lit8 := "bio"
+		lit9 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.otherBio)
+		argIndex++
+		expr4 := fmt.Sprintf("%s IS DISTINCT FROM %s", lit8, lit9)
+		groupClause3 = append(groupClause3, expr4)
+	}
+
+	groupClause3Result := strings.Join(groupClause3, " AND ")
+	if len(groupClause3Result) > 0 {
+		groupClause2 = append(groupClause2, fmt.Sprintf("(%s)", groupClause3Result))
+	}
+
+	lit10 := "active"
+	lit11 := "FALSE"
+	expr5 := fmt.Sprintf("%s IS NOT %s", lit10, lit11)
+	groupClause2 = append(groupClause2, expr5)
+	groupClause2Result := strings.Join(groupClause2, " AND ")
+	if len(groupClause2Result) > 0 {
+		groupClause1 = append(groupClause1, fmt.Sprintf("(%s)", groupClause2Result))
+	}
+
+	groupClause6 := make([]string, 0, 2)
+
+	groupClause7 := make([]string, 0, 2)
+
+	if input.nameLike != nil {
+		lit12 := "alias"
+		lit13 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.nameLike)
+		argIndex++
+		expr6 := fmt.Sprintf("%s NOT ILIKE %s", lit12, lit13)
+		groupClause7 = append(groupClause7, expr6)
+	}
+
+	lit14 := "alias"
+	lit15 := "'x%'"
+	expr7 := fmt.Sprintf("%s NOT SIMILAR TO %s", lit14, lit15)
+	groupClause7 = append(groupClause7, expr7)
+	groupClause7Result := strings.Join(groupClause7, " OR ")
+	if len(groupClause7Result) > 0 {
+		groupClause6 = append(groupClause6, fmt.Sprintf("(%s)", groupClause7Result))
+	}
+
+	groupClause6Result := strings.Join(groupClause6, " AND ")
+	if len(groupClause6Result) > 0 {
+		groupClause1 = append(groupClause1, fmt.Sprintf("NOT (%s)", groupClause6Result))
+	}
+
+	groupClause1Result := strings.Join(groupClause1, " AND ")
+	if len(groupClause1Result) > 0 {
+		sb.WriteString(fmt.Sprintf(" WHERE %s", groupClause1Result))
+	}
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
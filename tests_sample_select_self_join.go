@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorNamesakesRow struct {
+	id          int64
+	first_name  string
+	namesake_id int64
+}
+
+func QueryGetAuthorNamesakes() (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	sb.WriteString("SELECT a.id, a.first_name, namesake.id namesake_id FROM authors a")
+
+	sb.WriteString(" INNER JOIN authors namesake ON ")
+	groupClause1 := make([]string, 0, 2)
+
+	lit1 := "namesake.first_name"
+	lit2 := "a.first_name"
+	expr1 := fmt.Sprintf("%s = %s", lit1, lit2)
+	groupClause1 = append(groupClause1, expr1)
+	lit3 := "namesake.id"
+	lit4 := "a.id"
+	expr2 := fmt.Sprintf("%s != %s", lit3, lit4)
+	groupClause1 = append(groupClause1, expr2)
+	groupClause1Result := strings.Join(groupClause1, " AND ")
+	if len(groupClause1Result) > 0 {
+		sb.WriteString(fmt.Sprintf("%s", groupClause1Result))
+	}
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
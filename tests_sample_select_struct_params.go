@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type AuthorFilter struct {
+	name   *string
+	minID  *int
+	active bool
+}
+
+type FilterAuthorsInput struct {
+	filter  AuthorFilter
+	exclude *AuthorFilter
+}
+
+type FilterAuthorsRow struct {
+	id int64
+}
+
+func QueryFilterAuthors(input FilterAuthorsInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	sb.WriteString("SELECT id FROM authors")
+
+	groupClause1 := make([]string, 0, 2)
+
+	groupClause2 := make([]string, 0, 2)
+
+	groupClause3 := make([]string, 0, 2)
+
+	groupClause4 := make([]string, 0, 2)
+
+	if input.filter.name != nil {
+		lit1 := "first_name"
+		lit2 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.filter.name)
+		argIndex++
+		expr1 := fmt.Sprintf("%s = %s", lit1, lit2)
+		groupClause4 = append(groupClause4, expr1)
+	}
+
+	lit3 := "active"
+	lit4 := fmt.Sprintf("$%d", argIndex)
+	args = append(args, input.filter.active)
+	argIndex++
+	expr2 := fmt.Sprintf("%s = %s", lit3, lit4)
+	groupClause4 = append(groupClause4, expr2)
+	groupClause4Result := strings.Join(groupClause4, " AND ")
+	if len(groupClause4Result) > 0 {
+		groupClause3 = append(groupClause3, fmt.Sprintf("(%s)", groupClause4Result))
+	}
+
+	if input.filter.minID != nil {
+		if input.filter.minID != nil {
+			lit5 := "id"
+			lit6 := fmt.Sprintf("$%d", argIndex)
+			args = append(args, *input.filter.minID)
+			argIndex++
+			expr3 := fmt.Sprintf("%s >= %s", lit5, lit6)
+			groupClause3 = append(groupClause3, expr3)
+		}
+
+	}
+
+	groupClause3Result := strings.Join(groupClause3, " AND ")
+	if len(groupClause3Result) > 0 {
+		groupClause2 = append(groupClause2, fmt.Sprintf("(%s)", groupClause3Result))
+	}
+
+	if input.exclude != nil && input.exclude.name != nil {
+		lit7 := "last_name"
+		lit8 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.exclude.name)
+		argIndex++
+		expr4 := fmt.Sprintf("%s != %s", lit7, lit8)
+		groupClause2 = append(groupClause2, expr4)
+	}
+
+	groupClause2Result := strings.Join(groupClause2, " AND ")
+	if len(groupClause2Result) > 0 {
+		groupClause1 = append(groupClause1, fmt.Sprintf("(%s)", groupClause2Result))
+	}
+
+	if input.exclude == nil || input.exclude.name == nil {
+		if input.exclude != nil {
+			lit9 := "active"
+			lit10 := fmt.Sprintf("$%d", argIndex)
+			args = append(args, input.exclude.active)
+			argIndex++
+			expr5 := fmt.Sprintf("%s != %s", lit9, lit10)
+			groupClause1 = append(groupClause1, expr5)
+		}
+
+	}
+
+	groupClause1Result := strings.Join(groupClause1, " AND ")
+	if len(groupClause1Result) > 0 {
+		sb.WriteString(fmt.Sprintf(" WHERE %s", groupClause1Result))
+	}
+
+	lit11 := "id"
+	sb.WriteString(fmt.Sprintf(" ORDER BY %s", lit11))
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetCategorySubqueriesInput struct {
+	childName *string
+	minID     int
+}
+
+type GetCategorySubqueriesRow struct {
+	id          int64
+	child_count int64
+}
+
+func QueryGetCategorySubqueries(input GetCategorySubqueriesInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	var lit1 string
+	{
+		sb := strings.Builder{}
+
+		lit2 := "count(*)"
+		sb.WriteString(fmt.Sprintf("SELECT %s FROM categories c", lit2))
+
+		groupClause1 := make([]string, 0, 2)
+
+		lit3 := "c.parent_id"
+		lit4 := "p.id"
+		expr1 := fmt.Sprintf("%s = %s", lit3, lit4)
+		groupClause1 = append(groupClause1, expr1)
+		if input.childName != nil {
+			lit5 := "c.name"
+			lit6 := fmt.Sprintf("$%d", argIndex)
+			args = append(args, *input.childName)
+			argIndex++
+			expr2 := fmt.Sprintf("%s = %s", lit5, lit6)
+			groupClause1 = append(groupClause1, expr2)
+		}
+
+		groupClause1Result := strings.Join(groupClause1, " AND ")
+		if len(groupClause1Result) > 0 {
+			sb.WriteString(fmt.Sprintf(" WHERE %s", groupClause1Result))
+		}
+
+		lit1 = fmt.Sprintf("(%s)", sb.String())
+	}
+	sb.WriteString(fmt.Sprintf("SELECT p.id, %s child_count FROM categories p", lit1))
+
+	groupClause2 := make([]string, 0, 2)
+
+	groupClause3 := make([]string, 0, 2)
+
+	var lit7 string
+	{
+		sb := strings.Builder{}
+
+		sb.WriteString("SELECT id FROM categories c")
+
+		lit8 := "c.parent_id"
+		lit9 := "p.id"
+		expr3 := fmt.Sprintf("%s = %s", lit8, lit9)
+		sb.WriteString(fmt.Sprintf(" WHERE %s", expr3))
+
+		lit7 = fmt.Sprintf("EXISTS (%s)", sb.String())
+	}
+	groupClause3 = append(groupClause3, lit7)
+	lit10 := "p.parent_id"
+	var lit11 string
+	{
+		sb := strings.Builder{}
+
+		sb.WriteString("SELECT id FROM categories")
+
+		lit12 := "id"
+		lit13 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, input.minID)
+		argIndex++
+		expr4 := fmt.Sprintf("%s > %s", lit12, lit13)
+		sb.WriteString(fmt.Sprintf(" WHERE %s", expr4))
+
+		lit11 = fmt.Sprintf("(%s)", sb.String())
+	}
+	expr5 := fmt.Sprintf("%s IN %s", lit10, lit11)
+	groupClause3 = append(groupClause3, expr5)
+	groupClause3Result := strings.Join(groupClause3, " AND ")
+	if len(groupClause3Result) > 0 {
+		groupClause2 = append(groupClause2, fmt.Sprintf("(%s)", groupClause3Result))
+	}
+
+	lit14 := "p.id"
+	var lit15 string
+	{
+		sb := strings.Builder{}
+
+		var lit16 string
+		{
+			sb := strings.Builder{}
+
+			sb.WriteString("SELECT id, name FROM categories")
+
+			if input.childName != nil {
+				lit17 := "name"
+				lit18 := fmt.Sprintf("$%d", argIndex)
+				args = append(args, *input.childName)
+				argIndex++
+				expr6 := fmt.Sprintf("%s = %s", lit17, lit18)
+				sb.WriteString(fmt.Sprintf(" WHERE %s", expr6))
+
+			}
+
+			lit16 = fmt.Sprintf("(%s)", sb.String())
+		}
+		sb.WriteString(fmt.Sprintf("SELECT id FROM %s named", lit16))
+
+		lit15 = fmt.Sprintf("(%s)", sb.String())
+	}
+	expr7 := fmt.Sprintf("%s NOT IN %s", lit14, lit15)
+	groupClause2 = append(groupClause2, expr7)
+	groupClause2Result := strings.Join(groupClause2, " AND ")
+	if len(groupClause2Result) > 0 {
+		sb.WriteString(fmt.Sprintf(" WHERE %s", groupClause2Result))
+	}
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetAuthorAndCategoryNamesInput struct {
+	name              *string
+	includeCategories *string
+	limit             int
+}
+
+type GetAuthorAndCategoryNamesRow struct {
+	id    int64
+	label string
+}
+
+func QueryGetAuthorAndCategoryNames(input GetAuthorAndCategoryNamesInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	sb.WriteString("SELECT id, first_name label FROM authors")
+
+	if input.name != nil {
+		lit1 := "first_name"
+		lit2 := fmt.Sprintf("$%d", argIndex)
+		args = append(args, *input.name)
+		argIndex++
+		expr1 := fmt.Sprintf("%s = %s", lit1, lit2)
+		sb.WriteString(fmt.Sprintf(" WHERE %s", expr1))
+
+	}
+
+	if input.includeCategories != nil {
+		sb.WriteString(" UNION ALL ")
+
+		sb.WriteString("SELECT id, name FROM categories")
+
+		if input.includeCategories != nil {
+			lit3 := "name"
+			lit4 := fmt.Sprintf("$%d", argIndex)
+			args = append(args, *input.includeCategories)
+			argIndex++
+			expr2 := fmt.Sprintf("%s = %s", lit3, lit4)
+			sb.WriteString(fmt.Sprintf(" WHERE %s", expr2))
+
+		}
+
+	}
+
+	sb.WriteString(" EXCEPT ")
+
+	sb.WriteString("SELECT id, last_name FROM authors")
+
+	lit5 := "label"
+	lit6 := "id"
+	sb.WriteString(fmt.Sprintf(" ORDER BY %s DESC, %s", lit5, lit6))
+
+	lit7 := fmt.Sprintf("$%d", argIndex)
+	args = append(args, input.limit)
+	argIndex++
+	sb.WriteString(fmt.Sprintf(" LIMIT %s", lit7))
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GetCategoryRanksInput struct {
+	frameSize int
+}
+
+type GetCategoryRanksRow struct {
+	id            int64
+	name          string
+	position      int64
+	sibling_count int64
+	running_total string
+	previous_name *string
+}
+
+func QueryGetCategoryRanks(input GetCategoryRanksInput) (string, []interface{}) {
+	sb := strings.Builder{}
+	args := []interface{}{}
+
+	argIndex := 1
+
+	lit1 := "parent_id"
+	lit2 := "name"
+	lit3 := fmt.Sprintf("row_number() OVER (PARTITION BY %s ORDER BY %s)", lit1, lit2)
+	lit4 := "count(*) OVER siblings"
+	lit5 := "id"
+	lit6 := "id"
+	lit7 := fmt.Sprintf("$%d", argIndex)
+	args = append(args, input.frameSize)
+	argIndex++
+	lit8 := fmt.Sprintf("sum(%s) OVER (siblings ORDER BY %s ROWS BETWEEN %s PRECEDING AND CURRENT ROW)", lit5, lit6, lit7)
+	lit9 := "name"
+	lit10 := "id"
+	lit11 := fmt.Sprintf("lag(%s) OVER (ORDER BY %s)", lit9, lit10)
+	sb.WriteString(fmt.Sprintf("SELECT id, name, %s position, %s sibling_count, %s running_total, %s previous_name FROM categories", lit3, lit4, lit8, lit11))
+
+	lit12 := "parent_id"
+	sb.WriteString(fmt.Sprintf(" WINDOW siblings AS (PARTITION BY %s)", lit12))
+
+	lit13 := "name"
+	lit14 := fmt.Sprintf("rank() OVER (ORDER BY %s DESC)", lit13)
+	lit15 := "id"
+	sb.WriteString(fmt.Sprintf(" ORDER BY %s, %s", lit14, lit15))
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
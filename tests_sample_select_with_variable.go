@@ -9,6 +9,10 @@ type GetAuthorWithVariableInput struct {
 	id string
 }
 
+type GetAuthorWithVariableRow struct {
+	id int64
+}
+
 func QueryGetAuthorWithVariable(input GetAuthorWithVariableInput) (string, []interface{}) {
 	sb := strings.Builder{}
 	args := []interface{}{}
@@ -5,6 +5,10 @@ import (
 	"strings"
 )
 
+type GetAuthorSimpleSelectRow struct {
+	id int64
+}
+
 func QueryGetAuthorSimpleSelect() (string, []interface{}) {
 	sb := strings.Builder{}
 	args := []interface{}{}
@@ -5,6 +5,10 @@ import (
 	"strings"
 )
 
+type GetAuthorSimpleSelectAliasRow struct {
+	my_id int64
+}
+
 func QueryGetAuthorSimpleSelectAlias() (string, []interface{}) {
 	sb := strings.Builder{}
 	args := []interface{}{}
@@ -5,6 +5,10 @@ import (
 	"strings"
 )
 
+type GetAuthorSimpleSelectComparisonsRow struct {
+	id int64
+}
+
 func QueryGetAuthorSimpleSelectComparisons() (string, []interface{}) {
 	sb := strings.Builder{}
 	args := []interface{}{}